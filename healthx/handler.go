@@ -62,6 +62,8 @@ type Handler struct {
 	H             herodot.Writer
 	VersionString string
 	ReadyChecks   ReadyCheckers
+	// Registry holds the checks registered by components. It is optional.
+	Registry *Registry
 }
 
 // NewHandler instantiates a handler.
@@ -92,7 +94,44 @@ func (h *Handler) SetVersionRoutes(r router) {
 	r.GET(VersionPath, h.Version)
 }
 
-// Alive returns an ok status if the instance is ready to handle HTTP requests.
+// ServeMux returns a mux serving the health and version routes, for use without httprouter,
+// e.g. mounted on an admin mux next to other handlers.
+func (h *Handler) ServeMux(shareErrors bool) *http.ServeMux {
+	mux := http.NewServeMux()
+	for path, handle := range map[string]httprouter.Handle{
+		AliveCheckPath: h.Alive,
+		ReadyCheckPath: h.Ready(shareErrors),
+		VersionPath:    h.Version,
+	} {
+		handle := handle
+		mux.HandleFunc(path, func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				rw.Header().Set("Allow", "GET, HEAD")
+				rw.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			handle(rw, r, nil)
+		})
+	}
+	return mux
+}
+
+func notReadyStatus(errs map[string]error, shareErrors bool) *swaggerNotReadyStatus {
+	notReady := swaggerNotReadyStatus{
+		Errors: make(map[string]string, len(errs)),
+	}
+	for n, err := range errs {
+		if shareErrors {
+			notReady.Errors[n] = err.Error()
+		} else {
+			notReady.Errors[n] = "error may contain sensitive information and was obfuscated"
+		}
+	}
+	return &notReady
+}
+
+// Alive returns an ok status if the instance is ready to handle HTTP requests and all alive checks
+// of the Registry are ok. Errors are never shared, as this endpoint is usually public.
 //
 // swagger:route GET /health/alive health isInstanceAlive
 //
@@ -113,13 +152,21 @@ func (h *Handler) SetVersionRoutes(r router) {
 //     Responses:
 //       200: healthStatus
 //       500: genericError
+//       503: healthNotReadyStatus
 func (h *Handler) Alive(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	if errs := h.Registry.CheckAlive(r); len(errs) > 0 {
+		h.H.WriteCode(rw, r, http.StatusServiceUnavailable, notReadyStatus(errs, false))
+		return
+	}
+
 	h.H.Write(rw, r, &swaggerHealthStatus{
 		Status: "ok",
 	})
 }
 
-// Ready returns an ok status if the instance is ready to handle HTTP requests and all ReadyCheckers are ok.
+// Ready returns an ok status if the instance is ready to handle HTTP requests and all ReadyCheckers as well as
+// the ready checks of the Registry are ok. The ReadyCheckers run one after another, while the checks of the
+// Registry run concurrently. A check of the Registry must not have the name of one of the ReadyCheckers.
 //
 // swagger:route GET /health/ready health isInstanceReady
 //
@@ -142,22 +189,10 @@ func (h *Handler) Alive(rw http.ResponseWriter, r *http.Request, _ httprouter.Pa
 //       503: healthNotReadyStatus
 func (h *Handler) Ready(shareErrors bool) httprouter.Handle {
 	return func(rw http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-		var notReady = swaggerNotReadyStatus{
-			Errors: map[string]string{},
-		}
-
-		for n, c := range h.ReadyChecks {
-			if err := c(r); err != nil {
-				if shareErrors {
-					notReady.Errors[n] = err.Error()
-				} else {
-					notReady.Errors[n] = "error may contain sensitive information and was obfuscated"
-				}
-			}
-		}
+		errs := h.Registry.checkReady(r, h.ReadyChecks)
 
-		if len(notReady.Errors) > 0 {
-			h.H.WriteCode(rw, r, http.StatusServiceUnavailable, notReady)
+		if len(errs) > 0 {
+			h.H.WriteCode(rw, r, http.StatusServiceUnavailable, notReadyStatus(errs, shareErrors))
 			return
 		}
 
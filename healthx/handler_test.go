@@ -27,7 +27,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/stretchr/testify/assert"
@@ -82,3 +84,88 @@ func TestHealth(t *testing.T) {
 	require.NoError(t, json.NewDecoder(response.Body).Decode(&versionBody))
 	require.EqualValues(t, versionBody.Version, handler.VersionString)
 }
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	handler := &Handler{
+		H:             herodot.NewJSONWriter(nil),
+		VersionString: "test version",
+		Registry:      registry,
+	}
+	ts := httptest.NewServer(handler.ServeMux(false))
+	defer ts.Close()
+
+	get := func(path string) (int, string) {
+		response, err := http.Get(ts.URL + path)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		out, err := ioutil.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response.StatusCode, strings.TrimSpace(string(out))
+	}
+
+	code, _ := get(ReadyCheckPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	registry.AddReadyCheck("database", func(r *http.Request) error { return errors.New("connection refused") })
+	registry.AddReadyCheck("upstream", func(r *http.Request) error { return nil })
+	code, body := get(ReadyCheckPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, `{"errors":{"database":"error may contain sensitive information and was obfuscated"}}`, body)
+
+	code, _ = get(AliveCheckPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	registry.AddAliveCheck("deadlock", func(r *http.Request) error { return errors.New("stuck") })
+	code, _ = get(AliveCheckPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+
+	registry.RemoveCheck("deadlock")
+	registry.RemoveCheck("database")
+	code, _ = get(AliveCheckPath)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = get(ReadyCheckPath)
+	assert.Equal(t, http.StatusOK, code)
+
+	handler.ReadyChecks = ReadyCheckers{"database": func(r *http.Request) error { return nil }}
+	registry.AddReadyCheck("database", func(r *http.Request) error { return nil })
+	code, _ = get(ReadyCheckPath)
+	assert.Equal(t, http.StatusServiceUnavailable, code, "checks of the same name are not ready")
+	registry.RemoveCheck("database")
+	code, _ = get(ReadyCheckPath)
+	assert.Equal(t, http.StatusOK, code)
+	handler.ReadyChecks = nil
+
+	code, body = get(VersionPath)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"version":"test version"}`, body)
+
+	response, err := http.Post(ts.URL+AliveCheckPath, "application/json", nil)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestReadyChecksRunSequentially(t *testing.T) {
+	var running, overlapped int32
+	check := func(r *http.Request) error {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.StoreInt32(&overlapped, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	handler := &Handler{
+		H:           herodot.NewJSONWriter(nil),
+		ReadyChecks: ReadyCheckers{"a": check, "b": check, "c": check},
+	}
+	ts := httptest.NewServer(handler.ServeMux(false))
+	defer ts.Close()
+
+	response, err := http.Get(ts.URL + ReadyCheckPath)
+	require.NoError(t, err)
+	require.NoError(t, response.Body.Close())
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, 0, atomic.LoadInt32(&overlapped))
+}
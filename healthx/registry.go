@@ -0,0 +1,125 @@
+package healthx
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Registry collects the alive and ready checks of multiple components, such as database pings and upstream
+// checks, under unique names. Set it as Handler.Registry to have them reported by the health endpoints.
+//
+// A Registry is safe for concurrent use; checks may be added while the handler serves requests.
+type Registry struct {
+	mu    sync.RWMutex
+	alive ReadyCheckers
+	ready ReadyCheckers
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{alive: ReadyCheckers{}, ready: ReadyCheckers{}}
+}
+
+// AddAliveCheck adds a check which fails the alive endpoint, replacing any check of the same name.
+// Alive checks should only fail if the instance can not recover without a restart.
+func (r *Registry) AddAliveCheck(name string, c ReadyChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alive[name] = c
+}
+
+// AddReadyCheck adds a check which fails the ready endpoint, replacing any check of the same name.
+func (r *Registry) AddReadyCheck(name string, c ReadyChecker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ready[name] = c
+}
+
+// RemoveCheck removes the alive and ready checks of the name.
+func (r *Registry) RemoveCheck(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.alive, name)
+	delete(r.ready, name)
+}
+
+// CheckAlive runs all alive checks concurrently and returns the errors by check name.
+func (r *Registry) CheckAlive(req *http.Request) map[string]error {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	checks := make(ReadyCheckers, len(r.alive))
+	for n, c := range r.alive {
+		checks[n] = c
+	}
+	r.mu.RUnlock()
+	return checks.run(req)
+}
+
+// CheckReady runs all ready checks concurrently and returns the errors by check name.
+func (r *Registry) CheckReady(req *http.Request) map[string]error {
+	return r.readyChecks().run(req)
+}
+
+// readyChecks returns a copy of the ready checks.
+func (r *Registry) readyChecks() ReadyCheckers {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	checks := make(ReadyCheckers, len(r.ready))
+	for n, c := range r.ready {
+		checks[n] = c
+	}
+	return checks
+}
+
+// checkReady runs the ReadyCheckers one after another, as they might not be safe for concurrent use, and the
+// ready checks of the registry concurrently. Checks of the registry which have the name of one of the
+// ReadyCheckers fail, so that neither is hidden by the other.
+func (r *Registry) checkReady(req *http.Request, cs ReadyCheckers) map[string]error {
+	errs := map[string]error{}
+	for n, c := range cs {
+		if err := c(req); err != nil {
+			errs[n] = err
+		}
+	}
+
+	registered := r.readyChecks()
+	for n := range registered {
+		if _, ok := cs[n]; ok {
+			errs[n] = errors.Errorf("the check %q is registered both as a ReadyChecker and in the Registry", n)
+			delete(registered, n)
+		}
+	}
+	for n, err := range registered.run(req) {
+		errs[n] = err
+	}
+	return errs
+}
+
+// run runs the checks concurrently and returns the errors by check name.
+func (cs ReadyCheckers) run(r *http.Request) map[string]error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = map[string]error{}
+	)
+	for n, c := range cs {
+		wg.Add(1)
+		go func(n string, c ReadyChecker) {
+			defer wg.Done()
+			if err := c(r); err != nil {
+				mu.Lock()
+				errs[n] = err
+				mu.Unlock()
+			}
+		}(n, c)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,218 @@
+package jwksx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/square/go-jose.v2"
+)
+
+// ErrKeyNotFound is returned by Client.Key if the remote does not serve a key with the requested ID.
+var ErrKeyNotFound = errors.New("unable to find JSON Web Key")
+
+// DefaultClientTimeout is the timeout of the http.Client used to fetch the keys if none is set via WithHTTPClient.
+const DefaultClientTimeout = 10 * time.Second
+
+type (
+	// Client fetches JSON Web Key Sets from a remote endpoint and caches them. Unlike Fetcher, cached keys
+	// are refreshed periodically, so rotated and revoked keys are picked up. Fetches triggered by Key and Keys,
+	// e.g. by unknown key IDs, are attempted at most once every minimum refresh interval, even if they fail.
+	Client struct {
+		remote             string
+		hc                 *http.Client
+		refreshInterval    time.Duration
+		minRefreshInterval time.Duration
+
+		fetchMu sync.Mutex
+		// attemptedAt and attemptErr are the time and error of the last fetch, guarded by fetchMu
+		attemptedAt time.Time
+		attemptErr  error
+
+		mu        sync.RWMutex
+		keys      map[string]jose.JSONWebKey
+		set       jose.JSONWebKeySet
+		fetchedAt time.Time
+	}
+	clientOptions struct {
+		hc                 *http.Client
+		refreshInterval    time.Duration
+		minRefreshInterval time.Duration
+	}
+	// ClientOption configures a Client.
+	ClientOption func(*clientOptions)
+)
+
+// WithHTTPClient sets the http.Client used to fetch the keys. Defaults to a client with DefaultClientTimeout.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.hc = hc
+	}
+}
+
+// WithRefreshInterval sets the time after which cached keys are refreshed. Defaults to one hour.
+func WithRefreshInterval(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.refreshInterval = d
+	}
+}
+
+// WithMinRefreshInterval sets the minimum time between two fetches triggered by Key and Keys, which protects
+// the remote from clients sending made-up key IDs, and from retries while it is failing. Defaults to one minute.
+func WithMinRefreshInterval(d time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.minRefreshInterval = d
+	}
+}
+
+// NewClient returns a new client for the JSON Web Key Set served at remote. No request is made
+// until a key is requested or Refresh is called. The refresh interval must be positive.
+func NewClient(remote string, opts ...ClientOption) (*Client, error) {
+	o := &clientOptions{
+		hc:                 &http.Client{Timeout: DefaultClientTimeout},
+		refreshInterval:    time.Hour,
+		minRefreshInterval: time.Minute,
+	}
+	for _, f := range opts {
+		f(o)
+	}
+	if o.refreshInterval <= 0 {
+		return nil, errors.Errorf("the refresh interval must be positive, but is %s", o.refreshInterval)
+	}
+	return &Client{
+		remote:             remote,
+		hc:                 o.hc,
+		refreshInterval:    o.refreshInterval,
+		minRefreshInterval: o.minRefreshInterval,
+	}, nil
+}
+
+func (c *Client) lookup(kid string) (k jose.JSONWebKey, found bool, fetchedAt time.Time) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	k, found = c.keys[kid]
+	return k, found, c.fetchedAt
+}
+
+// Key returns the key with the given ID. If the cached keys are older than the refresh interval, they are
+// refreshed first; should that fail, or should a fetch have been attempted within the minimum refresh interval,
+// the cached key is returned anyway. Unknown key IDs trigger a refresh unless a fetch was attempted within the
+// minimum refresh interval, and result in ErrKeyNotFound.
+func (c *Client) Key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	k, found, fetchedAt := c.lookup(kid)
+	switch {
+	case found && time.Since(fetchedAt) < c.refreshInterval:
+		return &k, nil
+	case found:
+		if err := c.refresh(ctx, c.refreshInterval); err != nil {
+			return &k, nil
+		}
+	default:
+		if err := c.refresh(ctx, c.minRefreshInterval); err != nil {
+			return nil, err
+		}
+	}
+
+	if k, found, _ = c.lookup(kid); !found {
+		return nil, errors.Wrapf(ErrKeyNotFound, "key ID %s", kid)
+	}
+	return &k, nil
+}
+
+// Keys returns all keys, fetching them first if they are not cached or older than the refresh interval, unless
+// a fetch was attempted within the minimum refresh interval.
+func (c *Client) Keys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	if err := c.refresh(ctx, c.refreshInterval); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	set := jose.JSONWebKeySet{Keys: append([]jose.JSONWebKey{}, c.set.Keys...)}
+	return &set, nil
+}
+
+// Refresh fetches the keys from the remote, replacing the cached keys. It is not limited by the minimum
+// refresh interval.
+func (c *Client) Refresh(ctx context.Context) error {
+	return c.refresh(ctx, 0)
+}
+
+// refresh fetches the keys unless they were fetched within maxAge, or, if maxAge is positive, a fetch was
+// attempted within the minimum refresh interval, in which case the error of that attempt is returned.
+// Concurrent calls result in a single fetch.
+func (c *Client) refresh(ctx context.Context, maxAge time.Duration) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	c.mu.RLock()
+	fresh := c.keys != nil && time.Since(c.fetchedAt) < maxAge
+	c.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	if maxAge > 0 && !c.attemptedAt.IsZero() && time.Since(c.attemptedAt) < c.minRefreshInterval {
+		return c.attemptErr
+	}
+
+	attemptedAt := time.Now()
+	set, err := c.fetch(ctx)
+	// the fetch was not attempted for other callers if the context of this one ended
+	if err == nil || ctx.Err() == nil {
+		c.attemptedAt, c.attemptErr = attemptedAt, err
+	}
+	if err != nil {
+		return err
+	}
+
+	keys := make(map[string]jose.JSONWebKey, len(set.Keys))
+	for _, k := range set.Keys {
+		keys[k.KeyID] = k
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.keys, c.set, c.fetchedAt = keys, *set, time.Now()
+	return nil
+}
+
+func (c *Client) fetch(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.remote, nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	res, err := c.hc.Do(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("expected status code 200 but got %d when requesting %s", res.StatusCode, c.remote)
+	}
+
+	var set jose.JSONWebKeySet
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &set, nil
+}
+
+// Run refreshes the keys immediately and then every refresh interval until the context is canceled,
+// so Key does not have to fetch while handling requests. Errors are passed to onError, which may be nil.
+func (c *Client) Run(ctx context.Context, onError func(error)) {
+	ticker := time.NewTicker(c.refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := c.Refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package jwksx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const kid = "7d5f5ad0674ec2f2960b1a34f33370a0f71471fa0e3ef0c0a692977d276dafe8"
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+
+	var called int32
+	var failing int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&called, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(keys))
+	}))
+	defer ts.Close()
+
+	t.Run("case=caches keys and rate limits unknown key IDs", func(t *testing.T) {
+		atomic.StoreInt32(&called, 0)
+		c, err := NewClient(ts.URL, WithMinRefreshInterval(time.Hour))
+		require.NoError(t, err)
+
+		k, err := c.Key(ctx, kid)
+		require.NoError(t, err)
+		assert.EqualValues(t, secret, fmt.Sprintf("%s", k.Key))
+
+		_, err = c.Key(ctx, kid)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&called))
+
+		_, err = c.Key(ctx, "does-not-exist")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		assert.EqualValues(t, 1, atomic.LoadInt32(&called))
+
+		set, err := c.Keys(ctx)
+		require.NoError(t, err)
+		assert.Len(t, set.Keys, 1)
+	})
+
+	t.Run("case=unknown key IDs trigger a refresh", func(t *testing.T) {
+		atomic.StoreInt32(&called, 0)
+		c, err := NewClient(ts.URL, WithMinRefreshInterval(0))
+		require.NoError(t, err)
+
+		_, err = c.Key(ctx, "does-not-exist")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		_, err = c.Key(ctx, "does-not-exist")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+		assert.EqualValues(t, 2, atomic.LoadInt32(&called))
+	})
+
+	t.Run("case=stale keys are refreshed and kept if the remote fails", func(t *testing.T) {
+		atomic.StoreInt32(&called, 0)
+		c, err := NewClient(ts.URL, WithRefreshInterval(time.Nanosecond), WithMinRefreshInterval(0))
+		require.NoError(t, err)
+
+		_, err = c.Key(ctx, kid)
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&failing, 1)
+		defer atomic.StoreInt32(&failing, 0)
+
+		k, err := c.Key(ctx, kid)
+		require.NoError(t, err)
+		assert.Equal(t, kid, k.KeyID)
+		assert.EqualValues(t, 2, atomic.LoadInt32(&called))
+
+		assert.Error(t, c.Refresh(ctx))
+	})
+
+	t.Run("case=fetches are throttled while the remote fails", func(t *testing.T) {
+		atomic.StoreInt32(&called, 0)
+		c, err := NewClient(ts.URL, WithRefreshInterval(time.Nanosecond), WithMinRefreshInterval(time.Hour))
+		require.NoError(t, err)
+
+		_, err = c.Key(ctx, kid)
+		require.NoError(t, err)
+
+		atomic.StoreInt32(&failing, 1)
+		defer atomic.StoreInt32(&failing, 0)
+
+		require.Error(t, c.Refresh(ctx))
+		for i := 0; i < 3; i++ {
+			k, err := c.Key(ctx, kid)
+			require.NoError(t, err, "the stale key is returned")
+			assert.Equal(t, kid, k.KeyID)
+
+			_, err = c.Key(ctx, "does-not-exist")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+		}
+		assert.EqualValues(t, 2, atomic.LoadInt32(&called))
+
+		// without cached keys, the error of the last attempt is returned
+		c, err = NewClient(ts.URL, WithMinRefreshInterval(time.Hour))
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			_, err = c.Keys(ctx)
+			assert.ErrorContains(t, err, "500")
+			_, err = c.Key(ctx, kid)
+			assert.ErrorContains(t, err, "500")
+		}
+		assert.EqualValues(t, 3, atomic.LoadInt32(&called))
+	})
+
+	t.Run("case=rejects non-positive refresh intervals", func(t *testing.T) {
+		_, err := NewClient(ts.URL, WithRefreshInterval(0))
+		assert.Error(t, err)
+	})
+
+	t.Run("case=run refreshes in the background", func(t *testing.T) {
+		atomic.StoreInt32(&called, 0)
+		c, err := NewClient(ts.URL, WithRefreshInterval(10*time.Millisecond))
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			c.Run(ctx, func(err error) { t.Error(err) })
+			close(done)
+		}()
+
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&called) >= 2 }, time.Second, 5*time.Millisecond)
+		cancel()
+		<-done
+	})
+}
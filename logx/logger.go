@@ -0,0 +1,82 @@
+// Package logx defines the Logger accepted by the packages of this module, e.g. by the proxy for
+// access and error logs and by sqlcon for slow queries and retries.
+//
+// The interface is a subset of log/slog's *slog.Logger, so a *slog.Logger can be passed as is.
+// Use FromLogrus to pass a *logrusx.Logger.
+package logx
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/ory/x/logrusx"
+)
+
+// Logger logs a message with alternating keys and values, e.g. l.Info("request completed", "status", 200).
+// A key without a value is logged under the key "!BADKEY", like slog does.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type (
+	nop          struct{}
+	logrusLogger struct {
+		l *logrusx.Logger
+	}
+)
+
+// Nop returns a Logger which discards all messages.
+func Nop() Logger {
+	return nop{}
+}
+
+func (nop) Debug(string, ...interface{}) {}
+func (nop) Info(string, ...interface{})  {}
+func (nop) Warn(string, ...interface{})  {}
+func (nop) Error(string, ...interface{}) {}
+
+// FromLogrus adapts a *logrusx.Logger. The arguments are logged as fields, except for errors
+// under the key "error", which are logged using WithError.
+func FromLogrus(l *logrusx.Logger) Logger {
+	return &logrusLogger{l: l}
+}
+
+func (l *logrusLogger) with(args []interface{}) *logrusx.Logger {
+	ll := l.l
+	fields := logrus.Fields{}
+	for i := 0; i < len(args); i += 2 {
+		key, ok := args[i].(string)
+		if !ok || i+1 == len(args) {
+			fields["!BADKEY"] = args[i]
+			i--
+			continue
+		}
+		if err, ok := args[i+1].(error); ok && key == "error" {
+			ll = ll.WithError(err)
+			continue
+		}
+		fields[key] = args[i+1]
+	}
+	if len(fields) == 0 {
+		return ll
+	}
+	return ll.WithFields(fields)
+}
+
+func (l *logrusLogger) Debug(msg string, args ...interface{}) {
+	l.with(args).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, args ...interface{}) {
+	l.with(args).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, args ...interface{}) {
+	l.with(args).Warn(msg)
+}
+
+func (l *logrusLogger) Error(msg string, args ...interface{}) {
+	l.with(args).Error(msg)
+}
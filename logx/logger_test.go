@@ -0,0 +1,41 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/logrusx"
+)
+
+func TestFromLogrus(t *testing.T) {
+	hook := &test.Hook{}
+	l := FromLogrus(logrusx.New("", "", logrusx.WithHook(hook), logrusx.ForceLevel(logrus.DebugLevel)))
+
+	l.Info("request completed", "status", 200, "path", "/")
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.InfoLevel, entry.Level)
+	assert.Equal(t, "request completed", entry.Message)
+	assert.Equal(t, 200, entry.Data["status"])
+	assert.Equal(t, "/", entry.Data["path"])
+
+	l.Error("query failed", "error", errors.New("connection refused"), 42)
+	entry = hook.LastEntry()
+	assert.Equal(t, logrus.ErrorLevel, entry.Level)
+	assert.Equal(t, "connection refused", entry.Data["error"].(map[string]interface{})["message"])
+	assert.Equal(t, 42, entry.Data["!BADKEY"])
+
+	l.Debug("debug")
+	l.Warn("warn")
+	assert.Len(t, hook.AllEntries(), 4)
+}
+
+func TestNop(t *testing.T) {
+	var l Logger = Nop()
+	l.Info("discarded", "key", "value")
+}
@@ -15,14 +15,27 @@ type JaegerSampling struct {
 	ServerURL string `json:"server_url"`
 }
 
+type OTLPConfig struct {
+	ServerURL string       `json:"server_url"`
+	Insecure  bool         `json:"insecure"`
+	Sampling  OTLPSampling `json:"sampling"`
+}
+
+type OTLPSampling struct {
+	// SamplingRatio is the ratio of sampled root spans. Defaults to 1 if nil.
+	SamplingRatio *float64 `json:"sampling_ratio"`
+}
+
 type ProvidersConfig struct {
 	Jaeger JaegerConfig `json:"jaeger"`
+	OTLP   OTLPConfig   `json:"otlp"`
 }
 
 type Config struct {
-	ServiceName string          `json:"service_name"`
-	Provider    string          `json:"provider"`
-	Providers   ProvidersConfig `json:"providers"`
+	ServiceName        string            `json:"service_name"`
+	ResourceAttributes map[string]string `json:"resource_attributes,omitempty"`
+	Provider           string            `json:"provider"`
+	Providers          ProvidersConfig   `json:"providers"`
 }
 
 //go:embed config.schema.json
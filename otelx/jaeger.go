@@ -9,9 +9,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/jaeger"
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
@@ -33,12 +31,14 @@ func SetupJaeger(t *Tracer, tracerName string) (trace.Tracer, error) {
 		return nil, err
 	}
 
+	res, err := t.resource()
+	if err != nil {
+		return nil, err
+	}
+
 	tpOpts := []sdktrace.TracerProviderOption{
 		sdktrace.WithBatcher(exp),
-		sdktrace.WithResource(resource.NewWithAttributes(
-			semconv.SchemaURL,
-			semconv.ServiceNameKey.String(t.Config.ServiceName),
-		)),
+		sdktrace.WithResource(res),
 	}
 
 	samplingServerURL := t.Config.Providers.Jaeger.Sampling.ServerURL
@@ -54,6 +54,7 @@ func SetupJaeger(t *Tracer, tracerName string) (trace.Tracer, error) {
 	}
 	tp := sdktrace.NewTracerProvider(tpOpts...)
 	otel.SetTracerProvider(tp)
+	t.onShutdown(tp.Shutdown)
 
 	// At the moment, software across our cloud stack only support Zipkin (B3)
 	// and Jaeger propagation formats. Proposals for standardized formats for
@@ -1,16 +1,25 @@
 package otelx
 
 import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+
 	"github.com/ory/x/logrusx"
 	"github.com/ory/x/stringsx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 type Tracer struct {
 	Config *Config
 
-	l      *logrusx.Logger
-	tracer trace.Tracer
+	l        *logrusx.Logger
+	tracer   trace.Tracer
+	shutdown []func(context.Context) error
 }
 
 // Creates a new tracer. If name is empty, a default tracer name is used
@@ -43,6 +52,14 @@ func (t *Tracer) setup(name string) error {
 
 		t.tracer = tracer
 		t.l.Infof("Jaeger tracer configured! Sending spans to %s", t.Config.Providers.Jaeger.LocalAgentAddress)
+	case f.AddCase("otlp"):
+		tracer, err := SetupOTLP(t, name)
+		if err != nil {
+			return err
+		}
+
+		t.tracer = tracer
+		t.l.Infof("OTLP tracer configured! Sending spans and metrics to %s", t.Config.Providers.OTLP.ServerURL)
 	case f.AddCase(""):
 		t.l.Infof("No tracer configured - skipping tracing setup")
 	default:
@@ -63,3 +80,64 @@ func (t *Tracer) IsLoaded() bool {
 func (t *Tracer) Tracer() trace.Tracer {
 	return t.tracer
 }
+
+// resource returns the resource describing this service to the tracing backend.
+func (t *Tracer) resource() (*resource.Resource, error) {
+	attrs := []attribute.KeyValue{semconv.ServiceNameKey.String(t.Config.ServiceName)}
+	for k, v := range t.Config.ResourceAttributes {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	return resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attrs...))
+}
+
+func (t *Tracer) onShutdown(f func(context.Context) error) {
+	t.shutdown = append(t.shutdown, f)
+}
+
+// Shutdown flushes all pending spans and metrics and stops the exporters. All exporters are stopped,
+// also if some of them fail, and their errors are returned together.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	var errs shutdownErrors
+	for i := len(t.shutdown) - 1; i >= 0; i-- {
+		if err := t.shutdown[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.shutdown = nil
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+// shutdownErrors are the errors of several exporters which failed to stop.
+type shutdownErrors []error
+
+func (e shutdownErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, for Go versions supporting joined errors.
+func (e shutdownErrors) Unwrap() []error {
+	return e
+}
+
+// Is matches all errors, also if the Go version does not support joined errors.
+func (e shutdownErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/ory/x/logrusx"
 	"github.com/phayes/freeport"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
@@ -77,3 +79,23 @@ func TestJaegerTracer(t *testing.T) {
 	}
 	require.NoError(t, errs.Wait())
 }
+
+func TestShutdown(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	var stopped []int
+	ot := &Tracer{}
+	for i, err := range []error{first, nil, second} {
+		i, err := i, err
+		ot.onShutdown(func(context.Context) error {
+			stopped = append(stopped, i)
+			return err
+		})
+	}
+
+	err := ot.Shutdown(context.Background())
+	assert.Equal(t, []int{2, 1, 0}, stopped, "all exporters are stopped")
+	assert.ErrorIs(t, err, first)
+	assert.ErrorIs(t, err, second)
+	assert.EqualError(t, err, "second; first")
+	assert.NoError(t, ot.Shutdown(context.Background()))
+}
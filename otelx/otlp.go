@@ -0,0 +1,82 @@
+package otelx
+
+import (
+	"context"
+
+	"go.opentelemetry.io/contrib/propagators/b3"
+	jaegerPropagator "go.opentelemetry.io/contrib/propagators/jaeger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric/global"
+	"go.opentelemetry.io/otel/propagation"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetupOTLP sets up the global TracerProvider and MeterProvider to export spans and metrics
+// to Config.Providers.OTLP.ServerURL using OTLP over HTTP. The proxy's spans as well as the
+// SQL instrumentation use the global providers, so they end up in the same traces.
+//
+// NOTE: If Config.Providers.OTLP.Sampling.SamplingRatio is not specified, all traces are
+// sampled, while a ratio of 0 samples none. Traces whose parent was sampled are always sampled.
+func SetupOTLP(t *Tracer, tracerName string) (trace.Tracer, error) {
+	ctx := context.Background()
+	c := t.Config.Providers.OTLP
+
+	traceOpts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.ServerURL)}
+	metricOpts := []otlpmetrichttp.Option{otlpmetrichttp.WithEndpoint(c.ServerURL)}
+	if c.Insecure {
+		traceOpts = append(traceOpts, otlptracehttp.WithInsecure())
+		metricOpts = append(metricOpts, otlpmetrichttp.WithInsecure())
+	}
+
+	traceExp, err := otlptracehttp.New(ctx, traceOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := t.resource()
+	if err != nil {
+		return nil, err
+	}
+
+	ratio := 1.0
+	if c.Sampling.SamplingRatio != nil {
+		ratio = *c.Sampling.SamplingRatio
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(traceExp),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(tp)
+	t.onShutdown(tp.Shutdown)
+
+	metricExp, err := otlpmetrichttp.New(ctx, metricOpts...)
+	if err != nil {
+		return nil, err
+	}
+	mp := controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(), metricExp),
+		controller.WithExporter(metricExp),
+		controller.WithResource(res),
+	)
+	if err := mp.Start(ctx); err != nil {
+		return nil, err
+	}
+	global.SetMeterProvider(mp)
+	t.onShutdown(mp.Stop)
+
+	prop := propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+		jaegerPropagator.Jaeger{},
+		b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader|b3.B3SingleHeader)),
+	)
+	otel.SetTextMapPropagator(prop)
+	return tp.Tracer(tracerName), nil
+}
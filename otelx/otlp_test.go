@@ -0,0 +1,96 @@
+package otelx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/urlx"
+)
+
+func TestOTLPTracer(t *testing.T) {
+	received := make(chan *coltracepb.ExportTraceServiceRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if r.URL.Path != "/v1/traces" {
+			return
+		}
+
+		var req coltracepb.ExportTraceServiceRequest
+		require.NoError(t, proto.Unmarshal(body, &req))
+		received <- &req
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	ot, err := New("github.com/ory/x/otelx", logrusx.New("ory/x", "1"), &Config{
+		ServiceName:        "Ory X",
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+		Provider:           "otlp",
+		Providers: ProvidersConfig{
+			OTLP: OTLPConfig{
+				ServerURL: u.Host,
+				Insecure:  true,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	_, span := ot.Tracer().Start(context.Background(), "testSpan")
+	assert.True(t, span.SpanContext().IsSampled(), "all traces are sampled by default")
+	span.SetAttributes(attribute.Bool("testAttribute", true))
+	span.End()
+	require.NoError(t, ot.Shutdown(context.Background()))
+
+	select {
+	case req := <-received:
+		require.Len(t, req.ResourceSpans, 1)
+		attrs := map[string]string{}
+		for _, kv := range req.ResourceSpans[0].Resource.Attributes {
+			attrs[kv.Key] = kv.Value.GetStringValue()
+		}
+		assert.Equal(t, "Ory X", attrs["service.name"])
+		assert.Equal(t, "test", attrs["deployment.environment"])
+		assert.Equal(t, "testSpan", req.ResourceSpans[0].InstrumentationLibrarySpans[0].Spans[0].Name)
+	case <-time.After(15 * time.Second):
+		t.Fatal("expected to receive span, but did not receive any")
+	}
+}
+
+func TestOTLPSamplingRatio(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	ratio := 0.0
+	ot, err := New("github.com/ory/x/otelx", logrusx.New("ory/x", "1"), &Config{
+		ServiceName: "Ory X",
+		Provider:    "otlp",
+		Providers: ProvidersConfig{
+			OTLP: OTLPConfig{
+				ServerURL: urlx.ParseOrPanic(srv.URL).Host,
+				Insecure:  true,
+				Sampling:  OTLPSampling{SamplingRatio: &ratio},
+			},
+		},
+	})
+	require.NoError(t, err)
+	defer ot.Shutdown(context.Background())
+
+	_, span := ot.Tracer().Start(context.Background(), "testSpan")
+	defer span.End()
+	assert.False(t, span.SpanContext().IsSampled(), "a ratio of 0 samples no traces")
+}
@@ -0,0 +1,34 @@
+package sql
+
+import (
+	"database/sql/driver"
+
+	"github.com/luna-duclos/instrumentedsql"
+
+	"github.com/ory/x/sqlcon"
+)
+
+// SanitizeQuery replaces string and numeric literals in the query with "?", so that
+// statements can be recorded without the values they contain. See sqlcon.RedactLiterals.
+func SanitizeQuery(query string) string {
+	return sqlcon.RedactLiterals(query)
+}
+
+// WrapDriver wraps the driver so that queries, execs and transactions create spans, which are children of
+// the span in the context. The spans contain the statements sanitized by SanitizeQuery but no arguments,
+// and errors are redacted by sqlcon.RedactError. Only calls with a context are traced. The options are applied
+// after the defaults, so WithOpsExcluded replaces the exclusion of OpSQLRowsNext, and
+// instrumentedsql.WithTracer(NewTracer(WithRedactor(sqlcon.RedactAll))) replaces the redaction of statements.
+//
+// Register the returned driver with sql.Register:
+//
+//	sql.Register("pgx-traced", otelsql.WrapDriver(stdlib.GetDefaultDriver()))
+//	db, err := sql.Open("pgx-traced", dsn)
+func WrapDriver(d driver.Driver, opts ...instrumentedsql.Opt) driver.Driver {
+	return instrumentedsql.WrapDriver(d, append([]instrumentedsql.Opt{
+		instrumentedsql.WithTracer(NewTracer()),
+		instrumentedsql.WithOmitArgs(),
+		// one span per row is too much
+		instrumentedsql.WithOpsExcluded(instrumentedsql.OpSQLRowsNext),
+	}, opts...)...)
+}
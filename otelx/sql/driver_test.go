@@ -0,0 +1,137 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/luna-duclos/instrumentedsql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/ory/x/sqlcon"
+)
+
+type (
+	fakeDriver struct{}
+	fakeConn   struct{}
+	fakeTx     struct{}
+	fakeResult struct{}
+)
+
+func (fakeDriver) Open(string) (driver.Conn, error)  { return fakeConn{}, nil }
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+func (fakeTx) Commit() error                         { return nil }
+func (fakeTx) Rollback() error                       { return nil }
+func (fakeResult) LastInsertId() (int64, error)      { return 0, nil }
+func (fakeResult) RowsAffected() (int64, error)      { return 1, nil }
+func (fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if query == "INSERT INTO users (email) VALUES ('foo@bar.com')" {
+		return nil, &pq.Error{Code: "23505", Detail: "Key (email)=(foo@bar.com) already exists."}
+	}
+	if query == "SELECT * FROM users WHERE id = 'foo@bar.com'" {
+		return nil, &pq.Error{Code: "22P02", Message: `invalid input syntax for type uuid: "foo@bar.com"`}
+	}
+	return fakeResult{}, nil
+}
+
+func init() {
+	sql.Register("otelx-fake", WrapDriver(fakeDriver{}))
+	sql.Register("otelx-fake-redact-all", WrapDriver(fakeDriver{}, instrumentedsql.WithTracer(NewTracer(WithRedactor(sqlcon.RedactAll)))))
+}
+
+func TestWrapDriver(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, err := sql.Open("otelx-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, parent := otel.Tracer("").Start(context.Background(), "x.proxy")
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "UPDATE users SET age = 42 WHERE id = $1", 1)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	_, err = db.ExecContext(ctx, "INSERT INTO users (email) VALUES ('foo@bar.com')")
+	require.Error(t, err)
+	parent.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+		if span.Name() != "x.proxy" {
+			assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID(), span.Name())
+		}
+	}
+	require.Contains(t, spans, "sql-tx-begin")
+	require.Contains(t, spans, "sql-tx-commit")
+
+	require.Contains(t, spans, "sql-conn-exec")
+	var queries []string
+	for _, span := range recorder.Ended() {
+		for _, a := range span.Attributes() {
+			if a.Key == "query" {
+				queries = append(queries, a.Value.AsString())
+			}
+			assert.NotEqual(t, attribute.Key("args"), a.Key)
+		}
+	}
+	assert.ElementsMatch(t, []string{"UPDATE users SET age = ? WHERE id = $1", "INSERT INTO users (email) VALUES (?)"}, queries)
+
+	var failed sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Status().Code == codes.Error {
+			failed = span
+		}
+	}
+	require.NotNil(t, failed)
+	assert.Equal(t, sqlcon.ErrUniqueViolation.Error(), failed.Status().Description)
+	assert.NotContains(t, failed.Status().Description, "foo@bar.com")
+}
+
+func TestWrapDriverRedaction(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	db, err := sql.Open("otelx-fake-redact-all", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	ctx, parent := otel.Tracer("").Start(context.Background(), "x.proxy")
+	_, err = db.ExecContext(ctx, "SELECT * FROM users WHERE id = 'foo@bar.com'")
+	require.Error(t, err)
+	parent.End()
+
+	var failed sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Status().Code == codes.Error {
+			failed = span
+		}
+	}
+	require.NotNil(t, failed)
+	assert.Contains(t, failed.Attributes(), attribute.String("query", "SELECT"))
+	assert.Equal(t, "pq: invalid input syntax for type uuid: ?", failed.Status().Description)
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	for query, expected := range map[string]string{
+		"SELECT * FROM users WHERE email = 'foo@bar.com' AND name = 'O''Brien'": "SELECT * FROM users WHERE email = ? AND name = ?",
+		"SELECT * FROM users WHERE id = $1 LIMIT 10 OFFSET -5":                  "SELECT * FROM users WHERE id = $1 LIMIT ? OFFSET ?",
+		"SELECT v2.col1 FROM table2 v2 WHERE price > 1.5":                       "SELECT v2.col1 FROM table2 v2 WHERE price > ?",
+		"INSERT INTO t (a, b) VALUES (?, 3)":                                    "INSERT INTO t (a, b) VALUES (?, ?)",
+	} {
+		assert.Equal(t, expected, SanitizeQuery(query))
+	}
+}
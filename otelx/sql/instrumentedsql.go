@@ -9,11 +9,19 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/x/sqlcon"
 )
 
 const tracingComponent = "github.com/ory/x/otelx/sql"
 
-type tracer struct{}
+type (
+	tracer struct {
+		redactor sqlcon.Redactor
+	}
+	// TracerOption configures NewTracer.
+	TracerOption func(*tracer)
+)
 
 type span struct {
 	tracer
@@ -21,7 +29,21 @@ type span struct {
 	parent trace.Span
 }
 
-func NewTracer() instrumentedsql.Tracer { return tracer{} }
+// WithRedactor sets the redactor of the statements recorded in spans.
+// Default: sqlcon.RedactLiterals
+func WithRedactor(r sqlcon.Redactor) TracerOption {
+	return func(t *tracer) {
+		t.redactor = r
+	}
+}
+
+func NewTracer(opts ...TracerOption) instrumentedsql.Tracer {
+	t := tracer{}
+	for _, f := range opts {
+		f(&t)
+	}
+	return t
+}
 
 // GetSpan returns a span
 func (t tracer) GetSpan(ctx context.Context) instrumentedsql.Span {
@@ -29,7 +51,7 @@ func (t tracer) GetSpan(ctx context.Context) instrumentedsql.Span {
 		return span{ctx: nil}
 	}
 
-	return span{parent: trace.SpanFromContext(ctx), tracer: t}
+	return span{ctx: ctx, parent: trace.SpanFromContext(ctx), tracer: t}
 }
 
 func (s span) NewChild(name string) instrumentedsql.Span {
@@ -41,13 +63,16 @@ func (s span) NewChild(name string) instrumentedsql.Span {
 	tp := otel.GetTracerProvider().Tracer(tracingComponent)
 	_, parent = tp.Start(s.ctx, name)
 
-	return span{ctx: s.ctx, parent: parent}
+	return span{ctx: s.ctx, parent: parent, tracer: s.tracer}
 }
 
 func (s span) SetLabel(k, v string) {
 	if s.parent == nil {
 		return
 	}
+	if k == "query" {
+		v = s.redactor.Redact(v)
+	}
 	s.parent.SetAttributes(attribute.String(k, v))
 }
 
@@ -60,6 +85,7 @@ func (s span) SetError(err error) {
 		return
 	}
 
+	err = sqlcon.RedactError(err)
 	s.parent.SetStatus(codes.Error, err.Error())
 	s.parent.AddEvent("error", trace.WithAttributes(
 		attribute.String("message", err.Error())),
@@ -0,0 +1,206 @@
+// Package keysetpagination paginates by the key of the last item of a page instead of an offset, which
+// keeps queries fast on large tables:
+//
+//	p := &keysetpagination.Paginator{Dialect: "postgres", Columns: []keysetpagination.Column{
+//		{Name: "created_at", Descending: true},
+//		{Name: "id"},
+//	}}
+//	where, args, err := p.WhereToken(r.URL.Query().Get("page_token"), 0)
+//	order, err := p.OrderBy()
+//	// SELECT ... WHERE <where> ORDER BY <order> LIMIT 100
+//	next, err := keysetpagination.EncodeToken(keysetpagination.Cursor{last.CreatedAt, last.ID})
+package keysetpagination
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidToken is returned if a page token can not be decoded or does not match the columns.
+var ErrInvalidToken = errors.New("invalid page token")
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+type (
+	// Column is a column of the key. The key must be unique, e.g. by ending with the primary key,
+	// and its columns must not be NULL.
+	Column struct {
+		// Name is the column name, optionally qualified by the table, e.g. "users.id".
+		Name string
+		// Descending orders the column descending.
+		Descending bool
+	}
+	// Cursor are the values of the key columns of the last item of a page, in the order of the columns.
+	Cursor []interface{}
+	// Paginator builds the WHERE and ORDER BY clauses of a key.
+	Paginator struct {
+		// Dialect determines the placeholders and identifier quotes, e.g. "postgres", "cockroach",
+		// "mysql", "sqlite3" or "sqlserver".
+		Dialect string
+		// Columns are the columns of the key, in the order of significance.
+		Columns []Column
+		// Codec verifies the page tokens of WhereToken if set, which must then be created by TokenCodec.EncodeCursor.
+		Codec *TokenCodec
+	}
+)
+
+// EncodeToken encodes the cursor as an opaque, URL safe page token.
+func EncodeToken(c Cursor) (string, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeToken decodes a page token created by EncodeToken. As the values are encoded as JSON, numbers
+// are returned as int64 or float64, and times as strings in RFC 3339 format.
+func DecodeToken(token string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.WithStack(ErrInvalidToken)
+	}
+
+	var c Cursor
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&c); err != nil {
+		return nil, errors.WithStack(ErrInvalidToken)
+	}
+	return normalizeNumbers(c)
+}
+
+// normalizeNumbers converts the numbers decoded as json.Number to int64 or float64.
+func normalizeNumbers(c Cursor) (Cursor, error) {
+	for i, v := range c {
+		n, ok := v.(json.Number)
+		if !ok {
+			continue
+		}
+		var err error
+		if c[i], err = n.Int64(); err != nil {
+			if c[i], err = n.Float64(); err != nil {
+				return nil, errors.WithStack(ErrInvalidToken)
+			}
+		}
+	}
+	return c, nil
+}
+
+func (p *Paginator) quote(name string) (string, error) {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if !identifier.MatchString(part) {
+			return "", errors.Errorf("invalid column name %q", name)
+		}
+		switch p.Dialect {
+		case "mysql":
+			parts[i] = "`" + part + "`"
+		case "sqlserver":
+			parts[i] = "[" + part + "]"
+		default:
+			parts[i] = `"` + part + `"`
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+func (p *Paginator) placeholder(n int) string {
+	switch p.Dialect {
+	case "postgres", "cockroach":
+		return fmt.Sprintf("$%d", n)
+	case "sqlserver":
+		return fmt.Sprintf("@p%d", n)
+	default:
+		return "?"
+	}
+}
+
+// OrderBy returns the ORDER BY clause without the keywords, e.g. `"created_at" DESC, "id" ASC`.
+func (p *Paginator) OrderBy() (string, error) {
+	order := make([]string, len(p.Columns))
+	for i, c := range p.Columns {
+		name, err := p.quote(c.Name)
+		if err != nil {
+			return "", err
+		}
+		direction := "ASC"
+		if c.Descending {
+			direction = "DESC"
+		}
+		order[i] = name + " " + direction
+	}
+	return strings.Join(order, ", "), nil
+}
+
+// Where returns the WHERE condition without the keyword which selects the items after the cursor, and its
+// arguments. Placeholders are numbered after the offset, which is the number of arguments preceding them
+// in the query. It returns an empty condition without a cursor, but ErrInvalidToken if the cursor does not
+// match the columns.
+//
+// The condition is expanded, e.g. `("a" > $1) OR ("a" = $2 AND "b" < $3)`, as not all dialects support row
+// value comparisons and they can not mix directions.
+func (p *Paginator) Where(c Cursor, offset int) (string, []interface{}, error) {
+	if len(c) == 0 {
+		return "", nil, nil
+	}
+	if len(c) != len(p.Columns) {
+		return "", nil, errors.WithStack(ErrInvalidToken)
+	}
+
+	names := make([]string, len(p.Columns))
+	for i, column := range p.Columns {
+		var err error
+		if names[i], err = p.quote(column.Name); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var (
+		or   []string
+		args []interface{}
+	)
+	for i, column := range p.Columns {
+		and := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			args = append(args, c[j])
+			and = append(and, names[j]+" = "+p.placeholder(offset+len(args)))
+		}
+
+		operator := " > "
+		if column.Descending {
+			operator = " < "
+		}
+		args = append(args, c[i])
+		and = append(and, names[i]+operator+p.placeholder(offset+len(args)))
+		or = append(or, "("+strings.Join(and, " AND ")+")")
+	}
+	return strings.Join(or, " OR "), args, nil
+}
+
+// WhereToken is like Where, but decodes the cursor from the page token first, verifying it if Codec is set.
+// An empty token selects the first page.
+func (p *Paginator) WhereToken(token string, offset int) (string, []interface{}, error) {
+	if token == "" {
+		return "", nil, nil
+	}
+	var (
+		c   Cursor
+		err error
+	)
+	if p.Codec != nil {
+		c, err = p.Codec.DecodeCursor(token)
+	} else {
+		c, err = DecodeToken(token)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return p.Where(c, offset)
+}
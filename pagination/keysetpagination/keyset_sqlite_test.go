@@ -0,0 +1,56 @@
+//go:build sqlite
+// +build sqlite
+
+package keysetpagination
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginatorSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, rank INTEGER NOT NULL);
+INSERT INTO items (id, rank) VALUES (1, 2), (2, 1), (3, 2), (4, 3), (5, 1), (6, 2), (7, 3)`)
+	require.NoError(t, err)
+
+	p := &Paginator{Dialect: "sqlite3", Columns: []Column{{Name: "rank", Descending: true}, {Name: "id"}}}
+	order, err := p.OrderBy()
+	require.NoError(t, err)
+
+	var ids []int64
+	token := ""
+	for page := 0; page < 10; page++ {
+		where, args, err := p.WhereToken(token, 0)
+		require.NoError(t, err)
+		if where == "" {
+			where = "1 = 1"
+		}
+
+		rows, err := db.Query("SELECT id, rank FROM items WHERE "+where+" ORDER BY "+order+" LIMIT 3", args...)
+		require.NoError(t, err)
+		var last Cursor
+		for rows.Next() {
+			var id, rank int64
+			require.NoError(t, rows.Scan(&id, &rank))
+			ids = append(ids, id)
+			last = Cursor{rank, id}
+		}
+		require.NoError(t, rows.Close())
+
+		if last == nil {
+			break
+		}
+		token, err = EncodeToken(last)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []int64{4, 7, 1, 3, 6, 2, 5}, ids)
+}
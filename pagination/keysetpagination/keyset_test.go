@@ -0,0 +1,60 @@
+package keysetpagination
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToken(t *testing.T) {
+	token, err := EncodeToken(Cursor{"2022-06-01T10:00:00Z", int64(1) << 60, 1.5, true})
+	require.NoError(t, err)
+
+	c, err := DecodeToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, Cursor{"2022-06-01T10:00:00Z", int64(1) << 60, 1.5, true}, c)
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", "eyJhIjoxfQ"} {
+		_, err := DecodeToken(token)
+		assert.ErrorIs(t, err, ErrInvalidToken, token)
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	columns := []Column{{Name: "users.created_at", Descending: true}, {Name: "id"}}
+
+	for dialect, expected := range map[string]struct{ where, order string }{
+		"postgres":  {where: `("users"."created_at" < $3) OR ("users"."created_at" = $4 AND "id" > $5)`, order: `"users"."created_at" DESC, "id" ASC`},
+		"mysql":     {where: "(`users`.`created_at` < ?) OR (`users`.`created_at` = ? AND `id` > ?)", order: "`users`.`created_at` DESC, `id` ASC"},
+		"sqlserver": {where: `([users].[created_at] < @p3) OR ([users].[created_at] = @p4 AND [id] > @p5)`, order: `[users].[created_at] DESC, [id] ASC`},
+		"sqlite3":   {where: `("users"."created_at" < ?) OR ("users"."created_at" = ? AND "id" > ?)`, order: `"users"."created_at" DESC, "id" ASC`},
+	} {
+		t.Run("dialect="+dialect, func(t *testing.T) {
+			p := &Paginator{Dialect: dialect, Columns: columns}
+
+			where, args, err := p.Where(Cursor{"2022", int64(7)}, 2)
+			require.NoError(t, err)
+			assert.Equal(t, expected.where, where)
+			assert.Equal(t, []interface{}{"2022", "2022", int64(7)}, args)
+
+			order, err := p.OrderBy()
+			require.NoError(t, err)
+			assert.Equal(t, expected.order, order)
+		})
+	}
+
+	p := &Paginator{Dialect: "postgres", Columns: columns}
+	where, args, err := p.WhereToken("", 0)
+	require.NoError(t, err)
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	token, err := EncodeToken(Cursor{"2022"})
+	require.NoError(t, err)
+	_, _, err = p.WhereToken(token, 0)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, _, err = (&Paginator{Columns: []Column{{Name: "id; DROP TABLE users"}}}).Where(Cursor{1}, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package keysetpagination
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// tokenVersion prefixes signed page tokens, so the format can be changed without accepting old tokens by mistake.
+const tokenVersion = "1"
+
+// ErrTokenExpired is returned if a signed page token is valid but expired.
+var ErrTokenExpired = errors.New("page token expired")
+
+type (
+	// TokenCodec signs and verifies page tokens using HMAC-SHA256, so clients can not forge cursors. Tokens
+	// contain arbitrary JSON encodable fields, which are not encrypted, so they must not contain secrets.
+	TokenCodec struct {
+		// Secrets are the HMAC keys. The first one signs tokens, while all of them are accepted, which allows
+		// rotating secrets.
+		Secrets [][]byte
+		// TTL is the time a token is valid. Tokens do not expire if it is zero.
+		TTL time.Duration
+
+		now func() time.Time
+	}
+	signedPayload struct {
+		ExpiresAt int64           `json:"e,omitempty"`
+		Fields    json.RawMessage `json:"f"`
+	}
+)
+
+func (c *TokenCodec) timeNow() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+func sign(secret []byte, message string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(message))
+	return mac.Sum(nil)
+}
+
+// Encode signs the fields, e.g. a struct with the cursor and filters of the query, as a URL safe token.
+func (c *TokenCodec) Encode(fields interface{}) (string, error) {
+	if len(c.Secrets) == 0 {
+		return "", errors.New("no secret for signing page tokens configured")
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	p := signedPayload{Fields: raw}
+	if c.TTL > 0 {
+		p.ExpiresAt = c.timeNow().Add(c.TTL).Unix()
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+
+	message := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return message + "." + base64.RawURLEncoding.EncodeToString(sign(c.Secrets[0], message)), nil
+}
+
+// Decode verifies the token and decodes its fields into v. It returns ErrInvalidToken if the token was not
+// signed with one of the secrets, and ErrTokenExpired if it expired.
+func (c *TokenCodec) Decode(token string, v interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenVersion {
+		return errors.WithStack(ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errors.WithStack(ErrInvalidToken)
+	}
+
+	message := parts[0] + "." + parts[1]
+	valid := false
+	for _, secret := range c.Secrets {
+		if hmac.Equal(signature, sign(secret, message)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.WithStack(ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return errors.WithStack(ErrInvalidToken)
+	}
+	var p signedPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return errors.WithStack(ErrInvalidToken)
+	}
+	if p.ExpiresAt != 0 && !c.timeNow().Before(time.Unix(p.ExpiresAt, 0)) {
+		return errors.WithStack(ErrTokenExpired)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(p.Fields))
+	d.UseNumber()
+	if err := d.Decode(v); err != nil {
+		return errors.WithStack(ErrInvalidToken)
+	}
+	return nil
+}
+
+// EncodeCursor signs the cursor. Use it instead of EncodeToken together with Paginator.Codec.
+func (c *TokenCodec) EncodeCursor(cursor Cursor) (string, error) {
+	return c.Encode(cursor)
+}
+
+// DecodeCursor verifies a token created by EncodeCursor. Numbers are returned like by DecodeToken.
+func (c *TokenCodec) DecodeCursor(token string) (Cursor, error) {
+	var cursor Cursor
+	if err := c.Decode(token, &cursor); err != nil {
+		return nil, err
+	}
+	return normalizeNumbers(cursor)
+}
@@ -0,0 +1,67 @@
+package keysetpagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCodec(t *testing.T) {
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	c := &TokenCodec{Secrets: [][]byte{[]byte("secret")}, TTL: time.Hour, now: func() time.Time { return now }}
+
+	type fields struct {
+		Cursor Cursor `json:"c"`
+		Filter string `json:"q"`
+	}
+	token, err := c.Encode(fields{Cursor: Cursor{"a", 1}, Filter: "active"})
+	require.NoError(t, err)
+
+	var decoded fields
+	require.NoError(t, c.Decode(token, &decoded))
+	assert.Equal(t, "active", decoded.Filter)
+	assert.Len(t, decoded.Cursor, 2)
+
+	t.Run("case=rotated secrets are accepted", func(t *testing.T) {
+		rotated := &TokenCodec{Secrets: [][]byte{[]byte("new"), []byte("secret")}, now: c.now}
+		assert.NoError(t, rotated.Decode(token, &decoded))
+
+		other := &TokenCodec{Secrets: [][]byte{[]byte("new")}, now: c.now}
+		assert.ErrorIs(t, other.Decode(token, &decoded), ErrInvalidToken)
+	})
+
+	t.Run("case=forged tokens are rejected", func(t *testing.T) {
+		forged, err := (&TokenCodec{Secrets: [][]byte{[]byte("guessed")}}).Encode(fields{Filter: "all"})
+		require.NoError(t, err)
+
+		for _, token := range []string{forged, "", "1.e30.", "2" + token[1:], token[:len(token)-2], "unsigned"} {
+			assert.ErrorIs(t, c.Decode(token, &decoded), ErrInvalidToken, token)
+		}
+	})
+
+	t.Run("case=tokens expire", func(t *testing.T) {
+		expired := &TokenCodec{Secrets: c.Secrets, now: func() time.Time { return now.Add(time.Hour) }}
+		assert.ErrorIs(t, expired.Decode(token, &decoded), ErrTokenExpired)
+	})
+
+	t.Run("case=paginator verifies cursors", func(t *testing.T) {
+		p := &Paginator{Dialect: "postgres", Columns: []Column{{Name: "id"}}, Codec: c}
+		token, err := c.EncodeCursor(Cursor{int64(42)})
+		require.NoError(t, err)
+
+		where, args, err := p.WhereToken(token, 0)
+		require.NoError(t, err)
+		assert.Equal(t, `("id" > $1)`, where)
+		assert.Equal(t, []interface{}{int64(42)}, args)
+
+		unsigned, err := EncodeToken(Cursor{int64(42)})
+		require.NoError(t, err)
+		_, _, err = p.WhereToken(unsigned, 0)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	_, err = (&TokenCodec{}).Encode(fields{})
+	assert.Error(t, err)
+}
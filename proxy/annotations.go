@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/x/logrusx"
+)
+
+// Annotations is a per-request store which middlewares use to pass information to later middlewares,
+// loggers and traces, e.g. "user_id" or "rewrites_applied". It is safe for concurrent use, and all
+// methods are no-ops on a nil store.
+type Annotations struct {
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+const annotationsKey contextKey = "annotations"
+
+// WithAnnotations returns a context with an empty annotation store, unless the context already has one.
+// The proxy does this for every request, but handlers wrapping the proxy (e.g. access loggers) can
+// call it beforehand to read the annotations once the request completes.
+func WithAnnotations(ctx context.Context) context.Context {
+	if AnnotationsFromContext(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, annotationsKey, &Annotations{values: map[string]interface{}{}})
+}
+
+// AnnotationsFromContext returns the annotation store of the request, or nil if there is none.
+func AnnotationsFromContext(ctx context.Context) *Annotations {
+	a, _ := ctx.Value(annotationsKey).(*Annotations)
+	return a
+}
+
+// Annotate is a shorthand for setting an annotation on the request.
+func Annotate(r *http.Request, key string, value interface{}) {
+	AnnotationsFromContext(r.Context()).Set(key, value)
+}
+
+// Set sets the annotation, replacing any previous value.
+func (a *Annotations) Set(key string, value interface{}) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.values[key] = value
+}
+
+// Append appends the values to the annotation, which becomes a []interface{}.
+// A previous non-list value is kept as the first element.
+func (a *Annotations) Append(key string, values ...interface{}) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch v := a.values[key].(type) {
+	case nil:
+		a.values[key] = append([]interface{}{}, values...)
+	case []interface{}:
+		a.values[key] = append(v, values...)
+	default:
+		a.values[key] = append([]interface{}{v}, values...)
+	}
+}
+
+// Get returns the annotation.
+func (a *Annotations) Get(key string) (interface{}, bool) {
+	if a == nil {
+		return nil, false
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	v, ok := a.values[key]
+	return v, ok
+}
+
+// GetString returns the annotation if it is a string.
+func (a *Annotations) GetString(key string) (string, bool) {
+	v, _ := a.Get(key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// GetInt returns the annotation if it is an int.
+func (a *Annotations) GetInt(key string) (int, bool) {
+	v, _ := a.Get(key)
+	i, ok := v.(int)
+	return i, ok
+}
+
+// GetBool returns the annotation if it is a bool.
+func (a *Annotations) GetBool(key string) (bool, bool) {
+	v, _ := a.Get(key)
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// Delete removes the annotation.
+func (a *Annotations) Delete(key string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.values, key)
+}
+
+// All returns a copy of all annotations.
+func (a *Annotations) All() map[string]interface{} {
+	all := map[string]interface{}{}
+	if a == nil {
+		return all
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for k, v := range a.values {
+		if l, ok := v.([]interface{}); ok {
+			v = append([]interface{}{}, l...)
+		}
+		all[k] = v
+	}
+	return all
+}
+
+// LogFields returns the annotations as log fields.
+func (a *Annotations) LogFields() logrus.Fields {
+	return a.All()
+}
+
+// WithLogger adds the annotations to the logger under the "annotations" field.
+func (a *Annotations) WithLogger(l *logrusx.Logger) *logrusx.Logger {
+	if a == nil {
+		return l
+	}
+	return l.WithField("annotations", a.LogFields())
+}
+
+// SpanAttributes returns the annotations as span attributes prefixed with "proxy.annotation.".
+// The attributes are sorted by key.
+func (a *Annotations) SpanAttributes() []attribute.KeyValue {
+	all := a.All()
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]attribute.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		key := attribute.Key("proxy.annotation." + k)
+		switch v := all[k].(type) {
+		case string:
+			attrs = append(attrs, key.String(v))
+		case int:
+			attrs = append(attrs, key.Int(v))
+		case int64:
+			attrs = append(attrs, key.Int64(v))
+		case bool:
+			attrs = append(attrs, key.Bool(v))
+		case float64:
+			attrs = append(attrs, key.Float64(v))
+		default:
+			attrs = append(attrs, key.String(fmt.Sprint(v)))
+		}
+	}
+	return attrs
+}
+
+// AnnotateSpan adds the annotations as attributes to the span.
+func (a *Annotations) AnnotateSpan(span trace.Span) {
+	if a == nil {
+		return
+	}
+	span.SetAttributes(a.SpanAttributes()...)
+}
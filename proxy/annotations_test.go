@@ -0,0 +1,85 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestAnnotations(t *testing.T) {
+	t.Run("case=passed from request to response middlewares", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer upstream.Close()
+
+		var seen map[string]interface{}
+		var outer *Annotations
+		handler := New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{UpstreamHost: urlx.ParseOrPanic(upstream.URL).Host, UpstreamScheme: "http"}, nil
+		},
+			WithReqMiddleware(func(req *http.Request, config *HostConfig, body []byte) ([]byte, error) {
+				Annotate(req, "user_id", "foo")
+				AnnotationsFromContext(req.Context()).Append("rewrites_applied", "path")
+				return body, nil
+			}),
+			WithRespMiddleware(func(resp *http.Response, config *HostConfig, body []byte) ([]byte, error) {
+				a := AnnotationsFromContext(resp.Request.Context())
+				a.Append("rewrites_applied", "body")
+				seen = a.All()
+				return body, nil
+			}))
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(WithAnnotations(r.Context()))
+			outer = AnnotationsFromContext(r.Context())
+			handler.ServeHTTP(w, r)
+		}))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		expected := map[string]interface{}{"user_id": "foo", "rewrites_applied": []interface{}{"path", "body"}}
+		assert.Equal(t, expected, seen)
+		assert.Equal(t, expected, outer.All())
+	})
+
+	t.Run("case=typed getters and span attributes", func(t *testing.T) {
+		a := AnnotationsFromContext(WithAnnotations(context.Background()))
+		a.Set("user_id", "foo")
+		a.Set("attempts", 2)
+		a.Set("cached", true)
+
+		s, ok := a.GetString("user_id")
+		assert.True(t, ok)
+		assert.Equal(t, "foo", s)
+		_, ok = a.GetString("attempts")
+		assert.False(t, ok)
+		i, _ := a.GetInt("attempts")
+		assert.Equal(t, 2, i)
+
+		assert.Equal(t, []attribute.KeyValue{
+			attribute.Int("proxy.annotation.attempts", 2),
+			attribute.Bool("proxy.annotation.cached", true),
+			attribute.String("proxy.annotation.user_id", "foo"),
+		}, a.SpanAttributes())
+
+		a.Delete("cached")
+		_, ok = a.Get("cached")
+		assert.False(t, ok)
+	})
+
+	t.Run("case=nil store is a no-op", func(t *testing.T) {
+		a := AnnotationsFromContext(context.Background())
+		a.Set("foo", "bar")
+		_, ok := a.Get("foo")
+		assert.False(t, ok)
+		assert.Empty(t, a.All())
+	})
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// CertificateCache stores certificates obtained via ACME. It can be backed by any storage,
+// e.g. autocert.DirCache for a local directory or a shared database for a fleet of proxies.
+type CertificateCache = autocert.Cache
+
+// AutocertHostPolicy returns an autocert.HostPolicy which only allows certificates to be
+// requested for hosts the HostMapper resolves without error.
+func AutocertHostPolicy(hostMapper HostMapper) autocert.HostPolicy {
+	return func(ctx context.Context, host string) error {
+		if _, err := hostConfigForServerName(ctx, hostMapper, host); err != nil {
+			return errors.WithMessagef(err, "acme/autocert: host %q is not configured", host)
+		}
+		return nil
+	}
+}
+
+// NewAutocertManager returns an autocert.Manager which obtains and renews certificates for all
+// hosts known to the HostMapper and stores them in cache. The email is optional and used by the
+// certificate authority to notify about problems with issued certificates.
+func NewAutocertManager(hostMapper HostMapper, cache CertificateCache, email string) *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      cache,
+		HostPolicy: AutocertHostPolicy(hostMapper),
+		Email:      email,
+	}
+}
+
+// NewAutocertTLSConfig returns a tls.Config which serves the certificate configured in the
+// HostConfig (see GetCertificate) if there is one, and falls back to the autocert.Manager otherwise.
+func NewAutocertTLSConfig(hostMapper HostMapper, m *autocert.Manager) *tls.Config {
+	static := GetCertificate(hostMapper, NewCertificateStore())
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1", acme.ALPNProto},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			cert, err := static(hello)
+			if errors.Is(err, ErrNoCertificate) {
+				return m.GetCertificate(hello)
+			}
+			return cert, err
+		},
+	}
+}
@@ -0,0 +1,109 @@
+package proxy
+
+import (
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Balancer selects the upstream of a request from the UpstreamHosts of its HostConfig. It must be safe for
+	// concurrent use.
+	Balancer interface {
+		// Pick returns one of the hosts, which are never empty, and a function which is called once the
+		// request was proxied.
+		Pick(r *http.Request, hosts []string) (host string, done func())
+	}
+	roundRobinBalancer struct {
+		next sync.Map
+	}
+	randomBalancer struct {
+		mu  sync.Mutex
+		rnd *rand.Rand
+	}
+	leastConnectionsBalancer struct {
+		mu       sync.Mutex
+		inFlight map[string]int
+		offset   uint
+	}
+)
+
+// NewRoundRobinBalancer returns a Balancer which picks the hosts in turn. Each list of hosts is rotated
+// separately. It is used if no Balancer is set via WithBalancer.
+func NewRoundRobinBalancer() Balancer {
+	return new(roundRobinBalancer)
+}
+
+// NewRandomBalancer returns a Balancer which picks a random host.
+func NewRandomBalancer() Balancer {
+	// #nosec G404 -- the hosts are not picked for security reasons
+	return &randomBalancer{rnd: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// NewLeastConnectionsBalancer returns a Balancer which picks the host with the fewest requests in flight,
+// counting the requests of this proxy only. Ties are picked in turn.
+func NewLeastConnectionsBalancer() Balancer {
+	return &leastConnectionsBalancer{inFlight: make(map[string]int)}
+}
+
+// WithBalancer sets the Balancer which selects the upstream of hosts with several UpstreamHosts.
+// Default: NewRoundRobinBalancer
+func WithBalancer(b Balancer) Options {
+	return func(o *options) {
+		o.balancer = b
+	}
+}
+
+func (b *roundRobinBalancer) Pick(_ *http.Request, hosts []string) (string, func()) {
+	next, _ := b.next.LoadOrStore(strings.Join(hosts, ","), new(uint64))
+	n := atomic.AddUint64(next.(*uint64), 1) - 1
+	return hosts[n%uint64(len(hosts))], func() {}
+}
+
+func (b *randomBalancer) Pick(_ *http.Request, hosts []string) (string, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return hosts[b.rnd.Intn(len(hosts))], func() {}
+}
+
+func (b *leastConnectionsBalancer) Pick(_ *http.Request, hosts []string) (string, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.offset++
+	host := ""
+	for i := range hosts {
+		h := hosts[(b.offset+uint(i))%uint(len(hosts))]
+		if host == "" || b.inFlight[h] < b.inFlight[host] {
+			host = h
+		}
+	}
+	b.inFlight[host]++
+
+	var once sync.Once
+	return host, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if b.inFlight[host]--; b.inFlight[host] <= 0 {
+				delete(b.inFlight, host)
+			}
+		})
+	}
+}
+
+// balanced returns a copy of the HostConfig whose UpstreamHost is picked from its UpstreamHosts, and the
+// function which is called once the request was proxied. If the ReadUpstreamHost or a route replaces the
+// upstream of the request, the HostConfig itself is returned without picking a host.
+func (c *HostConfig) balanced(r *http.Request, b Balancer) (*HostConfig, func()) {
+	if c.forMethod(r.Method).routed(r.URL.Path).UpstreamHost != c.UpstreamHost {
+		return c, func() {}
+	}
+	host, done := b.Pick(r, c.UpstreamHosts)
+	cc := *c
+	cc.UpstreamHost = host
+	return &cc, done
+}
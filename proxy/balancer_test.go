@@ -0,0 +1,145 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestBalancer(t *testing.T) {
+	hosts := []string{"a", "b", "c"}
+	r := httptest.NewRequest("GET", "/", nil)
+	pick := func(b Balancer, hosts []string) string {
+		host, done := b.Pick(r, hosts)
+		done()
+		return host
+	}
+
+	t.Run("case=round robin", func(t *testing.T) {
+		b := NewRoundRobinBalancer()
+		var picked []string
+		for i := 0; i < 4; i++ {
+			picked = append(picked, pick(b, hosts))
+		}
+		assert.Equal(t, []string{"a", "b", "c", "a"}, picked)
+		assert.Equal(t, "x", pick(b, []string{"x", "y"}), "each list of hosts is rotated separately")
+	})
+
+	t.Run("case=random", func(t *testing.T) {
+		b := NewRandomBalancer()
+		picked := map[string]int{}
+		for i := 0; i < 300; i++ {
+			picked[pick(b, hosts)]++
+		}
+		assert.Len(t, picked, 3)
+	})
+
+	t.Run("case=least connections", func(t *testing.T) {
+		b := NewLeastConnectionsBalancer()
+		first, doneFirst := b.Pick(r, hosts)
+		second, doneSecond := b.Pick(r, hosts)
+		third, doneThird := b.Pick(r, hosts)
+		assert.ElementsMatch(t, hosts, []string{first, second, third})
+
+		doneSecond()
+		doneSecond()
+		assert.Equal(t, second, pick(b, hosts), "the host without requests in flight is picked")
+		doneFirst()
+		doneThird()
+		assert.Empty(t, b.(*leastConnectionsBalancer).inFlight)
+	})
+}
+
+func TestUpstreamHosts(t *testing.T) {
+	arrived, block := make(chan string, 1), make(chan struct{})
+	newUpstream := func(name string) string {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/block" {
+				arrived <- name
+				<-block
+			}
+			_, _ = w.Write([]byte(name))
+		}))
+		t.Cleanup(upstream.Close)
+		return urlx.ParseOrPanic(upstream.URL).Host
+	}
+	defer close(block)
+	hosts := []string{newUpstream("first"), newUpstream("second")}
+
+	newProxy := func(opts ...Options) *httptest.Server {
+		proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{UpstreamHosts: hosts, UpstreamScheme: "http"}, nil
+		}, opts...))
+		t.Cleanup(proxy.Close)
+		return proxy
+	}
+	get := func(t *testing.T, url string) string {
+		resp, err := http.Get(url)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("case=spreads the requests", func(t *testing.T) {
+		proxy := newProxy()
+		assert.Equal(t, []string{"first", "second", "first"}, []string{get(t, proxy.URL), get(t, proxy.URL), get(t, proxy.URL)})
+	})
+
+	t.Run("case=counts the requests in flight", func(t *testing.T) {
+		proxy := newProxy(WithBalancer(NewLeastConnectionsBalancer()))
+
+		go func() {
+			resp, err := http.Get(proxy.URL + "/block")
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+		busy := <-arrived
+		for i := 0; i < 3; i++ {
+			assert.NotEqual(t, busy, get(t, proxy.URL), "the idle upstream is picked")
+		}
+	})
+
+	t.Run("case=only picks requests forwarded to the upstream hosts", func(t *testing.T) {
+		b := NewLeastConnectionsBalancer()
+		c := &HostConfig{
+			UpstreamHosts:    hosts,
+			ReadUpstreamHost: "replica",
+			Routes:           []Route{{PathPrefix: "/api", UpstreamHost: "api"}},
+		}
+
+		for _, r := range []*http.Request{httptest.NewRequest("GET", "/", nil), httptest.NewRequest("POST", "/api/users", nil)} {
+			cc, done := c.balanced(r, b)
+			assert.Same(t, c, cc)
+			assert.Empty(t, b.(*leastConnectionsBalancer).inFlight, "no request is counted for %s %s", r.Method, r.URL.Path)
+			done()
+		}
+
+		cc, done := c.balanced(httptest.NewRequest("POST", "/", nil), b)
+		defer done()
+		assert.Contains(t, hosts, cc.UpstreamHost)
+		assert.Len(t, b.(*leastConnectionsBalancer).inFlight, 1)
+	})
+
+	t.Run("case=parses the config", func(t *testing.T) {
+		c, err := ParseHostConfig([]byte(`{"upstream_hosts":["a:80","b:80"],"upstream_scheme":"http"}`))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a:80", "b:80"}, c.UpstreamHosts)
+	})
+
+	t.Run("case=schedules take precedence", func(t *testing.T) {
+		c := (&HostConfig{UpstreamHosts: hosts, Schedules: []Schedule{{Start: time.Now().Add(-time.Minute), UpstreamHost: "maintenance"}}}).scheduled(time.Now())
+		assert.Equal(t, "maintenance", c.UpstreamHost)
+		assert.Empty(t, c.UpstreamHosts)
+	})
+}
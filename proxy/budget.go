@@ -0,0 +1,66 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// ResponseBudget limits the time the upstream has to respond. If the budget is exceeded,
+	// the upstream request is aborted and a fallback response is served instead.
+	ResponseBudget struct {
+		// Timeout is the time the upstream has to deliver the complete response.
+		Timeout time.Duration `json:"timeout,omitempty"`
+		// FallbackBody is served if the upstream does not respond in time.
+		FallbackBody []byte `json:"fallback_body,omitempty"`
+		// FallbackContentType is the content type of FallbackBody.
+		// Default: application/json
+		FallbackContentType string `json:"fallback_content_type,omitempty"`
+		// FallbackStatusCode is the status code of the fallback response.
+		// Default: 200
+		FallbackStatusCode int `json:"fallback_status_code,omitempty"`
+		// UseLastResponse serves the last successful response to the same URL, if there is one, instead of FallbackBody.
+		UseLastResponse bool `json:"use_last_response,omitempty"`
+	}
+	cachedResponse struct {
+		contentType string
+		body        []byte
+	}
+	responseCache struct {
+		sync.Map
+	}
+)
+
+func responseCacheKey(r *http.Request, c *HostConfig) string {
+	return c.originalHost + " " + r.URL.RequestURI()
+}
+
+func (rc *responseCache) get(r *http.Request, c *HostConfig) (*cachedResponse, bool) {
+	v, ok := rc.Load(responseCacheKey(r, c))
+	if !ok {
+		return nil, false
+	}
+	return v.(*cachedResponse), true
+}
+
+// remember stores successful responses of hosts which might need them as fallback.
+func (rc *responseCache) remember(resp *http.Response, c *HostConfig, body []byte) {
+	if c.ResponseBudget == nil || !c.ResponseBudget.UseLastResponse || resp.StatusCode != http.StatusOK {
+		return
+	}
+	rc.Store(responseCacheKey(resp.Request, c), &cachedResponse{
+		contentType: resp.Header.Get("Content-Type"),
+		body:        append([]byte(nil), body...),
+	})
+}
+
+// withBudget applies the response budget to the request context.
+func (b *ResponseBudget) withBudget(r *http.Request) (*http.Request, context.CancelFunc) {
+	if b == nil || b.Timeout <= 0 || isUpgradeRequest(r) {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), b.Timeout)
+	return r.WithContext(ctx), cancel
+}
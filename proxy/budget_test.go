@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestResponseBudget(t *testing.T) {
+	var slow int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&slow) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"items":[1,2,3]}`))
+	}))
+	defer upstream.Close()
+
+	newProxy := func(budget *ResponseBudget) *httptest.Server {
+		return httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{
+				UpstreamHost:   urlx.ParseOrPanic(upstream.URL).Host,
+				UpstreamScheme: "http",
+				TargetHost:     urlx.ParseOrPanic(upstream.URL).Host,
+				TargetScheme:   "http",
+				ResponseBudget: budget,
+			}, nil
+		}))
+	}
+
+	get := func(t *testing.T, u string) (int, string) {
+		resp, err := http.Get(u)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("case=serves static fallback", func(t *testing.T) {
+		atomic.StoreInt32(&slow, 1)
+		proxy := newProxy(&ResponseBudget{
+			Timeout:            50 * time.Millisecond,
+			FallbackBody:       []byte(`{"items":[]}`),
+			FallbackStatusCode: http.StatusPartialContent,
+		})
+		defer proxy.Close()
+
+		code, body := get(t, proxy.URL+"/items")
+		assert.Equal(t, http.StatusPartialContent, code)
+		assert.Equal(t, `{"items":[]}`, body)
+	})
+
+	t.Run("case=serves last response", func(t *testing.T) {
+		proxy := newProxy(&ResponseBudget{
+			Timeout:         50 * time.Millisecond,
+			FallbackBody:    []byte(`{"items":[]}`),
+			UseLastResponse: true,
+		})
+		defer proxy.Close()
+
+		atomic.StoreInt32(&slow, 0)
+		code, body := get(t, proxy.URL+"/items")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, `{"items":[1,2,3]}`, body)
+
+		atomic.StoreInt32(&slow, 1)
+		code, body = get(t, proxy.URL+"/items")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, `{"items":[1,2,3]}`, body)
+
+		_, body = get(t, proxy.URL+"/other")
+		assert.Equal(t, `{"items":[]}`, body)
+	})
+}
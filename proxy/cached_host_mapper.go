@@ -0,0 +1,175 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// RedisCache is the subset of Redis used by CachedHostMapper. Clients such as github.com/go-redis/redis
+	// can be adapted in a few lines, which keeps the Redis client out of this package's dependencies.
+	RedisCache interface {
+		// Get returns the value of the key, and false if the key does not exist.
+		Get(ctx context.Context, key string) (value []byte, found bool, err error)
+		// Set sets the value of the key, expiring after ttl.
+		Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+		// Del deletes the keys.
+		Del(ctx context.Context, keys ...string) error
+		// Publish publishes the message to the channel.
+		Publish(ctx context.Context, channel, message string) error
+		// Subscribe subscribes to the channel. The returned channel must be closed when the context is canceled.
+		Subscribe(ctx context.Context, channel string) (<-chan string, error)
+	}
+	// CachedHostMapper caches the results of a HostMapper in Redis, so a fleet of proxies shares lookups, and in memory.
+	// Invalidate removes a host from all caches of all instances running Run.
+	//
+	// Results are cached by request host, so the mapper must only depend on the host. Errors are not cached.
+	// As HostConfigs are stored as JSON, CORS options which are functions are lost.
+	CachedHostMapper struct {
+		// Mapper is the HostMapper whose results are cached.
+		Mapper HostMapper
+		// Redis is the shared cache.
+		Redis RedisCache
+		// TTL is the time a result is cached.
+		// Default: 1m
+		TTL time.Duration
+		// KeyPrefix is prepended to the host to build the Redis key.
+		// Default: proxy:host:
+		KeyPrefix string
+		// Channel is the pub/sub channel for invalidations.
+		// Default: proxy:host:invalidate
+		Channel string
+
+		local sync.Map
+	}
+	cachedHostConfig struct {
+		config    *HostConfig
+		expiresAt time.Time
+	}
+)
+
+// invalidateAll is published to invalidate all hosts.
+const invalidateAll = "*"
+
+func (m *CachedHostMapper) ttl() time.Duration {
+	if m.TTL <= 0 {
+		return time.Minute
+	}
+	return m.TTL
+}
+
+func (m *CachedHostMapper) key(host string) string {
+	if m.KeyPrefix == "" {
+		return "proxy:host:" + host
+	}
+	return m.KeyPrefix + host
+}
+
+func (m *CachedHostMapper) channel() string {
+	if m.Channel == "" {
+		return "proxy:host:invalidate"
+	}
+	return m.Channel
+}
+
+func (m *CachedHostMapper) lookup(ctx context.Context, r *http.Request) (*HostConfig, error) {
+	if cached, ok := m.local.Load(r.Host); ok && time.Now().Before(cached.(*cachedHostConfig).expiresAt) {
+		return cached.(*cachedHostConfig).config, nil
+	}
+
+	raw, found, err := m.Redis.Get(ctx, m.key(r.Host))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var c *HostConfig
+	if found {
+		c = new(HostConfig)
+		if err := json.Unmarshal(raw, c); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	} else {
+		if c, err = m.Mapper(ctx, r); err != nil {
+			return nil, err
+		}
+		if raw, err = json.Marshal(c); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := m.Redis.Set(ctx, m.key(r.Host), raw, m.ttl()); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	m.local.Store(r.Host, &cachedHostConfig{config: c, expiresAt: time.Now().Add(m.ttl())})
+	return c, nil
+}
+
+// HostMapper returns the HostMapper to pass to New.
+func (m *CachedHostMapper) HostMapper() HostMapper {
+	return func(ctx context.Context, r *http.Request) (*HostConfig, error) {
+		c, err := m.lookup(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		return c.CopyForRequest(), nil
+	}
+}
+
+// Invalidate removes the hosts from Redis and notifies all instances to remove them from their memory.
+// Without hosts, all hosts are removed from memory, while Redis entries expire after TTL.
+func (m *CachedHostMapper) Invalidate(ctx context.Context, hosts ...string) error {
+	if len(hosts) == 0 {
+		m.evict(invalidateAll)
+		return errors.WithStack(m.Redis.Publish(ctx, m.channel(), invalidateAll))
+	}
+
+	keys := make([]string, len(hosts))
+	for i, host := range hosts {
+		keys[i] = m.key(host)
+	}
+	if err := m.Redis.Del(ctx, keys...); err != nil {
+		return errors.WithStack(err)
+	}
+	for _, host := range hosts {
+		m.evict(host)
+		if err := m.Redis.Publish(ctx, m.channel(), host); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+func (m *CachedHostMapper) evict(host string) {
+	if host != invalidateAll {
+		m.local.Delete(host)
+		return
+	}
+	m.local.Range(func(key, _ interface{}) bool {
+		m.local.Delete(key)
+		return true
+	})
+}
+
+// Run subscribes to invalidations until the context is canceled. Without a subscription, the in-memory
+// cache only expires after TTL.
+func (m *CachedHostMapper) Run(ctx context.Context) error {
+	messages, err := m.Redis.Subscribe(ctx, m.channel())
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case host, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			m.evict(host)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryRedis struct {
+	mu          sync.Mutex
+	values      map[string][]byte
+	subscribers []chan string
+}
+
+func (r *memoryRedis) Get(_ context.Context, key string) ([]byte, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.values[key]
+	return v, ok, nil
+}
+
+func (r *memoryRedis) Set(_ context.Context, key string, value []byte, _ time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.values[key] = value
+	return nil
+}
+
+func (r *memoryRedis) Del(_ context.Context, keys ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range keys {
+		delete(r.values, k)
+	}
+	return nil
+}
+
+func (r *memoryRedis) Publish(_ context.Context, _, message string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, s := range r.subscribers {
+		s <- message
+	}
+	return nil
+}
+
+func (r *memoryRedis) Subscribe(context.Context, string) (<-chan string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := make(chan string, 10)
+	r.subscribers = append(r.subscribers, s)
+	return s, nil
+}
+
+func TestCachedHostMapper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var calls int32
+	upstream := "v1.internal"
+	mapper := func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		atomic.AddInt32(&calls, 1)
+		return &HostConfig{UpstreamHost: upstream, UpstreamScheme: "http"}, nil
+	}
+
+	redis := &memoryRedis{values: map[string][]byte{}}
+	first := &CachedHostMapper{Mapper: mapper, Redis: redis}
+	second := &CachedHostMapper{Mapper: mapper, Redis: redis}
+	go func() { _ = second.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		redis.mu.Lock()
+		defer redis.mu.Unlock()
+		return len(redis.subscribers) == 1
+	}, time.Second, time.Millisecond)
+
+	lookup := func(m *CachedHostMapper) string {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = "example.com"
+		c, err := m.HostMapper()(ctx, r)
+		require.NoError(t, err)
+		return c.UpstreamHost
+	}
+
+	assert.Equal(t, "v1.internal", lookup(first))
+	assert.Equal(t, "v1.internal", lookup(first))
+	assert.Equal(t, "v1.internal", lookup(second), "shared through redis")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	upstream = "v2.internal"
+	require.NoError(t, first.Invalidate(ctx, "example.com"))
+	assert.Equal(t, "v2.internal", lookup(first))
+	assert.Eventually(t, func() bool { return lookup(second) == "v2.internal" }, time.Second, time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,211 @@
+// Command proxy runs the reverse proxy of github.com/ory/x/proxy from a JSON or YAML config file:
+//
+//	serve:
+//	  addr: :8443
+//	  tls: true
+//	slow_client:
+//	  min_bytes_per_second: 1024
+//	  grace_period: 10s
+//	tracing:
+//	  service_name: proxy
+//	  provider: otlp
+//	  providers:
+//	    otlp:
+//	      server_url: localhost:4318
+//	hosts:
+//	  example.com:
+//	    upstream_host: upstream.internal:8080
+//	    upstream_scheme: http
+//	    tls_certificate_path: /etc/certs/example.com.pem
+//	    tls_key_path: /etc/certs/example.com.key
+//
+// The hosts are configured as documented by proxy.ConfigSchema.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ory/x/cmdx"
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/logx"
+	"github.com/ory/x/otelx"
+	"github.com/ory/x/proxy"
+)
+
+type (
+	duration time.Duration
+	config   struct {
+		Serve struct {
+			// Addr is the address to listen on. Defaults to :8080, or :8443 with TLS.
+			Addr string `json:"addr"`
+			// TLS serves the certificates configured per host.
+			TLS bool `json:"tls"`
+			// Autocert obtains the certificates of all hosts from Let's Encrypt instead. Implies TLS.
+			Autocert *struct {
+				Email    string `json:"email"`
+				CacheDir string `json:"cache_dir"`
+			} `json:"autocert"`
+			// H2C enables HTTP/2 without TLS.
+			H2C bool `json:"h2c"`
+		} `json:"serve"`
+		SlowClient *struct {
+			MinBytesPerSecond   int64    `json:"min_bytes_per_second"`
+			GracePeriod         duration `json:"grace_period"`
+			MaxResponseDuration duration `json:"max_response_duration"`
+		} `json:"slow_client"`
+		HostNotFound *struct {
+			StatusCode  int    `json:"status_code"`
+			ContentType string `json:"content_type"`
+			Body        string `json:"body"`
+		} `json:"host_not_found"`
+		Tracing *otelx.Config              `json:"tracing"`
+		Hosts   map[string]json.RawMessage `json:"hosts"`
+	}
+)
+
+func (d *duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return errors.WithStack(err)
+	}
+	p, err := time.ParseDuration(s)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	*d = duration(p)
+	return nil
+}
+
+func loadConfig(path string) (*config, map[string]*proxy.HostConfig, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	var c config
+	if err := json.Unmarshal(j, &c); err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	if len(c.Hosts) == 0 {
+		return nil, nil, errors.New("at least one host must be configured")
+	}
+
+	hosts := make(map[string]*proxy.HostConfig, len(c.Hosts))
+	for host, raw := range c.Hosts {
+		hc, err := proxy.ParseHostConfig(raw)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "host %s", host)
+		}
+		hosts[host] = hc
+	}
+	return &c, hosts, nil
+}
+
+func hostMapper(hosts map[string]*proxy.HostConfig) proxy.HostMapper {
+	return func(_ context.Context, r *http.Request) (*proxy.HostConfig, error) {
+		c, ok := hosts[r.Host]
+		if !ok {
+			if host, _, err := net.SplitHostPort(r.Host); err == nil {
+				c, ok = hosts[host]
+			}
+		}
+		if !ok {
+			return nil, errors.Wrapf(proxy.ErrHostNotFound, "host %s", r.Host)
+		}
+		return c.CopyForRequest(), nil
+	}
+}
+
+func main() {
+	configPath := flag.String("config", "proxy.yaml", "path to the JSON or YAML config file")
+	flag.Parse()
+
+	conf, hosts, err := loadConfig(*configPath)
+	if err != nil {
+		cmdx.Fatalf("Unable to load config %s: %+v", *configPath, err)
+	}
+
+	l := logrusx.New("proxy", "")
+	if err := run(l, conf, hosts); err != nil {
+		l.WithError(err).Fatal("Unable to serve the proxy.")
+	}
+}
+
+// run serves the proxy until it receives SIGINT or SIGTERM. It returns instead of exiting on errors, so the
+// traces are flushed.
+func run(l *logrusx.Logger, conf *config, hosts map[string]*proxy.HostConfig) error {
+	mapper := hostMapper(hosts)
+
+	if conf.Tracing != nil {
+		tracer, err := otelx.New("github.com/ory/x/proxy", l, conf.Tracing)
+		if err != nil {
+			return errors.WithMessage(err, "unable to set up tracing")
+		}
+		defer func() {
+			if err := tracer.Shutdown(context.Background()); err != nil {
+				l.WithError(err).Error("Unable to flush traces.")
+			}
+		}()
+	}
+
+	opts := []proxy.Options{
+		proxy.WithLogger(logx.FromLogrus(l)),
+		proxy.WithOnError(func(r *http.Request, err error) {
+			l.WithRequest(r).WithError(err).Warn("Unable to proxy request.")
+		}, func(_ *http.Response, err error) error {
+			return err
+		}),
+		proxy.WithErrorReporter(func(_ context.Context, c *proxy.HostConfig, err error) {
+			entry := l.WithError(err)
+			if c != nil {
+				entry = entry.WithField("upstream", c.UpstreamHost)
+			}
+			entry.Error("Proxy error.")
+		}),
+	}
+	if s := conf.SlowClient; s != nil {
+		opts = append(opts, proxy.WithSlowClientProtection(s.MinBytesPerSecond, time.Duration(s.GracePeriod), time.Duration(s.MaxResponseDuration)))
+	}
+	if h := conf.HostNotFound; h != nil {
+		opts = append(opts, proxy.WithHostNotFound(h.StatusCode, h.ContentType, []byte(h.Body)))
+	}
+
+	serveOpts := proxy.ServeOptions{Addr: conf.Serve.Addr, H2C: conf.Serve.H2C}
+	if a := conf.Serve.Autocert; a != nil {
+		var cache proxy.CertificateCache
+		if a.CacheDir != "" {
+			cache = autocert.DirCache(a.CacheDir)
+		}
+		serveOpts.TLSConfig = proxy.NewAutocertTLSConfig(mapper, proxy.NewAutocertManager(mapper, cache, a.Email))
+	} else if conf.Serve.TLS {
+		serveOpts.TLSConfig = proxy.NewTLSConfig(mapper)
+	}
+	if serveOpts.Addr == "" {
+		serveOpts.Addr = ":8080"
+		if serveOpts.TLSConfig != nil {
+			serveOpts.Addr = ":8443"
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	l.Infof("Proxying %d hosts on %s.", len(hosts), serveOpts.Addr)
+	return proxy.Serve(ctx, proxy.New(mapper, opts...), serveOpts)
+}
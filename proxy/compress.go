@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// responseEncoders are the encodings of proxy-generated responses, in order of preference.
+var responseEncoders = []struct {
+	name      string
+	newWriter func(io.Writer) (io.WriteCloser, error)
+}{
+	{name: "br", newWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return brotli.NewWriter(w), nil
+	}},
+	{name: "zstd", newWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w)
+	}},
+	{name: "gzip", newWriter: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	}},
+}
+
+// acceptedEncodings parses the Accept-Encoding header into a map of encodings to their quality.
+func acceptedEncodings(h http.Header) map[string]float64 {
+	accepted := map[string]float64{}
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, token := range strings.Split(v, ",") {
+			params := strings.Split(token, ";")
+			name := strings.ToLower(strings.TrimSpace(params[0]))
+			if name == "" {
+				continue
+			}
+			q := 1.0
+			for _, p := range params[1:] {
+				if kv := strings.SplitN(strings.TrimSpace(p), "=", 2); len(kv) == 2 && strings.EqualFold(kv[0], "q") {
+					if parsed, err := strconv.ParseFloat(kv[1], 64); err == nil {
+						q = parsed
+					}
+				}
+			}
+			accepted[name] = q
+		}
+	}
+	return accepted
+}
+
+// compressResponse compresses the body with the encoding preferred by the client. It returns the body unchanged
+// and an empty encoding if the client does not accept any supported encoding.
+func compressResponse(r *http.Request, body []byte) ([]byte, string, error) {
+	if r == nil || len(body) == 0 {
+		return body, "", nil
+	}
+	accepted := acceptedEncodings(r.Header)
+
+	var best float64
+	var index = -1
+	for i, e := range responseEncoders {
+		q, ok := accepted[e.name]
+		if !ok {
+			q, ok = accepted["*"]
+		}
+		if ok && q > best {
+			best, index = q, i
+		}
+	}
+	if index < 0 {
+		return body, "", nil
+	}
+
+	var b bytes.Buffer
+	w, err := responseEncoders[index].newWriter(&b)
+	if err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", errors.WithStack(err)
+	}
+	return b.Bytes(), responseEncoders[index].name, nil
+}
+
+// addVary adds the header name to the Vary header unless it is already listed.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, token := range strings.Split(v, ",") {
+			if t := strings.TrimSpace(token); t == "*" || strings.EqualFold(t, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressResponse(t *testing.T) {
+	body := bytes.Repeat([]byte("maintenance "), 100)
+
+	for _, tc := range []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{acceptEncoding: "", expected: ""},
+		{acceptEncoding: "identity", expected: ""},
+		{acceptEncoding: "gzip, deflate", expected: "gzip"},
+		{acceptEncoding: "gzip, deflate, br", expected: "br"},
+		{acceptEncoding: "br;q=0, zstd", expected: "zstd"},
+		{acceptEncoding: "gzip;q=1.0, br;q=0.5", expected: "gzip"},
+		{acceptEncoding: "*", expected: "br"},
+		{acceptEncoding: "*;q=0", expected: ""},
+	} {
+		t.Run("accept="+tc.acceptEncoding, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+
+			compressed, encoding, err := compressResponse(r, body)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, encoding)
+			if tc.expected == "" {
+				assert.Equal(t, body, compressed)
+			} else {
+				assert.Less(t, len(compressed), len(body))
+			}
+		})
+	}
+}
+
+func TestWriteResponseCompression(t *testing.T) {
+	body := bytes.Repeat([]byte(`{"error":"unknown host"}`), 10)
+	proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+		return nil, ErrHostNotFound
+	}, WithHostNotFound(http.StatusNotFound, "application/json", body)))
+	defer proxy.Close()
+
+	req, err := http.NewRequest(http.MethodGet, proxy.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "br")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "br", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", resp.Header.Get("Vary"))
+	actual, err := io.ReadAll(brotli.NewReader(resp.Body))
+	require.NoError(t, err)
+	assert.Equal(t, body, actual)
+}
@@ -0,0 +1,494 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/rs/cors"
+
+	"github.com/ory/jsonschema/v3"
+)
+
+//go:embed config.schema.json
+var ConfigSchema string
+
+const ConfigSchemaID = "ory://proxy-host-config"
+
+// ErrInvalidHostConfig is returned when a HostConfig fails validation.
+var ErrInvalidHostConfig = errors.New("invalid host config")
+
+// AddConfigSchema adds the host config schema to the compiler.
+// The interface is specified instead of `jsonschema.Compiler` to allow the use of any jsonschema library fork or version.
+func AddConfigSchema(c interface {
+	AddResource(url string, r io.Reader) error
+}) error {
+	return c.AddResource(ConfigSchemaID, bytes.NewBufferString(ConfigSchema))
+}
+
+var (
+	compiledSchema     *jsonschema.Schema
+	compiledSchemaErr  error
+	compiledSchemaOnce sync.Once
+)
+
+func configSchema() (*jsonschema.Schema, error) {
+	compiledSchemaOnce.Do(func() {
+		c := jsonschema.NewCompiler()
+		if compiledSchemaErr = AddConfigSchema(c); compiledSchemaErr != nil {
+			return
+		}
+		compiledSchema, compiledSchemaErr = c.Compile(context.Background(), ConfigSchemaID)
+	})
+	return compiledSchema, errors.WithStack(compiledSchemaErr)
+}
+
+// ParseHostConfig parses a JSON or YAML encoded host config, validates it against ConfigSchema and
+// runs the cross-field checks of HostConfig.Validate.
+func ParseHostConfig(raw []byte) (*HostConfig, error) {
+	j, err := yaml.YAMLToJSON(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	schema, err := configSchema()
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(bytes.NewReader(j)); err != nil {
+		return nil, errors.Wrap(ErrInvalidHostConfig, err.Error())
+	}
+
+	var c HostConfig
+	if err := json.Unmarshal(j, &c); err != nil {
+		return nil, errors.Wrap(ErrInvalidHostConfig, err.Error())
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func invalid(field, format string, args ...interface{}) error {
+	return errors.Wrapf(ErrInvalidHostConfig, field+": "+format, args...)
+}
+
+func validateScheme(field, scheme string, required bool) error {
+	switch scheme {
+	case "http", "https":
+		return nil
+	case "":
+		if !required {
+			return nil
+		}
+		return invalid(field, "must be set")
+	default:
+		return invalid(field, `must be "http" or "https" but is %q`, scheme)
+	}
+}
+
+func validatePathPrefix(field, prefix string, allowTrailingSlash bool) error {
+	if prefix == "" {
+		return nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return invalid(field, `must start with "/" but is %q`, prefix)
+	}
+	if !allowTrailingSlash && strings.HasSuffix(prefix, "/") {
+		return invalid(field, `must not end with "/" but is %q`, prefix)
+	}
+	return nil
+}
+
+// Validate checks the host config for errors which would otherwise only surface when serving requests,
+// such as unknown schemes, malformed path prefixes and contradicting TLS settings.
+func (c *HostConfig) Validate() error {
+	if c.UpstreamHost == "" && len(c.UpstreamHosts) == 0 {
+		return invalid("upstream_host", "must be set")
+	}
+	if c.UpstreamHost != "" && len(c.UpstreamHosts) > 0 {
+		return invalid("upstream_hosts", "must not be set together with upstream_host")
+	}
+	for i, host := range c.UpstreamHosts {
+		if host == "" {
+			return invalid("upstream_hosts", "must not contain empty hosts, but host %d is empty", i)
+		}
+	}
+	if err := validateScheme("upstream_scheme", c.UpstreamScheme, true); err != nil {
+		return err
+	}
+	if err := validateScheme("read_upstream_scheme", c.ReadUpstreamScheme, false); err != nil {
+		return err
+	}
+	if c.ReadUpstreamScheme != "" && c.ReadUpstreamHost == "" {
+		return invalid("read_upstream_scheme", "requires read_upstream_host")
+	}
+	if err := validateScheme("target_scheme", c.TargetScheme, false); err != nil {
+		return err
+	}
+	if c.UpstreamTrustDomain != "" && c.UpstreamScheme != "https" {
+		return invalid("upstream_trust_domain", `requires upstream_scheme "https"`)
+	}
+	if strings.Contains(c.UpstreamTrustDomain, "/") {
+		return invalid("upstream_trust_domain", `must be a trust domain such as "example.org", not a SPIFFE ID`)
+	}
+	if (c.UpstreamClientCertificatePath == "") != (c.UpstreamClientKeyPath == "") {
+		return invalid("upstream_client_certificate_path", "must be set together with upstream_client_key_path")
+	}
+	if (c.UpstreamClientCertificatePath != "" || c.UpstreamCAPath != "") && c.UpstreamScheme != "https" {
+		return invalid("upstream_client_certificate_path", `requires upstream_scheme "https"`)
+	}
+	if c.UpstreamClientCertificatePath != "" && c.UpstreamTrustDomain != "" {
+		return invalid("upstream_client_certificate_path", "must not be set together with upstream_trust_domain")
+	}
+	if (c.TLSCertificatePath == "") != (c.TLSKeyPath == "") {
+		return invalid("tls_certificate_path", "must be set together with tls_key_path")
+	}
+	if err := validatePathPrefix("path_prefix", c.PathPrefix, false); err != nil {
+		return err
+	}
+	if c.CorsEnabled && c.CorsOptions == nil {
+		return invalid("cors_enabled", "requires cors")
+	}
+
+	switch c.MethodOverride {
+	case MethodOverridePassThrough, MethodOverrideStrip, MethodOverrideHonor:
+	default:
+		return invalid("method_override", "unknown policy %q", c.MethodOverride)
+	}
+	switch c.ForwardedHeaders {
+	case ForwardedHeadersPassThrough, ForwardedHeadersAppend, ForwardedHeadersReplace, ForwardedHeadersStrip:
+	default:
+		return invalid("forwarded_headers", "unknown policy %q", c.ForwardedHeaders)
+	}
+
+	if p := c.UpstreamStatusPolicy; p != nil {
+		for _, code := range p.AllowedStatusCodes {
+			if code < 100 || code > 599 {
+				return invalid("upstream_status_policy.allowed_status_codes", "invalid status code %d", code)
+			}
+		}
+		if code := p.StatusCode; code != 0 && (code < 100 || code > 599) {
+			return invalid("upstream_status_policy.status_code", "invalid status code %d", code)
+		}
+	}
+	if b := c.ResponseBudget; b != nil {
+		if b.Timeout <= 0 {
+			return invalid("response_budget.timeout", "must be positive")
+		}
+		if code := b.FallbackStatusCode; code != 0 && (code < 100 || code > 599) {
+			return invalid("response_budget.fallback_status_code", "invalid status code %d", code)
+		}
+	}
+	for i := range c.Schedules {
+		if err := c.Schedules[i].validate(); err != nil {
+			return errors.WithMessagef(err, "schedules[%d]", i)
+		}
+	}
+	for i, r := range c.Routes {
+		if r.PathPrefix == "" {
+			return errors.WithMessagef(invalid("path_prefix", "must be set"), "routes[%d]", i)
+		}
+		if err := validatePathPrefix("path_prefix", r.PathPrefix, true); err != nil {
+			return errors.WithMessagef(err, "routes[%d]", i)
+		}
+		if err := validateScheme("upstream_scheme", r.UpstreamScheme, false); err != nil {
+			return errors.WithMessagef(err, "routes[%d]", i)
+		}
+	}
+	if p := c.SignedURLs; p != nil && len(p.Secrets) == 0 {
+		return invalid("signed_urls.secrets", "must not be empty")
+	}
+	if p := c.Webhook; p != nil {
+		switch p.Provider {
+		case WebhookStripe, WebhookGitHub, WebhookSlack:
+		default:
+			return invalid("webhook.provider", "unknown provider %q", p.Provider)
+		}
+		if len(p.Secrets) == 0 {
+			return invalid("webhook.secrets", "must not be empty")
+		}
+	}
+	for i, r := range c.ResponseHeaderRules {
+		if r.Name == "" {
+			return invalid("response_header_rules", "rule %d has no name", i)
+		}
+	}
+	return nil
+}
+
+func (s *Schedule) validate() error {
+	if (s.From == "") != (s.To == "") {
+		return invalid("from", "must be set together with to")
+	}
+	for _, f := range [...]struct{ name, clock string }{{"from", s.From}, {"to", s.To}} {
+		if f.clock == "" {
+			continue
+		}
+		if _, err := parseClock(f.clock); err != nil {
+			return invalid(f.name, `must be formatted as "15:04" but is %q`, f.clock)
+		}
+	}
+	if s.From == "" && s.Start.IsZero() && s.End.IsZero() {
+		return invalid("start", "either start, end, or from and to must be set")
+	}
+	if !s.Start.IsZero() && !s.End.IsZero() && !s.Start.Before(s.End) {
+		return invalid("end", "must be after start")
+	}
+	return validateScheme("upstream_scheme", s.UpstreamScheme, false)
+}
+
+// corsConfig is the JSON representation of cors.Options, which can not be encoded as it contains functions.
+type corsConfig struct {
+	AllowedOrigins     []string `json:"allowed_origins,omitempty"`
+	AllowedMethods     []string `json:"allowed_methods,omitempty"`
+	AllowedHeaders     []string `json:"allowed_headers,omitempty"`
+	ExposedHeaders     []string `json:"exposed_headers,omitempty"`
+	MaxAge             int      `json:"max_age,omitempty"`
+	AllowCredentials   bool     `json:"allow_credentials,omitempty"`
+	OptionsPassthrough bool     `json:"options_passthrough,omitempty"`
+	Debug              bool     `json:"debug,omitempty"`
+}
+
+func (c HostConfig) MarshalJSON() ([]byte, error) {
+	type hostConfig HostConfig
+	out := struct {
+		hostConfig
+		CorsOptions *corsConfig `json:"cors,omitempty"`
+	}{hostConfig: hostConfig(c)}
+	if o := c.CorsOptions; o != nil {
+		out.CorsOptions = &corsConfig{
+			AllowedOrigins:     o.AllowedOrigins,
+			AllowedMethods:     o.AllowedMethods,
+			AllowedHeaders:     o.AllowedHeaders,
+			ExposedHeaders:     o.ExposedHeaders,
+			MaxAge:             o.MaxAge,
+			AllowCredentials:   o.AllowCredentials,
+			OptionsPassthrough: o.OptionsPassthrough,
+			Debug:              o.Debug,
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (c *HostConfig) UnmarshalJSON(data []byte) error {
+	type hostConfig HostConfig
+	in := struct {
+		*hostConfig
+		CorsOptions *corsConfig `json:"cors,omitempty"`
+	}{hostConfig: (*hostConfig)(c)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if o := in.CorsOptions; o != nil {
+		c.CorsOptions = &cors.Options{
+			AllowedOrigins:     o.AllowedOrigins,
+			AllowedMethods:     o.AllowedMethods,
+			AllowedHeaders:     o.AllowedHeaders,
+			ExposedHeaders:     o.ExposedHeaders,
+			MaxAge:             o.MaxAge,
+			AllowCredentials:   o.AllowCredentials,
+			OptionsPassthrough: o.OptionsPassthrough,
+			Debug:              o.Debug,
+		}
+	}
+	return nil
+}
+
+func (b ResponseBudget) MarshalJSON() ([]byte, error) {
+	type responseBudget ResponseBudget
+	return json.Marshal(struct {
+		responseBudget
+		Timeout      string `json:"timeout,omitempty"`
+		FallbackBody string `json:"fallback_body,omitempty"`
+	}{responseBudget: responseBudget(b), Timeout: b.Timeout.String(), FallbackBody: string(b.FallbackBody)})
+}
+
+func (b *ResponseBudget) UnmarshalJSON(data []byte) error {
+	type responseBudget ResponseBudget
+	in := struct {
+		*responseBudget
+		Timeout      string `json:"timeout,omitempty"`
+		FallbackBody string `json:"fallback_body,omitempty"`
+	}{responseBudget: (*responseBudget)(b)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Timeout != "" {
+		timeout, err := time.ParseDuration(in.Timeout)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		b.Timeout = timeout
+	}
+	b.FallbackBody = []byte(in.FallbackBody)
+	return nil
+}
+
+func (p StatusPolicy) MarshalJSON() ([]byte, error) {
+	type statusPolicy StatusPolicy
+	return json.Marshal(struct {
+		statusPolicy
+		Body string `json:"body,omitempty"`
+	}{statusPolicy: statusPolicy(p), Body: string(p.Body)})
+}
+
+func (p *StatusPolicy) UnmarshalJSON(data []byte) error {
+	type statusPolicy StatusPolicy
+	in := struct {
+		*statusPolicy
+		Body string `json:"body,omitempty"`
+	}{statusPolicy: (*statusPolicy)(p)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	p.Body = []byte(in.Body)
+	return nil
+}
+
+func marshalSecrets(secrets [][]byte) []string {
+	out := make([]string, len(secrets))
+	for i, s := range secrets {
+		out[i] = string(s)
+	}
+	return out
+}
+
+func unmarshalSecrets(secrets []string) [][]byte {
+	out := make([][]byte, len(secrets))
+	for i, s := range secrets {
+		out[i] = []byte(s)
+	}
+	return out
+}
+
+func (p SignedURLPolicy) MarshalJSON() ([]byte, error) {
+	type signedURLPolicy SignedURLPolicy
+	return json.Marshal(struct {
+		signedURLPolicy
+		Secrets []string `json:"secrets,omitempty"`
+	}{signedURLPolicy: signedURLPolicy(p), Secrets: marshalSecrets(p.Secrets)})
+}
+
+func (p *SignedURLPolicy) UnmarshalJSON(data []byte) error {
+	type signedURLPolicy SignedURLPolicy
+	in := struct {
+		*signedURLPolicy
+		Secrets []string `json:"secrets,omitempty"`
+	}{signedURLPolicy: (*signedURLPolicy)(p)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	p.Secrets = unmarshalSecrets(in.Secrets)
+	return nil
+}
+
+func (p WebhookPolicy) MarshalJSON() ([]byte, error) {
+	type webhookPolicy WebhookPolicy
+	out := struct {
+		webhookPolicy
+		Secrets   []string `json:"secrets,omitempty"`
+		Tolerance string   `json:"tolerance,omitempty"`
+	}{webhookPolicy: webhookPolicy(p), Secrets: marshalSecrets(p.Secrets)}
+	if p.Tolerance != 0 {
+		out.Tolerance = p.Tolerance.String()
+	}
+	return json.Marshal(out)
+}
+
+func (p *WebhookPolicy) UnmarshalJSON(data []byte) error {
+	type webhookPolicy WebhookPolicy
+	in := struct {
+		*webhookPolicy
+		Secrets   []string `json:"secrets,omitempty"`
+		Tolerance string   `json:"tolerance,omitempty"`
+	}{webhookPolicy: (*webhookPolicy)(p)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	p.Secrets = unmarshalSecrets(in.Secrets)
+	if in.Tolerance != "" {
+		tolerance, err := time.ParseDuration(in.Tolerance)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		p.Tolerance = tolerance
+	}
+	return nil
+}
+
+func (s Schedule) MarshalJSON() ([]byte, error) {
+	type schedule Schedule
+	out := struct {
+		schedule
+		Start    *time.Time `json:"start,omitempty"`
+		End      *time.Time `json:"end,omitempty"`
+		Weekdays []string   `json:"weekdays,omitempty"`
+		Location string     `json:"location,omitempty"`
+	}{schedule: schedule(s)}
+	if !s.Start.IsZero() {
+		out.Start = &s.Start
+	}
+	if !s.End.IsZero() {
+		out.End = &s.End
+	}
+	for _, d := range s.Weekdays {
+		out.Weekdays = append(out.Weekdays, strings.ToLower(d.String()))
+	}
+	if s.Location != nil {
+		out.Location = s.Location.String()
+	}
+	return json.Marshal(out)
+}
+
+func (s *Schedule) UnmarshalJSON(data []byte) error {
+	type schedule Schedule
+	in := struct {
+		*schedule
+		Start    *time.Time `json:"start,omitempty"`
+		End      *time.Time `json:"end,omitempty"`
+		Weekdays []string   `json:"weekdays,omitempty"`
+		Location string     `json:"location,omitempty"`
+	}{schedule: (*schedule)(s)}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	if in.Start != nil {
+		s.Start = *in.Start
+	}
+	if in.End != nil {
+		s.End = *in.End
+	}
+	s.Weekdays = nil
+	for _, name := range in.Weekdays {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return err
+		}
+		s.Weekdays = append(s.Weekdays, day)
+	}
+	if in.Location != "" {
+		loc, err := time.LoadLocation(in.Location)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		s.Location = loc
+	}
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(d.String(), name) {
+			return d, nil
+		}
+	}
+	return 0, errors.Errorf("unknown weekday %q", name)
+}
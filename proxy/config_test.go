@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rs/cors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exampleHostConfig = `
+upstream_host: upstream.example.com
+upstream_scheme: https
+path_prefix: /api
+cors_enabled: true
+cors:
+  allowed_origins: [https://example.com]
+  allow_credentials: true
+response_budget:
+  timeout: 1500ms
+  fallback_body: '{"status":"degraded"}'
+schedules:
+  - weekdays: [saturday, sunday]
+    from: "22:00"
+    to: "06:00"
+    location: Europe/Berlin
+    upstream_host: maintenance.example.com
+routes:
+  - path_prefix: /admin
+    upstream_host: admin.example.com
+webhook:
+  provider: github
+  secrets: [secret]
+  tolerance: 1m
+  path_prefixes: [/hooks/]
+forwarded_headers: replace
+`
+
+func TestParseHostConfig(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	expected := &HostConfig{
+		UpstreamHost:   "upstream.example.com",
+		UpstreamScheme: "https",
+		PathPrefix:     "/api",
+		CorsEnabled:    true,
+		CorsOptions:    &cors.Options{AllowedOrigins: []string{"https://example.com"}, AllowCredentials: true},
+		ResponseBudget: &ResponseBudget{Timeout: 1500 * time.Millisecond, FallbackBody: []byte(`{"status":"degraded"}`)},
+		Schedules: []Schedule{{
+			Weekdays:     []time.Weekday{time.Saturday, time.Sunday},
+			From:         "22:00",
+			To:           "06:00",
+			Location:     berlin,
+			UpstreamHost: "maintenance.example.com",
+		}},
+		Routes:           []Route{{PathPrefix: "/admin", UpstreamHost: "admin.example.com"}},
+		Webhook:          &WebhookPolicy{Provider: WebhookGitHub, Secrets: [][]byte{[]byte("secret")}, Tolerance: time.Minute, PathPrefixes: []string{"/hooks/"}},
+		ForwardedHeaders: ForwardedHeadersReplace,
+	}
+
+	t.Run("format=yaml", func(t *testing.T) {
+		actual, err := ParseHostConfig([]byte(exampleHostConfig))
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("format=json", func(t *testing.T) {
+		raw, err := json.Marshal(expected)
+		require.NoError(t, err)
+		actual, err := ParseHostConfig(raw)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("case=schema violations", func(t *testing.T) {
+		for _, raw := range []string{
+			`{"upstream_scheme":"https"}`,
+			`{"upstream_hosts":[],"upstream_scheme":"https"}`,
+			`{"upstream_host":"example.com","upstream_hosts":["example.org"],"upstream_scheme":"https"}`,
+			`{"upstream_host":"example.com","upstream_scheme":"ftp"}`,
+			`{"upstream_host":"example.com","upstream_scheme":"https","unknown":true}`,
+			`{"upstream_host":"example.com","upstream_scheme":"https","path_prefix":"api/"}`,
+			`{"upstream_host":"example.com","upstream_scheme":"https","response_budget":{"timeout":"soon"}}`,
+		} {
+			_, err := ParseHostConfig([]byte(raw))
+			assert.ErrorIs(t, err, ErrInvalidHostConfig, raw)
+		}
+	})
+}
+
+func TestHostConfigValidate(t *testing.T) {
+	valid := func() *HostConfig {
+		return &HostConfig{UpstreamHost: "example.com", UpstreamScheme: "https"}
+	}
+	require.NoError(t, valid().Validate())
+
+	for _, tc := range []struct {
+		name   string
+		modify func(c *HostConfig)
+		field  string
+	}{
+		{name: "missing scheme", modify: func(c *HostConfig) { c.UpstreamScheme = "" }, field: "upstream_scheme"},
+		{name: "upstream host and hosts", modify: func(c *HostConfig) { c.UpstreamHosts = []string{"other.com"} }, field: "upstream_hosts"},
+		{name: "empty upstream host", modify: func(c *HostConfig) {
+			c.UpstreamHost = ""
+			c.UpstreamHosts = []string{"example.com", ""}
+		}, field: "upstream_hosts"},
+		{name: "trust domain without TLS", modify: func(c *HostConfig) {
+			c.UpstreamScheme = "http"
+			c.UpstreamTrustDomain = "example.org"
+		}, field: "upstream_trust_domain"},
+		{name: "certificate without key", modify: func(c *HostConfig) { c.TLSCertificatePath = "cert.pem" }, field: "tls_certificate_path"},
+		{name: "client certificate without key", modify: func(c *HostConfig) { c.UpstreamClientCertificatePath = "cert.pem" }, field: "upstream_client_certificate_path"},
+		{name: "client certificate without TLS", modify: func(c *HostConfig) {
+			c.UpstreamScheme = "http"
+			c.UpstreamClientCertificatePath, c.UpstreamClientKeyPath = "cert.pem", "key.pem"
+		}, field: "upstream_client_certificate_path"},
+		{name: "path prefix with trailing slash", modify: func(c *HostConfig) { c.PathPrefix = "/api/" }, field: "path_prefix"},
+		{name: "path prefix without leading slash", modify: func(c *HostConfig) { c.PathPrefix = "api" }, field: "path_prefix"},
+		{name: "cors without options", modify: func(c *HostConfig) { c.CorsEnabled = true }, field: "cors_enabled"},
+		{name: "invalid schedule", modify: func(c *HostConfig) { c.Schedules = []Schedule{{From: "25:00", To: "01:00"}} }, field: "schedules[0]: from:"},
+		{name: "route without prefix", modify: func(c *HostConfig) { c.Routes = []Route{{UpstreamHost: "other.com"}} }, field: "routes[0]: path_prefix"},
+		{name: "unknown webhook provider", modify: func(c *HostConfig) {
+			c.Webhook = &WebhookPolicy{Provider: "gitlab", Secrets: [][]byte{[]byte("secret")}}
+		}, field: "webhook.provider"},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			c := valid()
+			tc.modify(c)
+			err := c.Validate()
+			assert.ErrorIs(t, err, ErrInvalidHostConfig)
+			assert.Contains(t, err.Error(), tc.field)
+		})
+	}
+}
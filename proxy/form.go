@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// MultipartForm is a multipart/form-data body which preserves the order and headers of all parts.
+	MultipartForm struct {
+		Parts []*MultipartPart
+	}
+	// MultipartPart is a single part of a MultipartForm.
+	MultipartPart struct {
+		Header  textproto.MIMEHeader
+		Content []byte
+	}
+)
+
+// FormReqMiddleware returns a ReqMiddleware which parses application/x-www-form-urlencoded request bodies,
+// passes the form to f for modification and encodes it again. Other requests are passed on unchanged.
+func FormReqMiddleware(f func(req *http.Request, config *HostConfig, form url.Values) error) ReqMiddleware {
+	return func(req *http.Request, config *HostConfig, body []byte) ([]byte, error) {
+		if mediaType(req.Header) != "application/x-www-form-urlencoded" {
+			return body, nil
+		}
+
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if err := f(req, config, form); err != nil {
+			return nil, err
+		}
+		return []byte(form.Encode()), nil
+	}
+}
+
+// MultipartFormReqMiddleware returns a ReqMiddleware which parses multipart/form-data request bodies,
+// passes the form to f for modification and encodes it again using the original boundary.
+// Other requests are passed on unchanged.
+func MultipartFormReqMiddleware(f func(req *http.Request, config *HostConfig, form *MultipartForm) error) ReqMiddleware {
+	return func(req *http.Request, config *HostConfig, body []byte) ([]byte, error) {
+		mt, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+		if err != nil || mt != "multipart/form-data" || params["boundary"] == "" {
+			return body, nil
+		}
+
+		form, err := parseMultipartForm(body, params["boundary"])
+		if err != nil {
+			return nil, err
+		}
+		if err := f(req, config, form); err != nil {
+			return nil, err
+		}
+		return form.encode(params["boundary"])
+	}
+}
+
+func mediaType(h http.Header) string {
+	mt, _, _ := mime.ParseMediaType(h.Get("Content-Type"))
+	return mt
+}
+
+func parseMultipartForm(body []byte, boundary string) (*MultipartForm, error) {
+	form := new(MultipartForm)
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		p, err := r.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return form, nil
+		} else if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		content, err := io.ReadAll(p)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		form.Parts = append(form.Parts, &MultipartPart{Header: p.Header, Content: content})
+	}
+}
+
+func (f *MultipartForm) encode(boundary string) ([]byte, error) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	if err := w.SetBoundary(boundary); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, p := range f.Parts {
+		pw, err := w.CreatePart(p.Header)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if _, err := pw.Write(p.Content); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return b.Bytes(), nil
+}
+
+// FormName returns the name of the form field of this part.
+func (p *MultipartPart) FormName() string {
+	_, params, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	return params["name"]
+}
+
+// FileName returns the file name of this part, or an empty string if it is not a file.
+func (p *MultipartPart) FileName() string {
+	_, params, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	return params["filename"]
+}
+
+// Value returns the content of the first non-file part with the given name.
+func (f *MultipartForm) Value(name string) (string, bool) {
+	for _, p := range f.Parts {
+		if p.FormName() == name && p.FileName() == "" {
+			return string(p.Content), true
+		}
+	}
+	return "", false
+}
+
+// SetValue replaces the content of all non-file parts with the given name, or adds a new part if there is none.
+func (f *MultipartForm) SetValue(name, value string) {
+	found := false
+	for _, p := range f.Parts {
+		if p.FormName() == name && p.FileName() == "" {
+			p.Content = []byte(value)
+			found = true
+		}
+	}
+	if !found {
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": name}))
+		f.Parts = append(f.Parts, &MultipartPart{Header: h, Content: []byte(value)})
+	}
+}
+
+// Delete removes all parts with the given name, including files.
+func (f *MultipartForm) Delete(name string) {
+	parts := f.Parts[:0]
+	for _, p := range f.Parts {
+		if p.FormName() != name {
+			parts = append(parts, p)
+		}
+	}
+	f.Parts = parts
+}
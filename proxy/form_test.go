@@ -0,0 +1,77 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormReqMiddleware(t *testing.T) {
+	m := FormReqMiddleware(func(_ *http.Request, _ *HostConfig, form url.Values) error {
+		form.Set("csrf_token", "replaced")
+		form.Del("secret")
+		return nil
+	})
+
+	t.Run("case=rewrites url encoded forms", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "https://example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+		body, err := m(req, &HostConfig{}, []byte("csrf_token=original&secret=foo&name=bar"))
+		require.NoError(t, err)
+		assert.Equal(t, "csrf_token=replaced&name=bar", string(body))
+	})
+
+	t.Run("case=ignores other content types", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, "https://example.com", nil)
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		body, err := m(req, &HostConfig{}, []byte(`{"secret":"foo"}`))
+		require.NoError(t, err)
+		assert.Equal(t, `{"secret":"foo"}`, string(body))
+	})
+}
+
+func TestMultipartFormReqMiddleware(t *testing.T) {
+	var b bytes.Buffer
+	w := multipart.NewWriter(&b)
+	require.NoError(t, w.WriteField("csrf_token", "original"))
+	fw, err := w.CreateFormFile("upload", "file.txt")
+	require.NoError(t, err)
+	_, err = fw.Write([]byte("file content"))
+	require.NoError(t, err)
+	require.NoError(t, w.WriteField("secret", "foo"))
+	require.NoError(t, w.Close())
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com", nil)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	body, err := MultipartFormReqMiddleware(func(_ *http.Request, _ *HostConfig, form *MultipartForm) error {
+		v, ok := form.Value("csrf_token")
+		assert.True(t, ok)
+		assert.Equal(t, "original", v)
+
+		form.SetValue("csrf_token", "replaced")
+		form.SetValue("added", "value")
+		form.Delete("secret")
+		return nil
+	})(req, &HostConfig{}, b.Bytes())
+	require.NoError(t, err)
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	require.NoError(t, req.ParseMultipartForm(1<<20))
+	assert.Equal(t, []string{"replaced"}, req.MultipartForm.Value["csrf_token"])
+	assert.Equal(t, []string{"value"}, req.MultipartForm.Value["added"])
+	assert.NotContains(t, req.MultipartForm.Value, "secret")
+	require.Len(t, req.MultipartForm.File["upload"], 1)
+	assert.Equal(t, "file.txt", req.MultipartForm.File["upload"][0].Filename)
+}
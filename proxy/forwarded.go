@@ -0,0 +1,47 @@
+package proxy
+
+import "net/http"
+
+// ForwardedHeadersPolicy defines how the X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers are sent to the upstream.
+type ForwardedHeadersPolicy string
+
+const (
+	// ForwardedHeadersPassThrough forwards X-Forwarded-Proto and X-Forwarded-Host as received and appends the
+	// client IP to X-Forwarded-For, which is the default behavior of httputil.ReverseProxy.
+	ForwardedHeadersPassThrough ForwardedHeadersPolicy = ""
+	// ForwardedHeadersAppend appends the client IP to X-Forwarded-For and sets X-Forwarded-Proto and
+	// X-Forwarded-Host to the original scheme and host unless they were received from the client.
+	ForwardedHeadersAppend ForwardedHeadersPolicy = "append"
+	// ForwardedHeadersReplace discards the headers received from the client and sets them to the client IP,
+	// scheme and host of this connection. Use it if the proxy is the first hop.
+	ForwardedHeadersReplace ForwardedHeadersPolicy = "replace"
+	// ForwardedHeadersStrip removes the headers, so the upstream does not learn about the client.
+	ForwardedHeadersStrip ForwardedHeadersPolicy = "strip"
+)
+
+// trusted returns whether the headers received from the client may be used to determine the original scheme and host.
+func (p ForwardedHeadersPolicy) trusted() bool {
+	return p == ForwardedHeadersPassThrough || p == ForwardedHeadersAppend
+}
+
+// apply sets the headers of the outgoing request. X-Forwarded-For is set by httputil.ReverseProxy afterwards,
+// unless the header is present with a nil value.
+func (p ForwardedHeadersPolicy) apply(r *http.Request, c *HostConfig) {
+	switch p {
+	case ForwardedHeadersAppend:
+		if r.Header.Get("X-Forwarded-Proto") == "" {
+			r.Header.Set("X-Forwarded-Proto", c.originalScheme)
+		}
+		if r.Header.Get("X-Forwarded-Host") == "" {
+			r.Header.Set("X-Forwarded-Host", c.originalHost)
+		}
+	case ForwardedHeadersReplace:
+		r.Header.Del("X-Forwarded-For")
+		r.Header.Set("X-Forwarded-Proto", c.originalScheme)
+		r.Header.Set("X-Forwarded-Host", c.originalHost)
+	case ForwardedHeadersStrip:
+		r.Header["X-Forwarded-For"] = nil
+		r.Header.Del("X-Forwarded-Proto")
+		r.Header.Del("X-Forwarded-Host")
+	}
+}
@@ -0,0 +1,88 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestForwardedHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]string{
+			"for":   r.Header.Values("X-Forwarded-For"),
+			"proto": r.Header.Values("X-Forwarded-Proto"),
+			"host":  r.Header.Values("X-Forwarded-Host"),
+		})
+	}))
+	defer upstream.Close()
+
+	for _, tc := range []struct {
+		policy   ForwardedHeadersPolicy
+		received bool
+		expected map[string][]string
+	}{
+		{
+			policy:   ForwardedHeadersPassThrough,
+			received: true,
+			expected: map[string][]string{"for": {"10.0.0.1, 127.0.0.1"}, "proto": {"https"}, "host": {"spoofed.com"}},
+		},
+		{
+			policy:   ForwardedHeadersPassThrough,
+			expected: map[string][]string{"for": {"127.0.0.1"}, "proto": nil, "host": nil},
+		},
+		{
+			policy:   ForwardedHeadersAppend,
+			received: true,
+			expected: map[string][]string{"for": {"10.0.0.1, 127.0.0.1"}, "proto": {"https"}, "host": {"spoofed.com"}},
+		},
+		{
+			policy:   ForwardedHeadersAppend,
+			expected: map[string][]string{"for": {"127.0.0.1"}, "proto": {"http"}, "host": {"example.com"}},
+		},
+		{
+			policy:   ForwardedHeadersReplace,
+			received: true,
+			expected: map[string][]string{"for": {"127.0.0.1"}, "proto": {"http"}, "host": {"example.com"}},
+		},
+		{
+			policy:   ForwardedHeadersStrip,
+			received: true,
+			expected: map[string][]string{"for": nil, "proto": nil, "host": nil},
+		},
+	} {
+		t.Run("policy="+string(tc.policy), func(t *testing.T) {
+			proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+				return &HostConfig{
+					UpstreamHost:     urlx.ParseOrPanic(upstream.URL).Host,
+					UpstreamScheme:   "http",
+					ForwardedHeaders: tc.policy,
+				}, nil
+			}))
+			defer proxy.Close()
+
+			req, err := http.NewRequest(http.MethodGet, proxy.URL, nil)
+			require.NoError(t, err)
+			req.Host = "example.com"
+			if tc.received {
+				req.Header.Set("X-Forwarded-For", "10.0.0.1")
+				req.Header.Set("X-Forwarded-Proto", "https")
+				req.Header.Set("X-Forwarded-Host", "spoofed.com")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			var actual map[string][]string
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&actual))
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HeaderRule normalizes a response header before it is sent to the client.
+// Rules are applied in the order they are defined.
+type HeaderRule struct {
+	// Name is the name of the header the rule applies to. It is matched case-insensitively.
+	Name string `json:"name,omitempty"`
+	// RenameTo is the exact name, including its casing, under which the header is sent to the client,
+	// e.g. "ETag" instead of Go's canonical "Etag".
+	// If left empty, the canonical form of Name is used.
+	RenameTo string `json:"rename_to,omitempty"`
+	// Dedupe removes repeated values, keeping the first occurrence.
+	Dedupe bool `json:"dedupe,omitempty"`
+	// Coalesce joins all values into a single comma separated value.
+	// Must not be used with headers which do not support lists, such as Set-Cookie.
+	Coalesce bool `json:"coalesce,omitempty"`
+}
+
+// headerCasingWriter renames the headers of the response to the casing of the RenameTo of the rules before they
+// are written. httputil.ReverseProxy canonicalizes the names when it copies the upstream headers, so the casing
+// can only be applied to the headers of the client response.
+type headerCasingWriter struct {
+	http.ResponseWriter
+	// names maps the canonical names to their casing
+	names       map[string]string
+	wroteHeader bool
+}
+
+func applyHeaderRules(h http.Header, rules []HeaderRule) {
+	for _, rule := range rules {
+		var keys []string
+		for k := range h {
+			if strings.EqualFold(k, rule.Name) {
+				keys = append(keys, k)
+			}
+		}
+		// the same header might be present under different casings, merge them deterministically
+		sort.Strings(keys)
+
+		var values []string
+		for _, k := range keys {
+			values = append(values, h[k]...)
+			delete(h, k)
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		if rule.Dedupe {
+			seen := make(map[string]bool, len(values))
+			deduped := values[:0]
+			for _, v := range values {
+				if !seen[v] {
+					seen[v] = true
+					deduped = append(deduped, v)
+				}
+			}
+			values = deduped
+		}
+		if rule.Coalesce {
+			values = []string{strings.Join(values, ", ")}
+		}
+
+		name := rule.RenameTo
+		if name == "" {
+			name = textproto.CanonicalMIMEHeaderKey(rule.Name)
+		}
+		// not using h.Set, as it would canonicalize the name
+		h[name] = values
+	}
+}
+
+// withHeaderCasing wraps the writer if a rule renames a header to a name which is not canonical.
+func withHeaderCasing(w http.ResponseWriter, rules []HeaderRule) http.ResponseWriter {
+	var names map[string]string
+	for _, rule := range rules {
+		if canonical := textproto.CanonicalMIMEHeaderKey(rule.RenameTo); rule.RenameTo != "" && canonical != rule.RenameTo {
+			if names == nil {
+				names = make(map[string]string)
+			}
+			names[canonical] = rule.RenameTo
+		}
+	}
+	if names == nil {
+		return w
+	}
+	return &headerCasingWriter{ResponseWriter: w, names: names}
+}
+
+func (w *headerCasingWriter) rename() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.ResponseWriter.Header()
+	for canonical, name := range w.names {
+		if values, ok := h[canonical]; ok {
+			delete(h, canonical)
+			// not using h.Set, as it would canonicalize the name
+			h[name] = values
+		}
+	}
+}
+
+func (w *headerCasingWriter) WriteHeader(code int) {
+	// informational responses are followed by the final one, whose headers are renamed
+	if code >= 200 || code == http.StatusSwitchingProtocols {
+		w.rename()
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *headerCasingWriter) Write(p []byte) (int, error) {
+	w.rename()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *headerCasingWriter) Flush() {
+	w.rename()
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *headerCasingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *headerCasingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
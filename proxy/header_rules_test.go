@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestApplyHeaderRules(t *testing.T) {
+	h := http.Header{
+		"Etag":          {`"abc"`},
+		"Cache-Control": {"no-cache", "no-store", "no-cache"},
+		"x-custom":      {"a"},
+		"X-Custom":      {"b", "a"},
+		"Vary":          {"Origin"},
+	}
+
+	applyHeaderRules(h, []HeaderRule{
+		{Name: "etag", RenameTo: "ETag"},
+		{Name: "Cache-Control", Dedupe: true, Coalesce: true},
+		{Name: "X-Custom", Dedupe: true},
+		{Name: "X-Missing", Coalesce: true},
+	})
+
+	assert.Equal(t, []string{`"abc"`}, h["ETag"])
+	assert.NotContains(t, h, "Etag")
+	assert.Equal(t, []string{"no-cache, no-store"}, h["Cache-Control"])
+	assert.Equal(t, []string{"b", "a"}, h["X-Custom"])
+	assert.NotContains(t, h, "x-custom")
+	assert.NotContains(t, h, "X-Missing")
+	assert.Equal(t, []string{"Origin"}, h["Vary"])
+}
+
+func TestHeaderRulesCasing(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"abc"`)
+		w.Header().Set("X-Request-Id", "123")
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+		return &HostConfig{
+			UpstreamHost:   urlx.ParseOrPanic(upstream.URL).Host,
+			UpstreamScheme: "http",
+			ResponseHeaderRules: []HeaderRule{
+				{Name: "etag", RenameTo: "ETag"},
+				{Name: "x-request-id", RenameTo: "X-Request-ID"},
+			},
+		}, nil
+	}))
+	defer proxy.Close()
+
+	// the response is read off the wire, as http.Client canonicalizes the names
+	conn, err := net.Dial("tcp", urlx.ParseOrPanic(proxy.URL).Host)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n")
+	require.NoError(t, err)
+
+	var lines []string
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\r\n" {
+			break
+		}
+		lines = append(lines, line)
+	}
+	assert.Contains(t, lines, "ETag: \"abc\"\r\n")
+	assert.Contains(t, lines, "X-Request-ID: 123\r\n")
+	assert.NotContains(t, lines, "Etag: \"abc\"\r\n")
+}
@@ -0,0 +1,61 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// UpstreamCheck checks whether an upstream responds. Its Check method is a healthx.ReadyChecker, so it can be
+// registered using healthx.Registry.AddReadyCheck, e.g. once per upstream.
+type UpstreamCheck struct {
+	// Transport is used to send the check requests.
+	// Default: http.DefaultTransport
+	Transport http.RoundTripper
+	// Upstream is the URL to check, e.g. https://upstream.internal/health/alive.
+	Upstream *url.URL
+	// Method is the HTTP method of the check requests.
+	// Default: GET
+	Method string
+	// Timeout is the maximum duration of a check.
+	// Default: 5s
+	Timeout time.Duration
+}
+
+// Check sends a request to the upstream and returns an error if it fails or the upstream responds with a 5xx status code.
+func (u *UpstreamCheck) Check(r *http.Request) error {
+	transport, method, timeout := u.Transport, u.Method, u.Timeout
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if method == "" {
+		method = http.MethodGet
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.Upstream.String(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer resp.Body.Close()
+	// the body must be consumed for the connection to be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return errors.Errorf("upstream %s responded with status code %d", u.Upstream.Host, resp.StatusCode)
+	}
+	return nil
+}
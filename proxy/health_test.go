@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/herodot"
+
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/urlx"
+)
+
+func TestUpstreamCheck(t *testing.T) {
+	status := http.StatusOK
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer upstream.Close()
+
+	registry := healthx.NewRegistry()
+	registry.AddReadyCheck("upstream", (&UpstreamCheck{Upstream: urlx.ParseOrPanic(upstream.URL + "/health")}).Check)
+	registry.AddReadyCheck("database", func(*http.Request) error { return nil })
+	health := httptest.NewServer((&healthx.Handler{H: herodot.NewJSONWriter(nil), Registry: registry}).ServeMux(true))
+	defer health.Close()
+
+	resp, err := http.Get(health.URL + healthx.ReadyCheckPath)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	status = http.StatusBadGateway
+	resp, err = http.Get(health.URL + healthx.ReadyCheckPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var body struct {
+		Errors map[string]string `json:"errors"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Len(t, body.Errors, 1)
+	assert.Contains(t, body.Errors["upstream"], "status code 502")
+
+	upstream.Close()
+	assert.Error(t, (&UpstreamCheck{Upstream: urlx.ParseOrPanic(upstream.URL)}).Check(httptest.NewRequest("GET", "/", nil)))
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrHostNotFound should be returned (optionally wrapped) by a HostMapper if no HostConfig exists for the
+// requested host. Instead of calling the request error handler, the proxy responds with the
+// configured host not found response (see WithHostNotFound).
+var ErrHostNotFound = errors.New("no host config exists for the requested host")
+
+type hostNotFoundResponse struct {
+	code        int
+	contentType string
+	body        []byte
+	counter     prometheus.Counter
+}
+
+// WithHostNotFound sets the response for unmapped hosts. Use http.StatusMisdirectedRequest (421) if
+// the host is served by the proxy but reached via a connection for another host.
+// Default: 404 with the status text as body
+func WithHostNotFound(code int, contentType string, body []byte) Options {
+	return func(o *options) {
+		o.hostNotFound.code = code
+		o.hostNotFound.contentType = contentType
+		o.hostNotFound.body = body
+	}
+}
+
+// WithHostNotFoundCounter increments the counter whenever a request for an unmapped host is received.
+// The counter has to be registered by the caller.
+func WithHostNotFoundCounter(c prometheus.Counter) Options {
+	return func(o *options) {
+		o.hostNotFound.counter = c
+	}
+}
+
+func (o *options) respondHostNotFound(w http.ResponseWriter, r *http.Request) {
+	if o.hostNotFound.counter != nil {
+		o.hostNotFound.counter.Inc()
+	}
+
+	code, body := o.hostNotFound.code, o.hostNotFound.body
+	if code == 0 {
+		code = http.StatusNotFound
+	}
+	if body == nil {
+		body = []byte(http.StatusText(code))
+	}
+	o.writeResponse(w, r, code, o.hostNotFound.contentType, body)
+}
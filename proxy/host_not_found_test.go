@@ -0,0 +1,51 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostNotFound(t *testing.T) {
+	hostMapper := func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		return nil, errors.Wrapf(ErrHostNotFound, "host %s", r.Host)
+	}
+
+	t.Run("case=default response", func(t *testing.T) {
+		proxy := httptest.NewServer(New(hostMapper, WithOnError(func(*http.Request, error) {
+			t.Error("the request error handler must not be called")
+		}, nil)))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("case=custom response and counter", func(t *testing.T) {
+		counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "unmapped_hosts_total"})
+		proxy := httptest.NewServer(New(hostMapper,
+			WithHostNotFound(http.StatusMisdirectedRequest, "application/json", []byte(`{"error":"unknown host"}`)),
+			WithHostNotFoundCounter(counter)))
+		defer proxy.Close()
+
+		for i := 0; i < 2; i++ {
+			resp, err := http.Get(proxy.URL)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusMisdirectedRequest, resp.StatusCode)
+			assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+			assert.Equal(t, `{"error":"unknown host"}`, string(body))
+		}
+		assert.Equal(t, float64(2), testutil.ToFloat64(counter))
+	})
+}
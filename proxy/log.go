@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logx"
+)
+
+type accessLogWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+// WithLogger sets the logger for access and error logs. Every completed request is logged at info level,
+// including the annotations of the request (see Annotate). Without a logger, only errors are logged using
+// the standard library's log package, just like httputil.ReverseProxy does.
+func WithLogger(l logx.Logger) Options {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+func (o *options) logError(r *http.Request, msg string, err error) {
+	if o.logger == nil {
+		return
+	}
+	args := []interface{}{"error", err, "method", r.Method, "host", r.Host, "path", r.URL.Path}
+	if c, ok := r.Context().Value(hostConfigKey).(*HostConfig); ok && c != nil {
+		args = append(args, "upstream", c.UpstreamHost)
+	}
+	o.logger.Error(msg, args...)
+}
+
+// logAccess wraps the writer and returns a function which logs the request once it has been handled.
+func (o *options) logAccess(w http.ResponseWriter) (http.ResponseWriter, func(*http.Request)) {
+	if o.logger == nil {
+		return w, func(*http.Request) {}
+	}
+	start := time.Now()
+	aw := &accessLogWriter{ResponseWriter: w}
+	return aw, func(r *http.Request) {
+		status := aw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		args := []interface{}{
+			"method", r.Method,
+			"host", r.Host,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", aw.written,
+			"took", time.Since(start),
+		}
+		if c, ok := r.Context().Value(hostConfigKey).(*HostConfig); ok && c != nil {
+			args = append(args, "upstream", c.UpstreamHost)
+		}
+		if a := AnnotationsFromContext(r.Context()).All(); len(a) > 0 {
+			args = append(args, "annotations", a)
+		}
+		o.logger.Info("completed handling request", args...)
+	}
+}
+
+func (w *accessLogWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+func (w *accessLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *accessLogWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,106 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+type logEntry struct {
+	level, msg string
+	fields     map[string]interface{}
+}
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) log(level, msg string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(args); i += 2 {
+		fields[args[i].(string)] = args[i+1]
+	}
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, fields: fields})
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.log("debug", msg, args...) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.log("info", msg, args...) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.log("warn", msg, args...) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.log("error", msg, args...) }
+
+func (l *recordingLogger) all() []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]logEntry{}, l.entries...)
+}
+
+func TestWithLogger(t *testing.T) {
+	t.Run("case=logs completed requests with annotations", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("hello"))
+		}))
+		defer upstream.Close()
+		upstreamHost := urlx.ParseOrPanic(upstream.URL).Host
+
+		l := new(recordingLogger)
+		proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{UpstreamHost: upstreamHost, UpstreamScheme: "http"}, nil
+		}, WithLogger(l), WithReqMiddleware(func(req *http.Request, _ *HostConfig, body []byte) ([]byte, error) {
+			Annotate(req, "user_id", "foo")
+			return body, nil
+		})))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL + "/bar")
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		entries := l.all()
+		require.Len(t, entries, 1)
+		assert.Equal(t, "info", entries[0].level)
+		assert.Equal(t, "completed handling request", entries[0].msg)
+		assert.Equal(t, http.StatusCreated, entries[0].fields["status"])
+		assert.Equal(t, int64(5), entries[0].fields["bytes"])
+		assert.Equal(t, "/bar", entries[0].fields["path"])
+		assert.Equal(t, upstreamHost, entries[0].fields["upstream"])
+		assert.Equal(t, map[string]interface{}{"user_id": "foo"}, entries[0].fields["annotations"])
+	})
+
+	t.Run("case=logs upstream errors", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		unreachable := listener.Addr().String()
+		require.NoError(t, listener.Close())
+
+		l := new(recordingLogger)
+		proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{UpstreamHost: unreachable, UpstreamScheme: "http"}, nil
+		}, WithLogger(l)))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+		entries := l.all()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "error", entries[0].level)
+		assert.Error(t, entries[0].fields["error"].(error))
+		assert.Equal(t, unreachable, entries[0].fields["upstream"])
+		assert.Equal(t, http.StatusBadGateway, entries[1].fields["status"])
+	})
+}
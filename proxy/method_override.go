@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodOverridePolicy defines how method override headers such as X-HTTP-Method-Override are treated.
+type MethodOverridePolicy string
+
+const (
+	// MethodOverridePassThrough forwards method override headers unchanged to the upstream.
+	MethodOverridePassThrough MethodOverridePolicy = ""
+	// MethodOverrideStrip removes method override headers, protecting the upstream from verb tunneling.
+	MethodOverrideStrip MethodOverridePolicy = "strip"
+	// MethodOverrideHonor applies the method override of POST requests at the proxy and removes the headers.
+	// Only PUT, PATCH and DELETE can be tunneled.
+	MethodOverrideHonor MethodOverridePolicy = "honor"
+)
+
+var methodOverrideHeaders = []string{"X-HTTP-Method-Override", "X-HTTP-Method", "X-Method-Override"}
+
+func (p MethodOverridePolicy) apply(r *http.Request) {
+	if p == MethodOverridePassThrough {
+		return
+	}
+
+	var override string
+	for _, h := range methodOverrideHeaders {
+		if v := r.Header.Get(h); v != "" && override == "" {
+			override = strings.ToUpper(strings.TrimSpace(v))
+		}
+		r.Header.Del(h)
+	}
+
+	if p != MethodOverrideHonor || r.Method != http.MethodPost {
+		return
+	}
+	switch override {
+	case http.MethodPut, http.MethodPatch, http.MethodDelete:
+		r.Method = override
+	}
+}
@@ -0,0 +1,37 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodOverridePolicy(t *testing.T) {
+	newReq := func(method, override string) *http.Request {
+		r := httptest.NewRequest(method, "/", nil)
+		r.Header.Set("X-HTTP-Method-Override", override)
+		return r
+	}
+
+	for _, tc := range []struct {
+		name           string
+		policy         MethodOverridePolicy
+		req            *http.Request
+		expectedMethod string
+		expectedHeader string
+	}{
+		{name: "pass through", policy: MethodOverridePassThrough, req: newReq(http.MethodPost, "DELETE"), expectedMethod: http.MethodPost, expectedHeader: "DELETE"},
+		{name: "strip", policy: MethodOverrideStrip, req: newReq(http.MethodPost, "DELETE"), expectedMethod: http.MethodPost},
+		{name: "honor", policy: MethodOverrideHonor, req: newReq(http.MethodPost, "delete"), expectedMethod: http.MethodDelete},
+		{name: "honor only for POST", policy: MethodOverrideHonor, req: newReq(http.MethodGet, "DELETE"), expectedMethod: http.MethodGet},
+		{name: "honor only allowed methods", policy: MethodOverrideHonor, req: newReq(http.MethodPost, "CONNECT"), expectedMethod: http.MethodPost},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			tc.policy.apply(tc.req)
+			assert.Equal(t, tc.expectedMethod, tc.req.Method)
+			assert.Equal(t, tc.expectedHeader, tc.req.Header.Get("X-HTTP-Method-Override"))
+		})
+	}
+}
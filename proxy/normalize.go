@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMalformedRequest is returned when a request is rejected by the request normalization.
+var ErrMalformedRequest = errors.New("the request is malformed")
+
+// Normalization configures how requests are normalized before they are matched against the
+// PathPrefix and forwarded, to prevent the proxy and the upstream from interpreting a request differently.
+type Normalization struct {
+	// MergeSlashes replaces repeated slashes in the path with a single one. Encoded slashes (%2F) are kept.
+	MergeSlashes bool `json:"merge_slashes,omitempty"`
+	// RejectInvalidEncoding rejects requests with malformed percent-encoding in the query,
+	// or control characters in the decoded path.
+	RejectInvalidEncoding bool `json:"reject_invalid_encoding,omitempty"`
+	// RejectTraversal rejects requests containing "." or ".." path segments, including percent-encoded ones.
+	RejectTraversal bool `json:"reject_traversal,omitempty"`
+	// StripFragment removes the fragment from the request URL.
+	StripFragment bool `json:"strip_fragment,omitempty"`
+}
+
+// normalize applies the normalization to the request. Requests which must be rejected
+// result in an error wrapping ErrMalformedRequest.
+func (n *Normalization) normalize(r *http.Request) error {
+	if n == nil {
+		return nil
+	}
+
+	if n.StripFragment {
+		r.URL.Fragment = ""
+		r.URL.RawFragment = ""
+	}
+
+	if n.RejectInvalidEncoding {
+		if _, err := url.ParseQuery(r.URL.RawQuery); err != nil {
+			return errors.Wrap(ErrMalformedRequest, "invalid query encoding")
+		}
+		for _, c := range r.URL.Path {
+			if c < 0x20 || c == 0x7f {
+				return errors.Wrap(ErrMalformedRequest, "control character in path")
+			}
+		}
+	}
+
+	if n.RejectTraversal {
+		for _, segment := range strings.FieldsFunc(r.URL.Path, func(c rune) bool { return c == '/' || c == '\\' }) {
+			if segment == "." || segment == ".." {
+				return errors.Wrap(ErrMalformedRequest, "path traversal")
+			}
+		}
+	}
+
+	if n.MergeSlashes {
+		// only literal slashes are merged, encoded ones (%2F) are part of a segment and must stay encoded
+		escaped := r.URL.EscapedPath()
+		if merged := mergeSlashes(escaped); merged != escaped {
+			p, err := url.PathUnescape(merged)
+			if err != nil {
+				return errors.Wrap(ErrMalformedRequest, "invalid path encoding")
+			}
+			r.URL.Path, r.URL.RawPath = p, merged
+		}
+	}
+
+	return nil
+}
+
+func mergeSlashes(p string) string {
+	if !strings.Contains(p, "//") {
+		return p
+	}
+	var b strings.Builder
+	b.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && i > 0 && p[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalization(t *testing.T) {
+	all := &Normalization{MergeSlashes: true, RejectInvalidEncoding: true, RejectTraversal: true, StripFragment: true}
+
+	for _, tc := range []struct {
+		name, url           string
+		n                   *Normalization
+		expectedPath        string
+		expectedEscapedPath string
+		rejected            bool
+	}{
+		{name: "disabled", url: "https://example.com/foo//../bar", n: nil, expectedPath: "/foo//../bar"},
+		{name: "merge slashes", url: "https://example.com//foo///bar/", n: all, expectedPath: "/foo/bar/"},
+		{name: "encoded slashes are kept", url: "https://example.com/files/a%2F%2Fb", n: all, expectedPath: "/files/a//b", expectedEscapedPath: "/files/a%2F%2Fb"},
+		{name: "encoded slash before a slash", url: "https://example.com/files/x%2F/y", n: all, expectedPath: "/files/x//y", expectedEscapedPath: "/files/x%2F/y"},
+		{name: "merge slashes next to encoded ones", url: "https://example.com//files//a%2F%2Fb", n: all, expectedPath: "/files/a//b", expectedEscapedPath: "/files/a%2F%2Fb"},
+		{name: "traversal", url: "https://example.com/foo/../admin", n: all, rejected: true},
+		{name: "encoded traversal", url: "https://example.com/foo/%2e%2e/admin", n: all, rejected: true},
+		{name: "backslash traversal", url: "https://example.com/foo/..%5cadmin", n: all, rejected: true},
+		{name: "dot segment", url: "https://example.com/./admin", n: all, rejected: true},
+		{name: "dots in names are fine", url: "https://example.com/foo/..bar/file.tar.gz", n: all, expectedPath: "/foo/..bar/file.tar.gz"},
+		{name: "invalid query encoding", url: "https://example.com/foo?bar=%zz", n: all, rejected: true},
+		{name: "control character", url: "https://example.com/foo%00bar", n: all, rejected: true},
+	} {
+		t.Run("case="+tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.url)
+			require.NoError(t, err)
+			r := &http.Request{URL: u}
+
+			err = tc.n.normalize(r)
+			if tc.rejected {
+				assert.ErrorIs(t, err, ErrMalformedRequest)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedPath, r.URL.Path)
+			if tc.expectedEscapedPath != "" {
+				assert.Equal(t, tc.expectedEscapedPath, r.URL.EscapedPath())
+			}
+		})
+	}
+
+	t.Run("case=strip fragment", func(t *testing.T) {
+		u, err := url.Parse("https://example.com/foo#bar")
+		require.NoError(t, err)
+		r := &http.Request{URL: u}
+		require.NoError(t, all.normalize(r))
+		assert.Equal(t, "https://example.com/foo", r.URL.String())
+	})
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Prewarmer establishes connections to upstreams ahead of time and keeps them alive with periodic
+// requests, so that the first request after an idle period does not pay for the TCP and TLS handshakes.
+//
+// Connections are only reused by the proxy if the Prewarmer uses the same transport as the proxy (see WithTransport).
+// Keep in mind that http.Transport only keeps MaxIdleConnsPerHost (default 2) idle connections per host.
+type Prewarmer struct {
+	// Transport is used to establish the connections.
+	// Default: http.DefaultTransport
+	Transport http.RoundTripper
+	// Upstreams are the upstream base URLs to keep connections to, e.g. https://upstream.internal.
+	Upstreams []*url.URL
+	// Connections is the number of connections to keep per upstream.
+	// Default: 1
+	Connections int
+	// Interval is the time between two pings. Should be lower than the idle timeout of transport and upstream.
+	// Default: 30s
+	Interval time.Duration
+	// Method is the HTTP method of the pings.
+	// Default: HEAD
+	Method string
+}
+
+// Warm sends Connections concurrent requests to every upstream, establishing new connections if necessary.
+// It returns the first error encountered, but always tries all upstreams.
+func (p *Prewarmer) Warm(ctx context.Context) error {
+	transport, method, conns := p.Transport, p.Method, p.Connections
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	if method == "" {
+		method = http.MethodHead
+	}
+	if conns <= 0 {
+		conns = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, u := range p.Upstreams {
+		for i := 0; i < conns; i++ {
+			wg.Add(1)
+			go func(u *url.URL) {
+				defer wg.Done()
+				if err := ping(ctx, transport, method, u); err != nil {
+					mu.Lock()
+					defer mu.Unlock()
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+			}(u)
+		}
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Run warms the connections immediately and then every Interval until the context is canceled.
+// Errors are passed to onError, which may be nil.
+func (p *Prewarmer) Run(ctx context.Context, onError func(error)) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Warm(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func ping(ctx context.Context, transport http.RoundTripper, method string, u *url.URL) error {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// the body must be consumed for the connection to be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return errors.WithStack(resp.Body.Close())
+}
@@ -0,0 +1,56 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestPrewarmer(t *testing.T) {
+	const conns = 3
+
+	var newConns int32
+	arrived := make(chan struct{})
+	var once sync.Once
+	var inFlight int32
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// block until all pings are in flight, so that every ping needs its own connection
+		if atomic.AddInt32(&inFlight, 1) == conns {
+			once.Do(func() { close(arrived) })
+		}
+		<-arrived
+	}))
+	upstream.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&newConns, 1)
+		}
+	}
+	upstream.Start()
+	defer upstream.Close()
+
+	transport := &http.Transport{MaxIdleConnsPerHost: conns}
+	p := &Prewarmer{
+		Transport:   transport,
+		Upstreams:   []*url.URL{urlx.ParseOrPanic(upstream.URL)},
+		Connections: conns,
+	}
+
+	require.NoError(t, p.Warm(context.Background()))
+	assert.EqualValues(t, conns, atomic.LoadInt32(&newConns))
+
+	// subsequent requests reuse the warm connections
+	resp, err := (&http.Client{Transport: transport}).Get(upstream.URL)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.EqualValues(t, conns, atomic.LoadInt32(&newConns))
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"net/http"
 	"net/http/httputil"
+	"net/url"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/rs/cors"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ory/x/logx"
 )
 
 type (
@@ -20,37 +26,113 @@ type (
 		respMiddlewares []RespMiddleware
 		reqMiddlewares  []ReqMiddleware
 		transport       http.RoundTripper
+		slowClient      *slowClientPolicy
+		lastResponses   responseCache
+		errorReporter   ErrorReporter
+		hostNotFound    hostNotFoundResponse
+		svidSource      SVIDSource
+		websocket       websocketCallbacks
+		logger          logx.Logger
+		streaming       bool
+		// streamingContentTypes are streamed even without streaming
+		streamingContentTypes []string
+		flushInterval         time.Duration
+		balancer              Balancer
 	}
 	HostConfig struct {
 		// CorsEnabled is a flag to enable or disable CORS
 		// Default: false
-		CorsEnabled bool
+		CorsEnabled bool `json:"cors_enabled,omitempty"`
 		// CorsOptions allows to configure CORS
 		// If left empty, no CORS headers will be set even when CorsEnabled is true
-		CorsOptions *cors.Options
+		CorsOptions *cors.Options `json:"cors,omitempty"`
 		// CookieDomain is the host under which cookies are set.
 		// If left empty, no cookie domain will be set
-		CookieDomain string
+		CookieDomain string `json:"cookie_domain,omitempty"`
 		// UpstreamHost is the next upstream host the proxy will pass the request to.
 		// e.g. fluffy-bear-afiu23iaysd.oryapis.com
-		UpstreamHost string
+		UpstreamHost string `json:"upstream_host,omitempty"`
+		// UpstreamHosts are replicas of the upstream, which replace UpstreamHost. Each request is forwarded to one
+		// of them, picked by the Balancer set via WithBalancer. ReadUpstreamHost, routes and schedules with an
+		// UpstreamHost take precedence.
+		UpstreamHosts []string `json:"upstream_hosts,omitempty"`
 		// UpstreamScheme is the protocol used by the upstream service.
-		UpstreamScheme string
+		UpstreamScheme string `json:"upstream_scheme,omitempty"`
+		// ReadUpstreamHost, if set, replaces UpstreamHost for safe requests (GET, HEAD, OPTIONS),
+		// e.g. to serve them from a read replica. Routes take precedence.
+		ReadUpstreamHost string `json:"read_upstream_host,omitempty"`
+		// ReadUpstreamScheme is the protocol used by ReadUpstreamHost.
+		// If left empty, UpstreamScheme is used.
+		ReadUpstreamScheme string `json:"read_upstream_scheme,omitempty"`
+		// UpstreamTrustDomain is the SPIFFE trust domain of the upstream, e.g. "example.org". If set, the proxy
+		// authenticates to the upstream with mTLS using the SVIDs of the source set via WithSVIDSource.
+		UpstreamTrustDomain string `json:"upstream_trust_domain,omitempty"`
+		// UpstreamClientCertificatePath is the path to the PEM encoded certificate the proxy presents to upstreams
+		// which require mutual TLS. The certificate is reloaded when the file changes.
+		// If left empty, no client certificate is presented.
+		UpstreamClientCertificatePath string `json:"upstream_client_certificate_path,omitempty"`
+		// UpstreamClientKeyPath is the path to the PEM encoded private key belonging to UpstreamClientCertificatePath.
+		UpstreamClientKeyPath string `json:"upstream_client_key_path,omitempty"`
+		// UpstreamCAPath is the path to the PEM encoded certificates of the authorities which issued the certificates
+		// of the upstreams. They are reloaded when the file changes.
+		// If left empty, the upstreams are verified with the roots of the transport set via WithTransport,
+		// which default to the root certificates of the system.
+		UpstreamCAPath string `json:"upstream_ca_path,omitempty"`
 		// TargetHost is the final target of the request. Should be the same as UpstreamHost
 		// if the request is directly passed to the target service.
-		TargetHost string
+		TargetHost string `json:"target_host,omitempty"`
 		// TargetScheme is the final target's scheme
 		// (i.e. the scheme the target thinks it is running under)
-		TargetScheme string
+		TargetScheme string `json:"target_scheme,omitempty"`
 		// PathPrefix is a prefix that is prepended on the original host,
 		// but removed before forwarding.
-		PathPrefix string
+		PathPrefix string `json:"path_prefix,omitempty"`
+		// TLSCertificatePath is the path to the PEM encoded certificate presented to clients connecting
+		// to this host. It is only used when serving with the TLS config returned by NewTLSConfig.
+		TLSCertificatePath string `json:"tls_certificate_path,omitempty"`
+		// TLSKeyPath is the path to the PEM encoded private key belonging to TLSCertificatePath.
+		TLSKeyPath string `json:"tls_key_path,omitempty"`
+		// UpstreamStatusPolicy replaces upstream responses with disallowed status codes by a sanitized response.
+		// If left empty, all upstream responses are passed on.
+		UpstreamStatusPolicy *StatusPolicy `json:"upstream_status_policy,omitempty"`
+		// ResponseBudget serves a fallback response if the upstream does not respond in time.
+		// If left empty, the proxy waits for the upstream indefinitely.
+		ResponseBudget *ResponseBudget `json:"response_budget,omitempty"`
+		// Schedules override the upstream during time windows, e.g. for planned maintenance.
+		// The first schedule active at the time of the request is applied.
+		Schedules []Schedule `json:"schedules,omitempty"`
+		// Normalization normalizes or rejects requests before they are forwarded.
+		// If left empty, requests are forwarded as received.
+		Normalization *Normalization `json:"normalization,omitempty"`
+		// MethodOverride defines whether X-HTTP-Method-Override and similar headers are honored, stripped, or passed on.
+		// Default: passed on
+		MethodOverride MethodOverridePolicy `json:"method_override,omitempty"`
+		// Routes select a different upstream for requests matching a path prefix.
+		Routes []Route `json:"routes,omitempty"`
+		// SignedURLs requires requests to be signed using SignURL.
+		// If left empty, no signature is required.
+		SignedURLs *SignedURLPolicy `json:"signed_urls,omitempty"`
+		// Webhook requires requests to carry a valid webhook signature, e.g. of Stripe or GitHub.
+		// If left empty, no signature is required.
+		Webhook *WebhookPolicy `json:"webhook,omitempty"`
+		// ForwardedHeaders defines whether X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host are appended to,
+		// replaced, or stripped. Replaced or stripped headers are not used to determine the original host and scheme.
+		// Default: passed on as received, with the client IP appended to X-Forwarded-For
+		ForwardedHeaders ForwardedHeadersPolicy `json:"forwarded_headers,omitempty"`
+		// ResponseHeaderRules rename, dedupe, and coalesce upstream response headers.
+		ResponseHeaderRules []HeaderRule `json:"response_header_rules,omitempty"`
 		// originalHost the original hostname the request is coming from.
 		// This value will be maintained internally by the proxy.
 		originalHost string
 		// originalScheme is the original scheme of the request.
 		// This value will be maintained internally by the proxy.
 		originalScheme string
+		// originalURL is the URL of the request before any rewrites were applied.
+		// This value will be maintained internally by the proxy.
+		originalURL *url.URL
+		// originalHeader are the headers of the request before any rewrites were applied.
+		// This value will be maintained internally by the proxy.
+		originalHeader http.Header
 	}
 	Options    func(*options)
 	contextKey string
@@ -66,29 +148,38 @@ func director(o *options) func(*http.Request) {
 		ctx := r.Context()
 		ctx, span := otel.GetTracerProvider().Tracer("").Start(ctx, "x.proxy")
 		defer span.End()
+		defer AnnotationsFromContext(ctx).AnnotateSpan(span)
 
 		c, err := o.getHostConfig(r)
 		if err != nil {
 			o.onReqError(r, err)
 			return
 		}
+		c = c.forMethod(r.Method).routed(r.URL.Path)
 
-		if forwardedProto := r.Header.Get("X-Forwarded-Proto"); forwardedProto != "" {
+		if forwardedProto := r.Header.Get("X-Forwarded-Proto"); forwardedProto != "" && c.ForwardedHeaders.trusted() {
 			c.originalScheme = forwardedProto
 		} else if r.TLS == nil {
 			c.originalScheme = "http"
 		} else {
 			c.originalScheme = "https"
 		}
-		if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" {
+		if forwardedHost := r.Header.Get("X-Forwarded-Host"); forwardedHost != "" && c.ForwardedHeaders.trusted() {
 			c.originalHost = forwardedHost
 		} else {
 			c.originalHost = r.Host
 		}
+		c.originalURL = originalURL(r, c)
+		c.originalHeader = r.Header.Clone()
+		c.ForwardedHeaders.apply(r, c)
 
 		*r = *r.WithContext(context.WithValue(ctx, hostConfigKey, c))
 		headerRequestRewrite(r, c)
 
+		if o.streamRequest() {
+			return
+		}
+
 		var body []byte
 		var cb *compressableBody
 
@@ -130,6 +221,17 @@ func modifyResponse(o *options) func(*http.Response) error {
 		if err := headerResponseRewrite(r, c); err != nil {
 			return o.onResError(r, err)
 		}
+		applyHeaderRules(r.Header, c.ResponseHeaderRules)
+
+		if code := r.StatusCode; c.UpstreamStatusPolicy.sanitize(r) {
+			o.reportError(r.Request, errors.Errorf("upstream %s responded with disallowed status code %d", c.UpstreamHost, code))
+			return nil
+		}
+
+		if o.streamResponse(r, c) {
+			o.streamedResponse(r, c)
+			return nil
+		}
 
 		body, cb, err := bodyResponseRewrite(r, c)
 		if err != nil {
@@ -142,6 +244,8 @@ func modifyResponse(o *options) func(*http.Response) error {
 			}
 		}
 
+		o.lastResponses.remember(r, c, body)
+
 		n, err := cb.Write(body)
 		if err != nil {
 			return o.onResError(r, err)
@@ -151,6 +255,7 @@ func modifyResponse(o *options) func(*http.Response) error {
 		if err != nil {
 			return err
 		}
+		t = o.websocket.track(r, c, t)
 
 		r.Header.Del("Content-Length")
 		r.ContentLength = int64(n)
@@ -159,6 +264,42 @@ func modifyResponse(o *options) func(*http.Response) error {
 	}
 }
 
+// OriginalURL returns the URL the client requested, before the request was rewritten for the upstream.
+// It is only available after the request was passed to the upstream, e.g. in a RespMiddleware.
+func (c *HostConfig) OriginalURL() *url.URL {
+	if c.originalURL == nil {
+		return nil
+	}
+	u := *c.originalURL
+	return &u
+}
+
+// OriginalHost returns the host the client requested.
+func (c *HostConfig) OriginalHost() string {
+	return c.originalHost
+}
+
+// OriginalScheme returns the scheme the client requested.
+func (c *HostConfig) OriginalScheme() string {
+	return c.originalScheme
+}
+
+// OriginalHeader returns a copy of the headers the client sent, before the request was rewritten for the upstream.
+func (c *HostConfig) OriginalHeader() http.Header {
+	return c.originalHeader.Clone()
+}
+
+func originalURL(r *http.Request, c *HostConfig) *url.URL {
+	u := *r.URL
+	u.Scheme = c.originalScheme
+	u.Host = c.originalHost
+	if u.User != nil {
+		user := *u.User
+		u.User = &user
+	}
+	return &u
+}
+
 func WithOnError(onReqErr func(*http.Request, error), onResErr func(*http.Response, error) error) Options {
 	return func(o *options) {
 		o.onReqError = onReqErr
@@ -184,6 +325,13 @@ func WithTransport(t http.RoundTripper) Options {
 	}
 }
 
+// CopyForRequest returns a shallow copy of the HostConfig. HostMappers which look up shared HostConfigs return
+// a copy for every request, because the proxy stores per-request state in the HostConfig.
+func (c *HostConfig) CopyForRequest() *HostConfig {
+	cc := *c
+	return &cc
+}
+
 func (o *options) getHostConfig(r *http.Request) (*HostConfig, error) {
 	if cached, ok := r.Context().Value(hostConfigKey).(*HostConfig); ok && cached != nil {
 		return cached, nil
@@ -192,6 +340,7 @@ func (o *options) getHostConfig(r *http.Request) (*HostConfig, error) {
 	if err != nil {
 		return nil, err
 	}
+	c = c.scheduled(time.Now())
 	// cache the host config in the request context
 	// this will be passed on to the request and response proxy functions
 	*r = *r.WithContext(context.WithValue(r.Context(), hostConfigKey, c))
@@ -200,13 +349,65 @@ func (o *options) getHostConfig(r *http.Request) (*HostConfig, error) {
 
 func (o *options) beforeProxyMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		request = request.WithContext(WithAnnotations(request.Context()))
+		writer, logAccess := o.logAccess(writer)
+		// the request is replaced below, the access log and the panic report use the final one
+		defer func() { logAccess(request) }()
+		defer func() { o.recoverPanic(recover(), writer, request) }()
+		// the annotations of response middlewares are only known once the response was sent
+		defer func() {
+			AnnotationsFromContext(request.Context()).AnnotateSpan(trace.SpanFromContext(request.Context()))
+		}()
+
+		writer, request, cancel := o.slowClient.wrap(writer, request)
+		defer cancel()
+
 		// get the hostmapper configurations before the request is proxied
 		c, err := o.getHostConfig(request)
-		if err != nil {
+		if errors.Is(err, ErrHostNotFound) {
+			o.respondHostNotFound(writer, request)
+			return
+		} else if err != nil {
+			o.onReqError(request, err)
+			return
+		}
+
+		writer = withHeaderCasing(writer, c.ResponseHeaderRules)
+
+		if err := c.Normalization.normalize(request); err != nil {
+			o.onReqError(request, err)
+			o.writeResponse(writer, request, http.StatusBadRequest, "", []byte(http.StatusText(http.StatusBadRequest)))
+			return
+		}
+
+		c.MethodOverride.apply(request)
+
+		if err := c.SignedURLs.verify(request); err != nil {
+			o.onReqError(request, err)
+			o.writeResponse(writer, request, http.StatusForbidden, "", []byte(http.StatusText(http.StatusForbidden)))
+			return
+		}
+
+		if err := c.Webhook.verify(writer, request); err != nil {
 			o.onReqError(request, err)
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrWebhookBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			o.writeResponse(writer, request, status, "", []byte(http.StatusText(status)))
 			return
 		}
 
+		if len(c.UpstreamHosts) > 0 {
+			var done func()
+			c, done = c.balanced(request, o.balancer)
+			defer done()
+			request = request.WithContext(context.WithValue(request.Context(), hostConfigKey, c))
+		}
+
+		request, cancelBudget := c.ResponseBudget.withBudget(request)
+		defer cancelBudget()
+
 		// Add our Cors middleware.
 		// This middleware will only trigger if the host config has cors enabled on that request.
 		if c.CorsEnabled && c.CorsOptions != nil {
@@ -224,6 +425,9 @@ func New(hostMapper HostMapper, opts ...Options) http.Handler {
 		onReqError: func(*http.Request, error) {},
 		onResError: func(_ *http.Response, err error) error { return err },
 		transport:  http.DefaultTransport,
+
+		streamingContentTypes: DefaultStreamingContentTypes,
+		balancer:              NewRoundRobinBalancer(),
 	}
 
 	for _, op := range opts {
@@ -233,7 +437,9 @@ func New(hostMapper HostMapper, opts ...Options) http.Handler {
 	rp := &httputil.ReverseProxy{
 		Director:       director(o),
 		ModifyResponse: modifyResponse(o),
-		Transport:      o.transport,
+		ErrorHandler:   o.errorHandler,
+		Transport:      &upstreamTransport{o: o, certs: NewCertificateStore()},
+		FlushInterval:  o.flushInterval,
 	}
 
 	return o.beforeProxyMiddleware(rp)
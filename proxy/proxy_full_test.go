@@ -276,6 +276,33 @@ func TestFullIntegration(t *testing.T) {
 				return body, nil
 			},
 		},
+		{
+			desc: "original request in response middleware",
+			hostMapper: func(host string) (*HostConfig, error) {
+				return &HostConfig{PathPrefix: "/foo"}, nil
+			},
+			handler: func(assert *assert.Assertions, w http.ResponseWriter, r *http.Request) {
+				assert.Equal("/bar", r.URL.Path)
+				_, err := w.Write([]byte("OK"))
+				assert.NoError(err)
+			},
+			request: func(t *testing.T) *http.Request {
+				req, err := http.NewRequest(http.MethodGet, proxy.URL+"/foo/bar?baz=qux", nil)
+				require.NoError(t, err)
+				req.Host = "original.example.com"
+				req.Header.Set("X-Client-Header", "client value")
+				return req
+			},
+			assertResponse: func(t *testing.T, r *http.Response) {
+				assert.Equal(t, "https://original.example.com/foo/bar?baz=qux", r.Header.Get("X-Original-URL"))
+				assert.Equal(t, "client value", r.Header.Get("X-Original-Client-Header"))
+			},
+			respMiddleware: func(resp *http.Response, config *HostConfig, body []byte) ([]byte, error) {
+				resp.Header.Set("X-Original-URL", config.OriginalURL().String())
+				resp.Header.Set("X-Original-Client-Header", config.OriginalHeader().Get("X-Client-Header"))
+				return body, nil
+			},
+		},
 		{
 			desc: "custom request errors",
 			hostMapper: func(host string) (*HostConfig, error) {
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorReporter is called whenever the proxy recovers from a panic or maps an upstream error.
+// The errors carry a stack trace (see github.com/pkg/errors), which is understood by most error
+// tracking clients, e.g. Sentry. The HostConfig is nil if the error occurred before the host was mapped.
+type ErrorReporter func(ctx context.Context, config *HostConfig, err error)
+
+// WithErrorReporter sets the ErrorReporter.
+func WithErrorReporter(r ErrorReporter) Options {
+	return func(o *options) {
+		o.errorReporter = r
+	}
+}
+
+func (o *options) reportError(r *http.Request, err error) {
+	if o.errorReporter == nil {
+		return
+	}
+	c, _ := r.Context().Value(hostConfigKey).(*HostConfig)
+	o.errorReporter(r.Context(), c, err)
+}
+
+// recoverPanic reports the recovered value of a panic of the proxy handler and responds with an internal server
+// error. recover must be called by the deferred function itself:
+//
+//	defer func() { o.recoverPanic(recover(), writer, request) }()
+func (o *options) recoverPanic(v interface{}, w http.ResponseWriter, r *http.Request) {
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		// used by the reverse proxy and the http server to abort a response, not an actual error
+		panic(v)
+	}
+
+	err, ok := v.(error)
+	if !ok {
+		err = fmt.Errorf("%v", v)
+	}
+	o.reportError(r, errors.WithStack(errors.WithMessage(err, "proxy recovered from panic")))
+	o.writeResponse(w, r, http.StatusInternalServerError, "", []byte(http.StatusText(http.StatusInternalServerError)))
+}
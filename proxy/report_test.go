@@ -0,0 +1,63 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestErrorReporter(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	upstreamHost := urlx.ParseOrPanic(upstream.URL).Host
+	upstream.Close()
+
+	type report struct {
+		config *HostConfig
+		err    error
+	}
+	reports := make(chan report, 1)
+	reporter := WithErrorReporter(func(_ context.Context, config *HostConfig, err error) {
+		reports <- report{config, err}
+	})
+	hostMapper := func(context.Context, *http.Request) (*HostConfig, error) {
+		return &HostConfig{UpstreamHost: upstreamHost, UpstreamScheme: "http"}, nil
+	}
+
+	t.Run("case=reports recovered panics", func(t *testing.T) {
+		proxy := httptest.NewServer(New(hostMapper, reporter, WithSlowClientProtection(1, time.Minute, time.Minute), WithReqMiddleware(func(*http.Request, *HostConfig, []byte) ([]byte, error) {
+			panic("something went terribly wrong")
+		})))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		r := <-reports
+		require.NotNil(t, r.config, "the host config is reported with slow client protection")
+		assert.Contains(t, r.err.Error(), "something went terribly wrong")
+		assert.Contains(t, fmt.Sprintf("%+v", r.err), "report_test.go")
+	})
+
+	t.Run("case=reports upstream errors", func(t *testing.T) {
+		proxy := httptest.NewServer(New(hostMapper, reporter))
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+		r := <-reports
+		require.NotNil(t, r.config)
+		assert.Equal(t, upstreamHost, r.config.UpstreamHost)
+		assert.Error(t, r.err)
+	})
+}
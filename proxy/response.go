@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// writeResponse writes a response generated by the proxy itself, as opposed to one received from the upstream.
+// The body is compressed according to the Accept-Encoding header of the request.
+func (o *options) writeResponse(w http.ResponseWriter, r *http.Request, code int, contentType string, body []byte) {
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	addVary(w.Header(), "Accept-Encoding")
+	if w.Header().Get("Content-Encoding") == "" {
+		if compressed, encoding, err := compressResponse(r, body); err != nil {
+			o.reportError(r, err)
+		} else if encoding != "" {
+			w.Header().Set("Content-Encoding", encoding)
+			body = compressed
+		}
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	_, _ = w.Write(body)
+}
+
+// errorHandler is used as httputil.ReverseProxy.ErrorHandler.
+func (o *options) errorHandler(w http.ResponseWriter, r *http.Request, err error) {
+	if c, ok := r.Context().Value(hostConfigKey).(*HostConfig); ok && c.ResponseBudget != nil &&
+		(errors.Is(err, context.DeadlineExceeded) || errors.Is(r.Context().Err(), context.DeadlineExceeded)) {
+		b := c.ResponseBudget
+		if b.UseLastResponse {
+			if cached, ok := o.lastResponses.get(r, c); ok {
+				o.writeResponse(w, r, http.StatusOK, cached.contentType, cached.body)
+				return
+			}
+		}
+
+		code, contentType := b.FallbackStatusCode, b.FallbackContentType
+		if code == 0 {
+			code = http.StatusOK
+		}
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		o.writeResponse(w, r, code, contentType, b.FallbackBody)
+		return
+	}
+
+	o.reportError(r, errors.WithStack(err))
+
+	if o.logger != nil {
+		o.logError(r, "http: proxy error", err)
+	} else {
+		// same as the default of httputil.ReverseProxy
+		log.Printf("http: proxy error: %v", err)
+	}
+	w.WriteHeader(http.StatusBadGateway)
+}
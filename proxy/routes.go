@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Route forwards requests matching a path prefix to a different upstream than the one of its HostConfig.
+// Fields of the upstream which are left empty are taken from the HostConfig.
+type Route struct {
+	// PathPrefix is matched against the request path, after the HostConfig's PathPrefix was removed.
+	// A prefix of "/api" matches "/api" and "/api/users", but not "/apidocs".
+	PathPrefix string `json:"path_prefix,omitempty"`
+	// StripPathPrefix removes the route's PathPrefix before forwarding, just like HostConfig.PathPrefix.
+	StripPathPrefix bool `json:"strip_path_prefix,omitempty"`
+
+	UpstreamHost   string `json:"upstream_host,omitempty"`
+	UpstreamScheme string `json:"upstream_scheme,omitempty"`
+	TargetHost     string `json:"target_host,omitempty"`
+	TargetScheme   string `json:"target_scheme,omitempty"`
+}
+
+func (r *Route) matches(path string) bool {
+	if strings.HasSuffix(r.PathPrefix, "/") {
+		return strings.HasPrefix(path, r.PathPrefix)
+	}
+	return path == r.PathPrefix || strings.HasPrefix(path, r.PathPrefix+"/")
+}
+
+// routed returns a copy of the HostConfig with the route matching the request path applied,
+// or the HostConfig itself if no route matches. If several routes match, the longest PathPrefix wins.
+func (c *HostConfig) routed(path string) *HostConfig {
+	path = strings.TrimPrefix(path, c.PathPrefix)
+
+	var match *Route
+	for i := range c.Routes {
+		r := &c.Routes[i]
+		if r.matches(path) && (match == nil || len(r.PathPrefix) > len(match.PathPrefix)) {
+			match = r
+		}
+	}
+	if match == nil {
+		return c
+	}
+
+	cc := *c
+	if match.UpstreamHost != "" {
+		cc.UpstreamHost = match.UpstreamHost
+	}
+	if match.UpstreamScheme != "" {
+		cc.UpstreamScheme = match.UpstreamScheme
+	}
+	if match.TargetHost != "" {
+		cc.TargetHost = match.TargetHost
+	}
+	if match.TargetScheme != "" {
+		cc.TargetScheme = match.TargetScheme
+	}
+	if match.StripPathPrefix {
+		cc.PathPrefix = c.PathPrefix + strings.TrimSuffix(match.PathPrefix, "/")
+	}
+	return &cc
+}
+
+// forMethod returns a copy of the HostConfig using the read upstream if the method is safe,
+// or the HostConfig itself otherwise.
+func (c *HostConfig) forMethod(method string) *HostConfig {
+	if c.ReadUpstreamHost == "" {
+		return c
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		cc := *c
+		cc.UpstreamHost = c.ReadUpstreamHost
+		if c.ReadUpstreamScheme != "" {
+			cc.UpstreamScheme = c.ReadUpstreamScheme
+		}
+		return &cc
+	}
+	return c
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestRoutes(t *testing.T) {
+	newUpstream := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", name, r.URL.Path)
+		}))
+	}
+	app, auth, api := newUpstream("app"), newUpstream("auth"), newUpstream("api")
+	defer app.Close()
+	defer auth.Close()
+	defer api.Close()
+
+	proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+		return &HostConfig{
+			UpstreamHost:   urlx.ParseOrPanic(app.URL).Host,
+			UpstreamScheme: "http",
+			PathPrefix:     "/prefix",
+			Routes: []Route{
+				{PathPrefix: "/auth", UpstreamHost: urlx.ParseOrPanic(auth.URL).Host},
+				{PathPrefix: "/api/", UpstreamHost: urlx.ParseOrPanic(app.URL).Host},
+				{PathPrefix: "/api/v2", UpstreamHost: urlx.ParseOrPanic(api.URL).Host, StripPathPrefix: true},
+			},
+		}, nil
+	}))
+	defer proxy.Close()
+
+	for path, expected := range map[string]string{
+		"/prefix/":              "app /",
+		"/prefix/authors":       "app /authors",
+		"/prefix/auth":          "auth /auth",
+		"/prefix/auth/login":    "auth /auth/login",
+		"/prefix/api/v1/users":  "app /api/v1/users",
+		"/prefix/api/v2/users":  "api /users",
+		"/prefix/api/v2":        "api /",
+		"/prefix/api/v2-legacy": "app /api/v2-legacy",
+	} {
+		t.Run("path="+path, func(t *testing.T) {
+			resp, err := http.Get(proxy.URL + path)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, expected, string(body))
+		})
+	}
+}
+
+func TestReadUpstream(t *testing.T) {
+	c := &HostConfig{
+		UpstreamHost:       "primary.internal",
+		UpstreamScheme:     "https",
+		ReadUpstreamHost:   "replica.internal",
+		ReadUpstreamScheme: "http",
+		Routes:             []Route{{PathPrefix: "/admin", UpstreamHost: "admin.internal"}},
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodOptions} {
+		rc := c.forMethod(method)
+		assert.Equal(t, "replica.internal", rc.UpstreamHost, method)
+		assert.Equal(t, "http", rc.UpstreamScheme, method)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		assert.Equal(t, c, c.forMethod(method), method)
+	}
+
+	assert.Equal(t, "admin.internal", c.forMethod(http.MethodGet).routed("/admin/users").UpstreamHost)
+	assert.Equal(t, "primary.internal", c.UpstreamHost, "the original config must not be modified")
+}
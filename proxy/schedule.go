@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Schedule overrides the upstream of a HostConfig during a time window, e.g. to route to a
+// "scheduled maintenance" upstream. The window is either absolute (Start and End) or recurring
+// (Weekdays, From and To). Fields of the upstream which are left empty are not overridden.
+type Schedule struct {
+	// Start and End define an absolute time window.
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+	// Weekdays restricts the recurring window to these days. If empty, the window recurs daily.
+	Weekdays []time.Weekday `json:"weekdays,omitempty"`
+	// From and To define the recurring window as "15:04" in Location. The window may span midnight,
+	// in which case Weekdays refers to the day the window starts.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Location is the time zone of From and To.
+	// Default: UTC
+	Location *time.Location `json:"location,omitempty"`
+
+	UpstreamHost   string `json:"upstream_host,omitempty"`
+	UpstreamScheme string `json:"upstream_scheme,omitempty"`
+	TargetHost     string `json:"target_host,omitempty"`
+	TargetScheme   string `json:"target_scheme,omitempty"`
+}
+
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ActiveAt returns true if the schedule's window contains t.
+func (s *Schedule) ActiveAt(t time.Time) bool {
+	if !s.Start.IsZero() || !s.End.IsZero() {
+		if !s.Start.IsZero() && t.Before(s.Start) {
+			return false
+		}
+		if !s.End.IsZero() && !t.Before(s.End) {
+			return false
+		}
+		if s.From == "" && s.To == "" {
+			return true
+		}
+	}
+	if s.From == "" || s.To == "" {
+		return false
+	}
+
+	from, err := parseClock(s.From)
+	if err != nil {
+		return false
+	}
+	to, err := parseClock(s.To)
+	if err != nil {
+		return false
+	}
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	clock := t.Sub(midnight)
+
+	if from <= to {
+		return clock >= from && clock < to && s.onWeekday(t.Weekday())
+	}
+	// the window spans midnight
+	if clock >= from {
+		return s.onWeekday(t.Weekday())
+	}
+	return clock < to && s.onWeekday(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+func (s *Schedule) onWeekday(d time.Weekday) bool {
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, w := range s.Weekdays {
+		if w == d {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduled returns a copy of the HostConfig with the first schedule active at t applied,
+// or the HostConfig itself if no schedule is active.
+func (c *HostConfig) scheduled(t time.Time) *HostConfig {
+	for i := range c.Schedules {
+		s := &c.Schedules[i]
+		if !s.ActiveAt(t) {
+			continue
+		}
+
+		cc := *c
+		if s.UpstreamHost != "" {
+			cc.UpstreamHost = s.UpstreamHost
+			cc.UpstreamHosts = nil
+		}
+		if s.UpstreamScheme != "" {
+			cc.UpstreamScheme = s.UpstreamScheme
+		}
+		if s.TargetHost != "" {
+			cc.TargetHost = s.TargetHost
+		}
+		if s.TargetScheme != "" {
+			cc.TargetScheme = s.TargetScheme
+		}
+		return &cc
+	}
+	return c
+}
@@ -0,0 +1,81 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	// 2022-05-01 is a Sunday
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			panic(err)
+		}
+		return ts
+	}
+
+	for k, tc := range []struct {
+		name     string
+		schedule Schedule
+		active   []string
+		inactive []string
+	}{
+		{
+			name: "absolute window",
+			schedule: Schedule{
+				Start: at("2022-05-01T02:00:00Z"),
+				End:   at("2022-05-01T04:00:00Z"),
+			},
+			active:   []string{"2022-05-01T02:00:00Z", "2022-05-01T03:59:59Z"},
+			inactive: []string{"2022-05-01T01:59:59Z", "2022-05-01T04:00:00Z"},
+		},
+		{
+			name:     "daily window",
+			schedule: Schedule{From: "02:00", To: "04:00"},
+			active:   []string{"2022-05-01T02:00:00Z", "2022-05-03T03:30:00Z"},
+			inactive: []string{"2022-05-01T04:00:00Z", "2022-05-03T12:00:00Z"},
+		},
+		{
+			name:     "weekly window spanning midnight",
+			schedule: Schedule{Weekdays: []time.Weekday{time.Sunday}, From: "23:00", To: "01:00"},
+			active:   []string{"2022-05-01T23:30:00Z", "2022-05-02T00:30:00Z"},
+			inactive: []string{"2022-05-02T23:30:00Z", "2022-05-01T00:30:00Z"},
+		},
+		{
+			name:     "window in time zone",
+			schedule: Schedule{From: "02:00", To: "04:00", Location: time.FixedZone("UTC+2", 2*60*60)},
+			active:   []string{"2022-05-01T00:30:00Z"},
+			inactive: []string{"2022-05-01T02:30:00Z"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, ts := range tc.active {
+				assert.Truef(t, tc.schedule.ActiveAt(at(ts)), "case %d: expected %s to be active", k, ts)
+			}
+			for _, ts := range tc.inactive {
+				assert.Falsef(t, tc.schedule.ActiveAt(at(ts)), "case %d: expected %s to be inactive", k, ts)
+			}
+		})
+	}
+
+	t.Run("case=applies first active schedule", func(t *testing.T) {
+		c := &HostConfig{
+			UpstreamHost: "app.internal",
+			TargetHost:   "app.internal",
+			Schedules: []Schedule{
+				{Start: at("2022-05-01T00:00:00Z"), End: at("2022-05-02T00:00:00Z"), UpstreamHost: "maintenance.internal"},
+				{Start: at("2022-05-01T00:00:00Z"), UpstreamHost: "other.internal"},
+			},
+		}
+
+		scheduled := c.scheduled(at("2022-05-01T12:00:00Z"))
+		assert.Equal(t, "maintenance.internal", scheduled.UpstreamHost)
+		assert.Equal(t, "app.internal", scheduled.TargetHost)
+		assert.Equal(t, "app.internal", c.UpstreamHost, "the original config must not be modified")
+
+		assert.Equal(t, c, c.scheduled(at("2022-04-30T12:00:00Z")))
+	})
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+
+	"github.com/ory/graceful"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+type (
+	// HTTP3Server is implemented by HTTP/3 servers such as github.com/lucas-clemente/quic-go/http3.Server.
+	HTTP3Server interface {
+		ListenAndServe() error
+		Close() error
+		// SetQuicHeaders sets the Alt-Svc header to advertise HTTP/3 support.
+		SetQuicHeaders(http.Header) error
+	}
+	// ServeOptions configures Serve.
+	ServeOptions struct {
+		// Addr is the TCP (and UDP for HTTP/3) address to listen on.
+		Addr string
+		// TLSConfig enables TLS with HTTP/1.1 and HTTP/2. See NewTLSConfig for per-host certificates.
+		// If left empty, the proxy is served over plain HTTP/1.1.
+		TLSConfig *tls.Config
+		// H2C enables HTTP/2 without TLS (prior knowledge and upgrade). Ignored when TLSConfig is set.
+		H2C bool
+		// NewHTTP3Server, if set, is used to additionally serve HTTP/3 on the same address. Requires TLSConfig.
+		NewHTTP3Server func(addr string, handler http.Handler, tlsConfig *tls.Config) HTTP3Server
+		// Server allows to customize the http.Server. Unset timeouts are set to sane defaults.
+		Server *http.Server
+	}
+)
+
+// Serve serves the handler (usually created by New) according to the options until the
+// context is canceled, after which the servers are shut down gracefully.
+func Serve(ctx context.Context, handler http.Handler, opts ServeOptions) error {
+	server := opts.Server
+	if server == nil {
+		server = new(http.Server)
+	}
+	server = graceful.WithDefaults(server)
+	server.Addr = opts.Addr
+
+	var h3 HTTP3Server
+	if opts.NewHTTP3Server != nil {
+		if opts.TLSConfig == nil {
+			return errors.New("serving HTTP/3 requires a TLS config")
+		}
+		h3 = opts.NewHTTP3Server(opts.Addr, handler, opts.TLSConfig)
+		next := handler
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = h3.SetQuicHeaders(w.Header())
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	if opts.TLSConfig != nil {
+		server.TLSConfig = opts.TLSConfig
+		server.Handler = handler
+		if err := http2.ConfigureServer(server, nil); err != nil {
+			return errors.WithStack(err)
+		}
+	} else if opts.H2C {
+		server.Handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: server.IdleTimeout})
+	} else {
+		server.Handler = handler
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		var err error
+		if opts.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		errs <- err
+	}()
+	if h3 != nil {
+		go func() {
+			errs <- h3.ListenAndServe()
+		}()
+	}
+
+	shutdown := func() error {
+		sctx, cancel := context.WithTimeout(context.Background(), graceful.DefaultShutdownTimeout)
+		defer cancel()
+		if h3 != nil {
+			_ = h3.Close()
+		}
+		return errors.WithStack(server.Shutdown(sctx))
+	}
+
+	select {
+	case <-ctx.Done():
+		return shutdown()
+	case err := <-errs:
+		_ = shutdown()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return errors.WithStack(err)
+	}
+}
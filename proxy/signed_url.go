@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// SignedURLExpiresParam is the query parameter holding the expiry of a signed URL as a unix timestamp.
+	SignedURLExpiresParam = "expires"
+	// SignedURLSignatureParam is the query parameter holding the signature of a signed URL.
+	SignedURLSignatureParam = "signature"
+)
+
+var (
+	// ErrInvalidSignature is returned when a signed URL has a missing or invalid signature.
+	ErrInvalidSignature = errors.New("the url signature is invalid")
+	// ErrSignedURLExpired is returned when a signed URL has expired.
+	ErrSignedURLExpired = errors.New("the signed url has expired")
+)
+
+// SignedURLPolicy requires requests to carry a valid signature created by SignURL.
+type SignedURLPolicy struct {
+	// Secrets are used to verify signatures. Signatures created with any of the secrets are accepted,
+	// which allows to rotate secrets.
+	Secrets [][]byte `json:"secrets,omitempty"`
+	// PathPrefixes restricts the policy to requests whose path starts with one of the prefixes.
+	// If left empty, all requests must be signed.
+	PathPrefixes []string `json:"path_prefixes,omitempty"`
+}
+
+func signURLPath(secret []byte, path string, expires string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = mac.Write([]byte(path))
+	_, _ = mac.Write([]byte{'\n'})
+	_, _ = mac.Write([]byte(expires))
+	return mac.Sum(nil)
+}
+
+// SignURL returns a copy of the URL which is valid until expiresAt. The signature covers the path and the expiry.
+func SignURL(u *url.URL, secret []byte, expiresAt time.Time) *url.URL {
+	signed := *u
+	q := signed.Query()
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	q.Set(SignedURLExpiresParam, expires)
+	q.Set(SignedURLSignatureParam, base64.RawURLEncoding.EncodeToString(signURLPath(secret, u.EscapedPath(), expires)))
+	signed.RawQuery = q.Encode()
+	return &signed
+}
+
+// VerifySignedURL checks that the URL was signed by SignURL with one of the secrets and has not expired.
+func VerifySignedURL(u *url.URL, now time.Time, secrets ...[]byte) error {
+	q := u.Query()
+	expires, signature := q.Get(SignedURLExpiresParam), q.Get(SignedURLSignatureParam)
+	if expires == "" || signature == "" {
+		return errors.WithStack(ErrInvalidSignature)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.WithStack(ErrInvalidSignature)
+	}
+
+	valid := false
+	for _, secret := range secrets {
+		if hmac.Equal(mac, signURLPath(secret, u.EscapedPath(), expires)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.WithStack(ErrInvalidSignature)
+	}
+
+	ts, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return errors.WithStack(ErrInvalidSignature)
+	}
+	if !now.Before(time.Unix(ts, 0)) {
+		return errors.WithStack(ErrSignedURLExpired)
+	}
+	return nil
+}
+
+// verify checks the signature of the request if the policy applies, and removes the signature
+// parameters before the request is forwarded.
+func (p *SignedURLPolicy) verify(r *http.Request) error {
+	if p == nil {
+		return nil
+	}
+
+	applies := len(p.PathPrefixes) == 0
+	for _, prefix := range p.PathPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			applies = true
+			break
+		}
+	}
+	if !applies {
+		return nil
+	}
+
+	if err := VerifySignedURL(r.URL, time.Now(), p.Secrets...); err != nil {
+		return err
+	}
+
+	q := r.URL.Query()
+	q.Del(SignedURLExpiresParam)
+	q.Del(SignedURLSignatureParam)
+	r.URL.RawQuery = q.Encode()
+	return nil
+}
@@ -0,0 +1,69 @@
+package proxy
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestSignedURL(t *testing.T) {
+	secret, other := []byte("secret"), []byte("other secret")
+	u := urlx.ParseOrPanic("https://example.com/downloads/file.zip?version=2")
+	now := time.Now()
+
+	signed := SignURL(u, secret, now.Add(time.Hour))
+	assert.Equal(t, "2", signed.Query().Get("version"))
+	assert.NotEqual(t, u.String(), signed.String())
+
+	t.Run("case=valid", func(t *testing.T) {
+		assert.NoError(t, VerifySignedURL(signed, now, secret))
+		assert.NoError(t, VerifySignedURL(signed, now, other, secret), "rotated secrets are accepted")
+	})
+
+	t.Run("case=wrong secret", func(t *testing.T) {
+		assert.ErrorIs(t, VerifySignedURL(signed, now, other), ErrInvalidSignature)
+	})
+
+	t.Run("case=expired", func(t *testing.T) {
+		assert.ErrorIs(t, VerifySignedURL(signed, now.Add(2*time.Hour), secret), ErrSignedURLExpired)
+	})
+
+	t.Run("case=tampered path", func(t *testing.T) {
+		tampered := *signed
+		tampered.Path = "/downloads/other.zip"
+		assert.ErrorIs(t, VerifySignedURL(&tampered, now, secret), ErrInvalidSignature)
+	})
+
+	t.Run("case=tampered expiry", func(t *testing.T) {
+		q := signed.Query()
+		q.Set(SignedURLExpiresParam, "99999999999")
+		tampered := *signed
+		tampered.RawQuery = q.Encode()
+		assert.ErrorIs(t, VerifySignedURL(&tampered, now, secret), ErrInvalidSignature)
+	})
+
+	t.Run("case=unsigned", func(t *testing.T) {
+		assert.ErrorIs(t, VerifySignedURL(u, now, secret), ErrInvalidSignature)
+	})
+
+	t.Run("case=policy strips signature", func(t *testing.T) {
+		r := newRequest(t, signed.String())
+		require.NoError(t, (&SignedURLPolicy{Secrets: [][]byte{secret}}).verify(r))
+		assert.Equal(t, "version=2", r.URL.RawQuery)
+
+		r = newRequest(t, "https://example.com/public/index.html")
+		assert.NoError(t, (&SignedURLPolicy{Secrets: [][]byte{secret}, PathPrefixes: []string{"/downloads/"}}).verify(r))
+		assert.Error(t, (&SignedURLPolicy{Secrets: [][]byte{secret}}).verify(r))
+	})
+}
+
+func newRequest(t *testing.T, u string) *http.Request {
+	r, err := http.NewRequest(http.MethodGet, u, nil)
+	require.NoError(t, err)
+	return r
+}
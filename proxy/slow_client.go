@@ -0,0 +1,131 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrSlowClient is returned when writing the response is aborted because the client reads it too slowly.
+var ErrSlowClient = errors.New("the client is reading the response too slowly")
+
+type (
+	slowClientPolicy struct {
+		minBytesPerSecond   int64
+		gracePeriod         time.Duration
+		maxResponseDuration time.Duration
+	}
+	slowClientWriter struct {
+		http.ResponseWriter
+		policy  *slowClientPolicy
+		cancel  context.CancelFunc
+		start   time.Time // set on the first write
+		written int64
+	}
+)
+
+// WithSlowClientProtection aborts responses to clients reading slower than minBytesPerSecond
+// once the grace period has passed, or taking longer than maxResponseDuration in total. Aborting
+// cancels the request context, which frees the upstream connection. Zero values disable the
+// respective check. Websocket connections are not affected.
+func WithSlowClientProtection(minBytesPerSecond int64, gracePeriod, maxResponseDuration time.Duration) Options {
+	return func(o *options) {
+		o.slowClient = &slowClientPolicy{
+			minBytesPerSecond:   minBytesPerSecond,
+			gracePeriod:         gracePeriod,
+			maxResponseDuration: maxResponseDuration,
+		}
+	}
+}
+
+// wrap returns the writer and request to use for proxying. The request context is canceled as soon
+// as the client is considered too slow.
+func (p *slowClientPolicy) wrap(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, *http.Request, context.CancelFunc) {
+	if p == nil || isUpgradeRequest(r) {
+		return w, r, func() {}
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	return &slowClientWriter{
+		ResponseWriter: w,
+		policy:         p,
+		cancel:         cancel,
+	}, r.WithContext(ctx), cancel
+}
+
+// check aborts the response if the client is too slow. The throughput is only checked after writing,
+// as the time spent waiting for the next chunk from the upstream must not count against the client.
+func (w *slowClientWriter) check(afterWrite bool) error {
+	elapsed := time.Since(w.start)
+	if w.policy.maxResponseDuration > 0 && elapsed > w.policy.maxResponseDuration {
+		w.cancel()
+		return errors.WithStack(ErrSlowClient)
+	}
+	if afterWrite && w.policy.minBytesPerSecond > 0 && elapsed > w.policy.gracePeriod &&
+		float64(w.written)/elapsed.Seconds() < float64(w.policy.minBytesPerSecond) {
+		w.cancel()
+		return errors.WithStack(ErrSlowClient)
+	}
+	return nil
+}
+
+// writeBudget is the maximum time a single write of n bytes may block.
+func (w *slowClientWriter) writeBudget(n int) time.Duration {
+	var budget time.Duration
+	if w.policy.minBytesPerSecond > 0 {
+		budget = w.policy.gracePeriod + time.Duration(float64(n)/float64(w.policy.minBytesPerSecond)*float64(time.Second))
+	}
+	if w.policy.maxResponseDuration > 0 {
+		if remaining := w.policy.maxResponseDuration - time.Since(w.start); budget == 0 || remaining < budget {
+			budget = remaining
+		}
+	}
+	return budget
+}
+
+func (w *slowClientWriter) Write(p []byte) (int, error) {
+	if w.start.IsZero() {
+		// the clock starts with the response, not with the request
+		w.start = time.Now()
+	}
+	if err := w.check(false); err != nil {
+		return 0, err
+	}
+
+	if budget := w.writeBudget(len(p)); budget > 0 {
+		// the write itself might block on a client which does not read at all
+		timer := time.AfterFunc(budget, w.cancel)
+		defer timer.Stop()
+	}
+
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, w.check(true)
+}
+
+func (w *slowClientWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap is used by http.ResponseController.
+func (w *slowClientWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func isUpgradeRequest(r *http.Request) bool {
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
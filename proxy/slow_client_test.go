@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowClientWriter(t *testing.T) {
+	newWriter := func(p *slowClientPolicy, start time.Time) (*slowClientWriter, *http.Request) {
+		w, r, _ := p.wrap(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		sw := w.(*slowClientWriter)
+		sw.start = start
+		return sw, r
+	}
+
+	t.Run("case=allows fast clients", func(t *testing.T) {
+		w, r := newWriter(&slowClientPolicy{minBytesPerSecond: 1, maxResponseDuration: time.Minute}, time.Now())
+		_, err := w.Write([]byte("hello"))
+		require.NoError(t, err)
+		assert.NoError(t, r.Context().Err())
+	})
+
+	t.Run("case=aborts below minimum throughput", func(t *testing.T) {
+		w, r := newWriter(&slowClientPolicy{minBytesPerSecond: 1 << 20, gracePeriod: time.Second}, time.Now().Add(-time.Minute))
+		_, err := w.Write([]byte("hello"))
+		assert.ErrorIs(t, err, ErrSlowClient)
+		assert.Error(t, r.Context().Err())
+	})
+
+	t.Run("case=aborts after maximum duration", func(t *testing.T) {
+		w, r := newWriter(&slowClientPolicy{maxResponseDuration: time.Second}, time.Now().Add(-time.Minute))
+		_, err := w.Write([]byte("hello"))
+		assert.ErrorIs(t, err, ErrSlowClient)
+		assert.Error(t, r.Context().Err())
+	})
+
+	t.Run("case=does not wrap websocket upgrades", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Connection", "keep-alive, Upgrade")
+		rec := httptest.NewRecorder()
+		w, _, _ := (&slowClientPolicy{maxResponseDuration: time.Second}).wrap(rec, r)
+		assert.Equal(t, rec, w)
+	})
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// SVIDSource provides the X.509 SVID of this workload and the trust bundles to verify upstreams with.
+	// It is usually backed by the SPIFFE Workload API, e.g. using an adapter around
+	// github.com/spiffe/go-spiffe/v2/workloadapi.X509Source. Both methods are called on every TLS handshake,
+	// so that rotated certificates and bundles are picked up automatically.
+	SVIDSource interface {
+		// GetX509SVID returns the current certificate and private key of this workload.
+		GetX509SVID() (*tls.Certificate, error)
+		// GetX509BundleForTrustDomain returns the current root certificates of the trust domain, e.g. "example.org".
+		GetX509BundleForTrustDomain(trustDomain string) ([]*x509.Certificate, error)
+	}
+	// upstreamTransport selects the round tripper based on the HostConfig of the request.
+	upstreamTransport struct {
+		o      *options
+		spiffe sync.Map
+		certs  *CertificateStore
+
+		mu   sync.Mutex
+		mtls map[mtlsKey]*mtlsTransport
+	}
+)
+
+// WithSVIDSource enables mTLS using SPIFFE X.509 SVIDs for upstreams whose HostConfig has an UpstreamTrustDomain.
+func WithSVIDSource(s SVIDSource) Options {
+	return func(o *options) {
+		o.svidSource = s
+	}
+}
+
+func (t *upstreamTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c, ok := r.Context().Value(hostConfigKey).(*HostConfig)
+	switch {
+	case !ok:
+		return t.o.transport.RoundTrip(r)
+	case c.UpstreamTrustDomain != "":
+		if t.o.svidSource == nil {
+			return nil, errors.Errorf("upstream %s requires a SPIFFE trust domain, but no SVID source is configured", c.UpstreamHost)
+		}
+		return t.spiffeTransport(c.UpstreamTrustDomain).RoundTrip(r)
+	case c.UpstreamClientCertificatePath != "" || c.UpstreamCAPath != "":
+		rt, err := t.mtlsTransport(c)
+		if err != nil {
+			return nil, err
+		}
+		return rt.RoundTrip(r)
+	}
+	return t.o.transport.RoundTrip(r)
+}
+
+// baseTransport returns a copy of the configured transport, or of the default transport if it can not be copied.
+func (t *upstreamTransport) baseTransport() *http.Transport {
+	if ht, ok := t.o.transport.(*http.Transport); ok {
+		return ht.Clone()
+	}
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+func (t *upstreamTransport) spiffeTransport(trustDomain string) http.RoundTripper {
+	if cached, ok := t.spiffe.Load(trustDomain); ok {
+		return cached.(http.RoundTripper)
+	}
+
+	source := t.o.svidSource
+	ht := t.baseTransport()
+	ht.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return source.GetX509SVID()
+		},
+		// the server name is not part of a SPIFFE ID, the peer is verified below instead
+		InsecureSkipVerify: true, // #nosec G402
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifySPIFFEPeer(source, trustDomain, rawCerts)
+		},
+	}
+
+	actual, _ := t.spiffe.LoadOrStore(trustDomain, ht)
+	return actual.(http.RoundTripper)
+}
+
+func verifySPIFFEPeer(source SVIDSource, trustDomain string, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return errors.New("the upstream did not present a certificate")
+	}
+
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		certs[i] = cert
+	}
+
+	bundle, err := source.GetX509BundleForTrustDomain(trustDomain)
+	if err != nil {
+		return err
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, root := range bundle {
+		roots.AddCert(root)
+	}
+	for _, intermediate := range certs[1:] {
+		intermediates.AddCert(intermediate)
+	}
+
+	if _, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return errors.WithStack(err)
+	}
+
+	for _, u := range certs[0].URIs {
+		if u.Scheme == "spiffe" && u.Host == trustDomain {
+			return nil
+		}
+	}
+	return errors.Errorf("the upstream certificate does not contain a SPIFFE ID of trust domain %q", trustDomain)
+}
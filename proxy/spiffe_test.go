@@ -0,0 +1,114 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+type staticSVIDSource struct {
+	svid   *tls.Certificate
+	bundle []*x509.Certificate
+}
+
+func (s *staticSVIDSource) GetX509SVID() (*tls.Certificate, error) {
+	return s.svid, nil
+}
+
+func (s *staticSVIDSource) GetX509BundleForTrustDomain(string) ([]*x509.Certificate, error) {
+	return s.bundle, nil
+}
+
+func newSPIFFECertificate(t *testing.T, id string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, *tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: id},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	} else {
+		template.URIs = []*url.URL{urlx.ParseOrPanic(id)}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key, &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+func TestSPIFFEUpstream(t *testing.T) {
+	ca, caKey, _ := newSPIFFECertificate(t, "example.org CA", nil, nil)
+	_, _, upstreamCert := newSPIFFECertificate(t, "spiffe://example.org/upstream", ca, caKey)
+	_, _, proxyCert := newSPIFFECertificate(t, "spiffe://example.org/proxy", ca, caKey)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].URIs[0].String()))
+	}))
+	upstream.TLS = &tls.Config{
+		Certificates: []tls.Certificate{*upstreamCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+
+	newProxy := func(trustDomain string) *httptest.Server {
+		return httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{
+				UpstreamHost:        urlx.ParseOrPanic(upstream.URL).Host,
+				UpstreamScheme:      "https",
+				UpstreamTrustDomain: trustDomain,
+			}, nil
+		}, WithSVIDSource(&staticSVIDSource{svid: proxyCert, bundle: []*x509.Certificate{ca}})))
+	}
+
+	t.Run("case=authenticates with SVID", func(t *testing.T) {
+		proxy := newProxy("example.org")
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "spiffe://example.org/proxy", string(body))
+	})
+
+	t.Run("case=rejects upstream of other trust domain", func(t *testing.T) {
+		proxy := newProxy("other.org")
+		defer proxy.Close()
+
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	})
+}
@@ -0,0 +1,178 @@
+package proxy
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/sqlcon"
+)
+
+// DefaultSQLHostTable is the table used by SQLHostMapper if no table is set.
+const DefaultSQLHostTable = "proxy_hosts"
+
+var sqlIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SQLHostMapper loads HostConfigs from a SQL table and keeps them in memory. The table has the columns
+// "host" (the request host, optionally including the port), "config" (the HostConfig as accepted by
+// ParseHostConfig) and "updated_at". Use Migrate to create it.
+//
+// Changes are picked up by Run, which polls the table and reloads it if the number of rows or the
+// latest "updated_at" changed. Rows must therefore be touched when they are updated.
+type SQLHostMapper struct {
+	// DB is the connection to the database, e.g. as opened by pop or sql.Open.
+	DB *sql.DB
+	// Table is the name of the table.
+	// Default: DefaultSQLHostTable
+	Table string
+	// PollInterval is the time between two checks for changes.
+	// Default: 10s
+	PollInterval time.Duration
+
+	mu          sync.RWMutex
+	hosts       map[string]*HostConfig
+	fingerprint string
+}
+
+func (m *SQLHostMapper) table() (string, error) {
+	table := m.Table
+	if table == "" {
+		table = DefaultSQLHostTable
+	}
+	if !sqlIdentifier.MatchString(table) {
+		return "", errors.Errorf("invalid table name %q", table)
+	}
+	return table, nil
+}
+
+// Migrate creates the table unless it exists already.
+func (m *SQLHostMapper) Migrate(ctx context.Context) error {
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	_, err = m.DB.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	host VARCHAR(255) NOT NULL PRIMARY KEY,
+	config TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, table))
+	return sqlcon.HandleError(err)
+}
+
+func (m *SQLHostMapper) currentFingerprint(ctx context.Context, table string) (string, error) {
+	var count int64
+	var latest interface{}
+	if err := m.DB.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*), MAX(updated_at) FROM %s", table)).Scan(&count, &latest); err != nil {
+		return "", sqlcon.HandleError(err)
+	}
+	if b, ok := latest.([]byte); ok {
+		latest = string(b)
+	}
+	return fmt.Sprintf("%d/%v", count, latest), nil
+}
+
+// Load reads all HostConfigs from the table. If a row can not be parsed, an error is returned
+// and the previously loaded HostConfigs are kept.
+func (m *SQLHostMapper) Load(ctx context.Context) error {
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	fingerprint, err := m.currentFingerprint(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, fmt.Sprintf("SELECT host, config FROM %s", table))
+	if err != nil {
+		return sqlcon.HandleError(err)
+	}
+	defer rows.Close()
+
+	hosts := map[string]*HostConfig{}
+	for rows.Next() {
+		var host, raw string
+		if err := rows.Scan(&host, &raw); err != nil {
+			return sqlcon.HandleError(err)
+		}
+		c, err := ParseHostConfig([]byte(raw))
+		if err != nil {
+			return errors.WithMessagef(err, "host %s", host)
+		}
+		hosts[host] = c
+	}
+	if err := rows.Err(); err != nil {
+		return sqlcon.HandleError(err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hosts, m.fingerprint = hosts, fingerprint
+	return nil
+}
+
+// Refresh reloads the table if it changed since the last load.
+func (m *SQLHostMapper) Refresh(ctx context.Context) error {
+	table, err := m.table()
+	if err != nil {
+		return err
+	}
+	fingerprint, err := m.currentFingerprint(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	m.mu.RLock()
+	unchanged := m.hosts != nil && fingerprint == m.fingerprint
+	m.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+	return m.Load(ctx)
+}
+
+// Run refreshes the HostConfigs immediately and then every PollInterval until the context is canceled.
+// Errors are passed to onError, which may be nil.
+func (m *SQLHostMapper) Run(ctx context.Context, onError func(error)) {
+	interval := m.PollInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.Refresh(ctx); err != nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// HostMapper returns the HostMapper to pass to New. The host is looked up including the port first, then without it.
+// Unknown hosts result in ErrHostNotFound.
+func (m *SQLHostMapper) HostMapper() HostMapper {
+	return func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+
+		c, ok := m.hosts[r.Host]
+		if !ok {
+			c, ok = m.hosts[stripPort(r.Host)]
+		}
+		if !ok {
+			return nil, errors.Wrapf(ErrHostNotFound, "host %s", r.Host)
+		}
+		return c.CopyForRequest(), nil
+	}
+}
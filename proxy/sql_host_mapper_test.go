@@ -0,0 +1,93 @@
+//go:build sqlite
+// +build sqlite
+
+package proxy
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLHostMapper(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	m := &SQLHostMapper{DB: db}
+	require.NoError(t, m.Migrate(ctx))
+	require.NoError(t, m.Migrate(ctx), "migrating twice is fine")
+
+	_, err = db.Exec(`INSERT INTO proxy_hosts (host, config, updated_at) VALUES
+		('example.com', '{"upstream_host":"upstream.internal","upstream_scheme":"http"}', '2022-01-01 00:00:00'),
+		('other.com:8080', 'upstream_host: other.internal'||char(10)||'upstream_scheme: https', '2022-01-01 00:00:00')`)
+	require.NoError(t, err)
+	require.NoError(t, m.Load(ctx))
+
+	lookup := func(host string) (*HostConfig, error) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = host
+		return m.HostMapper()(ctx, r)
+	}
+
+	t.Run("case=finds host with and without port", func(t *testing.T) {
+		c, err := lookup("example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, "upstream.internal", c.UpstreamHost)
+
+		c, err = lookup("other.com:8080")
+		require.NoError(t, err)
+		assert.Equal(t, "other.internal", c.UpstreamHost)
+	})
+
+	t.Run("case=returns copies", func(t *testing.T) {
+		c, err := lookup("example.com")
+		require.NoError(t, err)
+		c.UpstreamHost = "changed"
+
+		c, err = lookup("example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "upstream.internal", c.UpstreamHost)
+	})
+
+	t.Run("case=unknown host", func(t *testing.T) {
+		_, err := lookup("unknown.com")
+		assert.ErrorIs(t, err, ErrHostNotFound)
+	})
+
+	t.Run("case=refreshes changes", func(t *testing.T) {
+		_, err := db.Exec(`UPDATE proxy_hosts SET config = '{"upstream_host":"new.internal","upstream_scheme":"http"}', updated_at = '2022-01-02 00:00:00' WHERE host = 'example.com'`)
+		require.NoError(t, err)
+		_, err = db.Exec(`DELETE FROM proxy_hosts WHERE host = 'other.com:8080'`)
+		require.NoError(t, err)
+		require.NoError(t, m.Refresh(ctx))
+
+		c, err := lookup("example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "new.internal", c.UpstreamHost)
+		_, err = lookup("other.com:8080")
+		assert.ErrorIs(t, err, ErrHostNotFound)
+	})
+
+	t.Run("case=keeps previous state on invalid rows", func(t *testing.T) {
+		_, err := db.Exec(`INSERT INTO proxy_hosts (host, config) VALUES ('invalid.com', '{"upstream_scheme":"ftp"}')`)
+		require.NoError(t, err)
+		assert.ErrorIs(t, m.Refresh(ctx), ErrInvalidHostConfig)
+
+		c, err := lookup("example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "new.internal", c.UpstreamHost)
+	})
+
+	t.Run("case=rejects invalid table names", func(t *testing.T) {
+		assert.Error(t, (&SQLHostMapper{DB: db, Table: "hosts; DROP TABLE users"}).Load(ctx))
+	})
+}
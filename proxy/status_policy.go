@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// StatusPolicy decides which upstream status codes are passed on to the client.
+type StatusPolicy struct {
+	// AllowedStatusCodes lists the upstream status codes which are passed on unchanged.
+	// If left empty, all status codes below 500 are allowed.
+	AllowedStatusCodes []int `json:"allowed_status_codes,omitempty"`
+	// StatusCode is the status code of the sanitized response.
+	// If left empty, the upstream status code is kept.
+	StatusCode int `json:"status_code,omitempty"`
+	// Body is the body of the sanitized response.
+	// If left empty, the status text of the status code is used.
+	Body []byte `json:"body,omitempty"`
+	// ContentType is the content type of Body.
+	// Default: text/plain; charset=utf-8
+	ContentType string `json:"content_type,omitempty"`
+}
+
+func (p *StatusPolicy) allows(code int) bool {
+	if len(p.AllowedStatusCodes) == 0 {
+		return code < 500
+	}
+	for _, allowed := range p.AllowedStatusCodes {
+		if allowed == code {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitize replaces the response body and status if the status code is not allowed.
+// It returns true if the response was replaced.
+func (p *StatusPolicy) sanitize(resp *http.Response) bool {
+	if p == nil || p.allows(resp.StatusCode) {
+		return false
+	}
+
+	if resp.Body != nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}
+
+	if p.StatusCode != 0 {
+		resp.StatusCode = p.StatusCode
+	}
+	resp.Status = strconv.Itoa(resp.StatusCode) + " " + http.StatusText(resp.StatusCode)
+
+	body := p.Body
+	if len(body) == 0 {
+		body = []byte(http.StatusText(resp.StatusCode))
+	}
+	contentType := p.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	resp.Header.Del("Content-Encoding")
+	if compressed, encoding, err := compressResponse(resp.Request, body); err == nil && encoding != "" {
+		resp.Header.Set("Content-Encoding", encoding)
+		body = compressed
+	}
+	addVary(resp.Header, "Accept-Encoding")
+	resp.Header.Set("Content-Type", contentType)
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	resp.ContentLength = int64(len(body))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return true
+}
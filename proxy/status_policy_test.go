@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusPolicy(t *testing.T) {
+	newResp := func(code int) *http.Response {
+		return &http.Response{
+			StatusCode: code,
+			Header:     http.Header{"Content-Type": {"text/html"}, "Content-Encoding": {"gzip"}},
+			Body:       io.NopCloser(strings.NewReader("panic: runtime error at /srv/app/main.go:42")),
+		}
+	}
+
+	t.Run("case=nil policy allows everything", func(t *testing.T) {
+		var p *StatusPolicy
+		assert.False(t, p.sanitize(newResp(http.StatusInternalServerError)))
+	})
+
+	t.Run("case=default allows non-server errors", func(t *testing.T) {
+		p := &StatusPolicy{}
+		assert.False(t, p.sanitize(newResp(http.StatusNotFound)))
+
+		resp := newResp(http.StatusInternalServerError)
+		require.True(t, p.sanitize(resp))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusText(http.StatusInternalServerError), string(body))
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("Content-Encoding"))
+		assert.Equal(t, "text/plain; charset=utf-8", resp.Header.Get("Content-Type"))
+	})
+
+	t.Run("case=custom allowlist and body", func(t *testing.T) {
+		p := &StatusPolicy{
+			AllowedStatusCodes: []int{http.StatusOK},
+			StatusCode:         http.StatusBadGateway,
+			Body:               []byte(`{"error":"upstream failed"}`),
+			ContentType:        "application/json",
+		}
+		assert.False(t, p.sanitize(newResp(http.StatusOK)))
+
+		resp := newResp(http.StatusTeapot)
+		require.True(t, p.sanitize(resp))
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"error":"upstream failed"}`, string(body))
+		assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+		assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+		assert.EqualValues(t, len(body), resp.ContentLength)
+	})
+}
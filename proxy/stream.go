@@ -0,0 +1,83 @@
+package proxy
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultStreamingContentTypes are the content types of responses which are always streamed, see
+// WithStreamingContentTypes.
+var DefaultStreamingContentTypes = []string{"text/event-stream"}
+
+// WithStreaming pipes request and response bodies directly to the upstream and the client instead of reading
+// them into memory, so large uploads and downloads do not exhaust the memory of the proxy. Bodies are only
+// buffered if they have to be, i.e. request bodies if request middlewares are set, and response bodies if
+// response middlewares are set or the host's ResponseBudget uses the last response.
+//
+// Streamed response bodies are passed on as received, so URLs of the TargetHost are not rewritten to the
+// original host in them.
+func WithStreaming() Options {
+	return func(o *options) {
+		o.streaming = true
+	}
+}
+
+// WithStreamingContentTypes sets the content types of responses which are streamed to the client even without
+// WithStreaming, e.g. Server-Sent Events, which the client processes as they arrive. Their headers are rewritten,
+// but their bodies are not passed to response middlewares. Passing no content types disables the detection.
+// Default: DefaultStreamingContentTypes
+func WithStreamingContentTypes(contentTypes ...string) Options {
+	return func(o *options) {
+		o.streamingContentTypes = contentTypes
+	}
+}
+
+// WithFlushInterval sets how often streamed response bodies are flushed to the client, see
+// httputil.ReverseProxy.FlushInterval. A negative interval flushes after every write; responses of streaming
+// content types and without a Content-Length are always flushed after every write.
+// Default: 0, flushing only after the body was copied
+func WithFlushInterval(d time.Duration) Options {
+	return func(o *options) {
+		o.flushInterval = d
+	}
+}
+
+// streamRequest returns true if the request body is piped to the upstream.
+func (o *options) streamRequest() bool {
+	return o.streaming && len(o.reqMiddlewares) == 0
+}
+
+// streamResponse returns true if the response body is piped to the client.
+func (o *options) streamResponse(r *http.Response, c *HostConfig) bool {
+	if o.isStreamingContentType(r.Header.Get("Content-Type")) {
+		return true
+	}
+	return o.streaming && len(o.respMiddlewares) == 0 && (c.ResponseBudget == nil || !c.ResponseBudget.UseLastResponse)
+}
+
+// isStreamingContentType returns true if the media type of the content type is one of the streaming content types.
+func (o *options) isStreamingContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range o.streamingContentTypes {
+		if strings.EqualFold(mediaType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamedResponse tracks upgraded connections of streamed responses, whose bodies are passed on as is.
+func (o *options) streamedResponse(r *http.Response, c *HostConfig) {
+	if conn, ok := r.Body.(io.ReadWriteCloser); ok {
+		r.Body = o.websocket.track(r, c, conn)
+	}
+}
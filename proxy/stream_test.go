@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestStreaming(t *testing.T) {
+	// roundTrip proxies a request whose body is sent in two parts to an upstream which responds in two parts.
+	// Streamed request bodies arrive at the upstream before they are complete, and streamed response bodies arrive
+	// at the client before they are complete.
+	roundTrip := func(t *testing.T, opts ...Options) (requestStreamed, responseStreamed bool, body string) {
+		received, release := make(chan struct{}), make(chan struct{})
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body := bufio.NewReader(r.Body)
+			first, _ := body.ReadString('\n')
+			close(received)
+			rest, _ := io.ReadAll(body)
+
+			_, _ = w.Write([]byte(first))
+			w.(http.Flusher).Flush()
+			select {
+			case <-release:
+			case <-time.After(time.Second):
+			}
+			_, _ = w.Write(rest)
+		}))
+		defer upstream.Close()
+		upstreamHost := urlx.ParseOrPanic(upstream.URL).Host
+
+		proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{
+				UpstreamHost:   upstreamHost,
+				UpstreamScheme: "http",
+				TargetHost:     upstreamHost,
+				TargetScheme:   "http",
+			}, nil
+		}, opts...))
+		defer proxy.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		pr, pw := io.Pipe()
+		req, err := http.NewRequestWithContext(ctx, "POST", proxy.URL, pr)
+		require.NoError(t, err)
+
+		firstLine := make(chan string, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			r := bufio.NewReader(resp.Body)
+			first, _ := r.ReadString('\n')
+			firstLine <- first
+			rest, _ := io.ReadAll(r)
+			body = first + string(rest)
+		}()
+
+		_, err = pw.Write([]byte("first\n"))
+		require.NoError(t, err)
+		select {
+		case <-received:
+			requestStreamed = true
+		case <-time.After(200 * time.Millisecond):
+		}
+		_, _ = pw.Write([]byte("second\n"))
+		_ = pw.Close()
+
+		select {
+		case first := <-firstLine:
+			assert.Equal(t, "first\n", first)
+			responseStreamed = true
+		case <-time.After(200 * time.Millisecond):
+		}
+		close(release)
+		<-done
+		return requestStreamed, responseStreamed, body
+	}
+
+	t.Run("case=streams the bodies", func(t *testing.T) {
+		requestStreamed, responseStreamed, body := roundTrip(t, WithStreaming())
+		assert.True(t, requestStreamed)
+		assert.True(t, responseStreamed)
+		assert.Equal(t, "first\nsecond\n", body)
+	})
+
+	t.Run("case=buffers the bodies without streaming", func(t *testing.T) {
+		requestStreamed, responseStreamed, body := roundTrip(t)
+		assert.False(t, requestStreamed)
+		assert.False(t, responseStreamed)
+		assert.Equal(t, "first\nsecond\n", body)
+	})
+
+	t.Run("case=buffers the bodies for middlewares", func(t *testing.T) {
+		var requested, responded []byte
+		requestStreamed, responseStreamed, _ := roundTrip(t, WithStreaming(),
+			WithReqMiddleware(func(_ *http.Request, _ *HostConfig, body []byte) ([]byte, error) {
+				requested = body
+				return body, nil
+			}),
+			WithRespMiddleware(func(_ *http.Response, _ *HostConfig, body []byte) ([]byte, error) {
+				responded = body
+				return body, nil
+			}),
+		)
+		assert.False(t, requestStreamed)
+		assert.False(t, responseStreamed)
+		assert.Equal(t, "first\nsecond\n", string(requested))
+		assert.Equal(t, "first\nsecond\n", string(responded))
+	})
+}
+
+func TestStreamingContentTypes(t *testing.T) {
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret", Domain: "upstream.example.org"})
+		_, _ = w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+		_, _ = w.Write([]byte("data: second\n\n"))
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	newProxy := func(opts ...Options) *httptest.Server {
+		return httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			return &HostConfig{
+				UpstreamHost:   urlx.ParseOrPanic(upstream.URL).Host,
+				UpstreamScheme: "http",
+				TargetHost:     "upstream.example.org",
+				CookieDomain:   "example.org",
+			}, nil
+		}, opts...))
+	}
+
+	// firstEvent returns the first event if it arrives before the upstream completes the response
+	firstEvent := func(t *testing.T, proxy *httptest.Server, contentType string) (cookies []*http.Cookie, event string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, "GET", proxy.URL+"?type="+url.QueryEscape(contentType), nil)
+		require.NoError(t, err)
+
+		events := make(chan string, 1)
+		go func() {
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			cookies = resp.Cookies()
+			event, _ := bufio.NewReader(resp.Body).ReadString('\n')
+			events <- event
+		}()
+		select {
+		case event := <-events:
+			return cookies, event
+		case <-time.After(200 * time.Millisecond):
+			return nil, ""
+		}
+	}
+
+	t.Run("case=streams server-sent events", func(t *testing.T) {
+		proxy := newProxy()
+		defer proxy.Close()
+
+		cookies, event := firstEvent(t, proxy, "text/event-stream; charset=utf-8")
+		assert.Equal(t, "data: first\n", event)
+		require.Len(t, cookies, 1)
+		assert.Equal(t, "example.org", cookies[0].Domain, "the headers are rewritten")
+	})
+
+	t.Run("case=streams the configured content types", func(t *testing.T) {
+		proxy := newProxy(WithStreamingContentTypes("application/x-ndjson"), WithFlushInterval(-1))
+		defer proxy.Close()
+
+		_, event := firstEvent(t, proxy, "application/x-ndjson")
+		assert.Equal(t, "data: first\n", event)
+		_, event = firstEvent(t, proxy, "text/event-stream")
+		assert.Empty(t, event, "the default content types are replaced")
+	})
+}
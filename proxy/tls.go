@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoCertificate is returned when the HostConfig of a host does not reference a certificate.
+var ErrNoCertificate = errors.New("no tls certificate is configured for this host")
+
+type (
+	// CertificateStore loads certificates from disk and reloads them when the files change.
+	CertificateStore struct {
+		mu    sync.RWMutex
+		certs map[certificateKey]*loadedCertificate
+	}
+	certificateKey struct {
+		certPath, keyPath string
+	}
+	loadedCertificate struct {
+		cert              *tls.Certificate
+		certMod, keyMod   time.Time
+		certSize, keySize int64
+	}
+)
+
+// NewCertificateStore creates an empty CertificateStore.
+func NewCertificateStore() *CertificateStore {
+	return &CertificateStore{certs: make(map[certificateKey]*loadedCertificate)}
+}
+
+// Load returns the certificate stored at the given paths. The files are only read again
+// if their modification time or size changed since the last call.
+func (s *CertificateStore) Load(certPath, keyPath string) (*tls.Certificate, error) {
+	certInfo, err := os.Stat(certPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	keyInfo, err := os.Stat(keyPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	k := certificateKey{certPath: certPath, keyPath: keyPath}
+
+	s.mu.RLock()
+	cached, ok := s.certs[k]
+	s.mu.RUnlock()
+	if ok && cached.certMod.Equal(certInfo.ModTime()) && cached.keyMod.Equal(keyInfo.ModTime()) &&
+		cached.certSize == certInfo.Size() && cached.keySize == keyInfo.Size() {
+		return cached.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		if ok {
+			// the files might be in the middle of being replaced, keep serving the old certificate
+			return cached.cert, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs[k] = &loadedCertificate{
+		cert:     &cert,
+		certMod:  certInfo.ModTime(),
+		keyMod:   keyInfo.ModTime(),
+		certSize: certInfo.Size(),
+		keySize:  keyInfo.Size(),
+	}
+	return &cert, nil
+}
+
+// GetCertificate returns a function usable as tls.Config.GetCertificate. It resolves the SNI server name
+// through the HostMapper, just like a request for that host would be, and serves the certificate referenced
+// by the HostConfig's TLSCertificatePath and TLSKeyPath.
+func GetCertificate(hostMapper HostMapper, store *CertificateStore) func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		c, err := hostConfigForServerName(hello.Context(), hostMapper, hello.ServerName)
+		if err != nil {
+			return nil, err
+		}
+		if c.TLSCertificatePath == "" || c.TLSKeyPath == "" {
+			return nil, errors.WithStack(ErrNoCertificate)
+		}
+		return store.Load(c.TLSCertificatePath, c.TLSKeyPath)
+	}
+}
+
+// NewTLSConfig returns a tls.Config that selects the certificate per host using GetCertificate.
+func NewTLSConfig(hostMapper HostMapper) *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: GetCertificate(hostMapper, NewCertificateStore()),
+	}
+}
+
+// hostConfigForServerName calls the HostMapper with a synthetic request for the given host.
+func hostConfigForServerName(ctx context.Context, hostMapper HostMapper, serverName string) (*HostConfig, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r := (&http.Request{
+		Method: http.MethodGet,
+		Host:   serverName,
+		URL:    &url.URL{Scheme: "https", Host: serverName, Path: "/"},
+		Header: http.Header{},
+	}).WithContext(ctx)
+	return hostMapper(ctx, r)
+}
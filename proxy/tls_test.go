@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/tlsx"
+)
+
+func writeCertificate(t *testing.T, certPath, keyPath string) *tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	cert, err := tlsx.CreateSelfSignedCertificate(key)
+	require.NoError(t, err)
+	block, err := tlsx.PEMBlockForKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600))
+
+	tc, err := tls.LoadX509KeyPair(certPath, keyPath)
+	require.NoError(t, err)
+	return &tc
+}
+
+func TestGetCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	expected := writeCertificate(t, certPath, keyPath)
+
+	getCertificate := GetCertificate(func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		if r.Host != "example.com" {
+			return &HostConfig{}, nil
+		}
+		return &HostConfig{TLSCertificatePath: certPath, TLSKeyPath: keyPath}, nil
+	}, NewCertificateStore())
+
+	t.Run("case=selects certificate by server name", func(t *testing.T) {
+		actual, err := getCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, expected.Certificate, actual.Certificate)
+	})
+
+	t.Run("case=fails for host without certificate", func(t *testing.T) {
+		_, err := getCertificate(&tls.ClientHelloInfo{ServerName: "unknown.com"})
+		assert.ErrorIs(t, err, ErrNoCertificate)
+	})
+
+	t.Run("case=reloads changed certificate", func(t *testing.T) {
+		reloaded := writeCertificate(t, certPath, keyPath)
+		future := time.Now().Add(time.Minute)
+		require.NoError(t, os.Chtimes(certPath, future, future))
+
+		actual, err := getCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, reloaded.Certificate, actual.Certificate)
+		assert.NotEqual(t, expected.Certificate, actual.Certificate)
+	})
+}
+
+func TestAutocertHostPolicy(t *testing.T) {
+	policy := AutocertHostPolicy(func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		if r.Host != "example.com" {
+			return nil, errors.New("unknown host")
+		}
+		return &HostConfig{}, nil
+	})
+
+	assert.NoError(t, policy(context.Background(), "example.com"))
+	assert.Error(t, policy(context.Background(), "evil.com"))
+}
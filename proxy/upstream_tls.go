@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	mtlsKey struct {
+		certPath, keyPath, caPath string
+	}
+	// mtlsTransport is the transport of the upstreams sharing a client certificate and authorities.
+	mtlsTransport struct {
+		transport *http.Transport
+		caMod     time.Time
+		caSize    int64
+	}
+)
+
+// mtlsTransport returns a copy of the configured transport presenting the client certificate of the HostConfig
+// and verifying the upstream with its authorities, or with the roots of the configured transport if it has none.
+// The client certificate is loaded on every handshake through the CertificateStore, while changed authorities
+// replace the transport, which closes its idle connections.
+func (t *upstreamTransport) mtlsTransport(c *HostConfig) (http.RoundTripper, error) {
+	k := mtlsKey{certPath: c.UpstreamClientCertificatePath, keyPath: c.UpstreamClientKeyPath, caPath: c.UpstreamCAPath}
+	if (k.certPath == "") != (k.keyPath == "") {
+		return nil, errors.Errorf("upstream %s requires both a client certificate and key", c.UpstreamHost)
+	}
+
+	var caInfo os.FileInfo
+	if k.caPath != "" {
+		var err error
+		if caInfo, err = os.Stat(k.caPath); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cached, ok := t.mtls[k]
+	if ok && (caInfo == nil || cached.caMod.Equal(caInfo.ModTime()) && cached.caSize == caInfo.Size()) {
+		return cached.transport, nil
+	}
+
+	ht := t.baseTransport()
+	// keep the settings of the configured transport, e.g. its roots if the HostConfig has no authorities
+	if ht.TLSClientConfig == nil {
+		ht.TLSClientConfig = new(tls.Config)
+	} else {
+		ht.TLSClientConfig = ht.TLSClientConfig.Clone()
+	}
+	if ht.TLSClientConfig.MinVersion < tls.VersionTLS12 {
+		ht.TLSClientConfig.MinVersion = tls.VersionTLS12
+	}
+	if k.certPath != "" {
+		certs := t.certs
+		ht.TLSClientConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.Load(k.certPath, k.keyPath)
+		}
+	}
+	loaded := &mtlsTransport{transport: ht}
+	if caInfo != nil {
+		roots, err := loadCertPool(k.caPath)
+		if err != nil {
+			if ok {
+				// the file might be in the middle of being replaced, keep verifying with the old authorities
+				return cached.transport, nil
+			}
+			return nil, err
+		}
+		ht.TLSClientConfig.RootCAs = roots
+		loaded.caMod, loaded.caSize = caInfo.ModTime(), caInfo.Size()
+	}
+
+	if t.mtls == nil {
+		t.mtls = make(map[mtlsKey]*mtlsTransport)
+	}
+	if ok {
+		cached.transport.CloseIdleConnections()
+	}
+	t.mtls[k] = loaded
+	return ht, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(raw) {
+		return nil, errors.Errorf("no PEM encoded certificates found in %s", path)
+	}
+	return pool, nil
+}
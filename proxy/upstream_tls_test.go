@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestUpstreamMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caPath, upstreamKeyPath := filepath.Join(dir, "upstream.pem"), filepath.Join(dir, "upstream.key")
+	clientCertPath, clientKeyPath := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	upstreamCert := writeCertificate(t, caPath, upstreamKeyPath)
+	clientCert := writeCertificate(t, clientCertPath, clientKeyPath)
+
+	// the upstream is self-signed, so its certificate is the authority as well
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// every request performs a new handshake
+		w.Header().Set("Connection", "close")
+		_, _ = w.Write([]byte(hex.EncodeToString(r.TLS.PeerCertificates[0].Raw)))
+	}))
+	upstream.TLS = &tls.Config{
+		Certificates: []tls.Certificate{*upstreamCert},
+		ClientAuth:   tls.RequireAnyClientCert,
+	}
+	upstream.StartTLS()
+	defer upstream.Close()
+	upstreamHost := "localhost:" + urlx.ParseOrPanic(upstream.URL).Port()
+
+	newProxy := func(c *HostConfig, opts ...Options) *httptest.Server {
+		return httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+			cc := *c
+			cc.UpstreamHost, cc.UpstreamScheme = upstreamHost, "https"
+			return &cc, nil
+		}, opts...))
+	}
+	presented := func(t *testing.T, proxy *httptest.Server) (int, string) {
+		resp, err := http.Get(proxy.URL)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	t.Run("case=presents the client certificate", func(t *testing.T) {
+		proxy := newProxy(&HostConfig{UpstreamClientCertificatePath: clientCertPath, UpstreamClientKeyPath: clientKeyPath, UpstreamCAPath: caPath})
+		defer proxy.Close()
+
+		status, cert := presented(t, proxy)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, hex.EncodeToString(clientCert.Certificate[0]), cert)
+
+		t.Run("case=reloads the changed client certificate", func(t *testing.T) {
+			reloaded := writeCertificate(t, clientCertPath, clientKeyPath)
+			future := time.Now().Add(time.Minute)
+			require.NoError(t, os.Chtimes(clientCertPath, future, future))
+
+			status, cert := presented(t, proxy)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, hex.EncodeToString(reloaded.Certificate[0]), cert)
+		})
+	})
+
+	t.Run("case=keeps the TLS config of the transport", func(t *testing.T) {
+		leaf, err := x509.ParseCertificate(upstreamCert.Certificate[0])
+		require.NoError(t, err)
+		roots := x509.NewCertPool()
+		roots.AddCert(leaf)
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+
+		proxy := newProxy(&HostConfig{UpstreamClientCertificatePath: clientCertPath, UpstreamClientKeyPath: clientKeyPath}, WithTransport(transport))
+		defer proxy.Close()
+
+		status, cert := presented(t, proxy)
+		assert.Equal(t, http.StatusOK, status, "the upstream is verified with the roots of the transport")
+		assert.NotEmpty(t, cert)
+		assert.Nil(t, transport.TLSClientConfig.GetClientCertificate, "the transport is not modified")
+	})
+
+	t.Run("case=rejects upstreams of other authorities", func(t *testing.T) {
+		otherCAPath := filepath.Join(dir, "other.pem")
+		writeCertificate(t, otherCAPath, filepath.Join(dir, "other.key"))
+		proxy := newProxy(&HostConfig{UpstreamClientCertificatePath: clientCertPath, UpstreamClientKeyPath: clientKeyPath, UpstreamCAPath: otherCAPath})
+		defer proxy.Close()
+
+		status, _ := presented(t, proxy)
+		assert.Equal(t, http.StatusBadGateway, status)
+
+		t.Run("case=reloads the changed authorities", func(t *testing.T) {
+			raw, err := os.ReadFile(caPath)
+			require.NoError(t, err)
+			require.NoError(t, os.WriteFile(otherCAPath, raw, 0600))
+			future := time.Now().Add(time.Minute)
+			require.NoError(t, os.Chtimes(otherCAPath, future, future))
+
+			status, _ := presented(t, proxy)
+			assert.Equal(t, http.StatusOK, status)
+		})
+	})
+
+	t.Run("case=fails without client certificate", func(t *testing.T) {
+		proxy := newProxy(&HostConfig{UpstreamCAPath: caPath})
+		defer proxy.Close()
+
+		status, _ := presented(t, proxy)
+		assert.Equal(t, http.StatusBadGateway, status)
+	})
+
+	t.Run("case=loads the authorities", func(t *testing.T) {
+		pool, err := loadCertPool(caPath)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(upstreamCert.Certificate[0])
+		require.NoError(t, err)
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: pool, DNSName: "localhost"})
+		assert.NoError(t, err)
+
+		_, err = loadCertPool(clientKeyPath)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,198 @@
+package proxy
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WebhookProvider is a webhook sender whose signature scheme is supported by WebhookPolicy.
+type WebhookProvider string
+
+const (
+	// WebhookStripe verifies the Stripe-Signature header.
+	WebhookStripe WebhookProvider = "stripe"
+	// WebhookGitHub verifies the X-Hub-Signature-256 header.
+	WebhookGitHub WebhookProvider = "github"
+	// WebhookSlack verifies the X-Slack-Signature and X-Slack-Request-Timestamp headers.
+	WebhookSlack WebhookProvider = "slack"
+)
+
+const (
+	// DefaultWebhookTolerance is the maximum age of a signed webhook if WebhookPolicy.Tolerance is not set.
+	DefaultWebhookTolerance = 5 * time.Minute
+	// DefaultWebhookMaxBodyBytes is the maximum size of a webhook body if WebhookPolicy.MaxBodyBytes is not set.
+	DefaultWebhookMaxBodyBytes int64 = 1 << 20
+)
+
+var (
+	// ErrInvalidWebhookSignature is returned when a webhook has a missing or invalid signature.
+	ErrInvalidWebhookSignature = errors.New("the webhook signature is invalid")
+	// ErrWebhookExpired is returned when the timestamp of a signed webhook is outside the tolerance.
+	ErrWebhookExpired = errors.New("the webhook timestamp is outside the tolerance")
+	// ErrWebhookBodyTooLarge is returned when the body of a webhook exceeds WebhookPolicy.MaxBodyBytes.
+	ErrWebhookBodyTooLarge = errors.New("the webhook body is too large")
+)
+
+// WebhookPolicy requires requests to carry a valid webhook signature of the provider.
+type WebhookPolicy struct {
+	// Provider is the sender of the webhooks.
+	Provider WebhookProvider `json:"provider,omitempty"`
+	// Secrets are the signing secrets. Signatures created with any of the secrets are accepted,
+	// which allows to rotate secrets.
+	Secrets [][]byte `json:"secrets,omitempty"`
+	// Tolerance is the maximum difference between the signed timestamp and now.
+	// It is ignored for providers which do not sign a timestamp.
+	// Default: DefaultWebhookTolerance
+	Tolerance time.Duration `json:"tolerance,omitempty"`
+	// PathPrefixes restricts the policy to requests whose path starts with one of the prefixes.
+	// If left empty, all requests must be signed.
+	PathPrefixes []string `json:"path_prefixes,omitempty"`
+	// MaxBodyBytes is the maximum size of the body, which is buffered to verify the signature.
+	// Default: DefaultWebhookMaxBodyBytes
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+}
+
+func hmacSHA256(secret []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	for _, p := range parts {
+		_, _ = mac.Write(p)
+	}
+	return mac.Sum(nil)
+}
+
+// matchesAny returns true if the hex encoded signature is the HMAC-SHA256 of the payload using any of the secrets.
+func matchesAny(signature string, secrets [][]byte, payload ...[]byte) bool {
+	mac, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	for _, secret := range secrets {
+		if hmac.Equal(mac, hmacSHA256(secret, payload...)) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkTimestamp(timestamp string, now time.Time, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.WithStack(ErrInvalidWebhookSignature)
+	}
+	if tolerance == 0 {
+		tolerance = DefaultWebhookTolerance
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > tolerance || d < -tolerance {
+		return errors.WithStack(ErrWebhookExpired)
+	}
+	return nil
+}
+
+// VerifyStripeSignature verifies the value of the Stripe-Signature header, e.g. "t=1492774577,v1=5257a8...".
+func VerifyStripeSignature(header string, body []byte, now time.Time, tolerance time.Duration, secrets ...[]byte) error {
+	var timestamp string
+	var signatures []string
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			timestamp = kv[1]
+		case "v1":
+			signatures = append(signatures, kv[1])
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.WithStack(ErrInvalidWebhookSignature)
+	}
+
+	valid := false
+	for _, signature := range signatures {
+		if matchesAny(signature, secrets, []byte(timestamp), []byte{'.'}, body) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return errors.WithStack(ErrInvalidWebhookSignature)
+	}
+	return checkTimestamp(timestamp, now, tolerance)
+}
+
+// VerifyGitHubSignature verifies the value of the X-Hub-Signature-256 header, e.g. "sha256=757107...".
+func VerifyGitHubSignature(header string, body []byte, secrets ...[]byte) error {
+	signature := strings.TrimPrefix(header, "sha256=")
+	if signature == header || !matchesAny(signature, secrets, body) {
+		return errors.WithStack(ErrInvalidWebhookSignature)
+	}
+	return nil
+}
+
+// VerifySlackSignature verifies the values of the X-Slack-Signature and X-Slack-Request-Timestamp headers.
+func VerifySlackSignature(signatureHeader, timestamp string, body []byte, now time.Time, tolerance time.Duration, secrets ...[]byte) error {
+	signature := strings.TrimPrefix(signatureHeader, "v0=")
+	if signature == signatureHeader || timestamp == "" ||
+		!matchesAny(signature, secrets, []byte("v0:"+timestamp+":"), body) {
+		return errors.WithStack(ErrInvalidWebhookSignature)
+	}
+	return checkTimestamp(timestamp, now, tolerance)
+}
+
+// verify checks the webhook signature of the request if the policy applies. The body is buffered up to
+// MaxBodyBytes and passed on unchanged.
+func (p *WebhookPolicy) verify(w http.ResponseWriter, r *http.Request) error {
+	if p == nil {
+		return nil
+	}
+
+	applies := len(p.PathPrefixes) == 0
+	for _, prefix := range p.PathPrefixes {
+		if strings.HasPrefix(r.URL.Path, prefix) {
+			applies = true
+			break
+		}
+	}
+	if !applies {
+		return nil
+	}
+
+	var body []byte
+	if r.Body != nil {
+		limit := p.MaxBodyBytes
+		if limit <= 0 {
+			limit = DefaultWebhookMaxBodyBytes
+		}
+		var err error
+		body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+		_ = r.Body.Close()
+		if err != nil && int64(len(body)) >= limit {
+			// the reader returns the bytes up to the limit before failing
+			return errors.WithStack(ErrWebhookBodyTooLarge)
+		} else if err != nil {
+			return errors.WithStack(err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	switch p.Provider {
+	case WebhookStripe:
+		return VerifyStripeSignature(r.Header.Get("Stripe-Signature"), body, time.Now(), p.Tolerance, p.Secrets...)
+	case WebhookGitHub:
+		return VerifyGitHubSignature(r.Header.Get("X-Hub-Signature-256"), body, p.Secrets...)
+	case WebhookSlack:
+		return VerifySlackSignature(r.Header.Get("X-Slack-Signature"), r.Header.Get("X-Slack-Request-Timestamp"), body, time.Now(), p.Tolerance, p.Secrets...)
+	default:
+		return errors.Errorf("unknown webhook provider %q", p.Provider)
+	}
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestWebhookSignatures(t *testing.T) {
+	secret, other := []byte("whsec"), []byte("other")
+	body := []byte(`{"type":"invoice.paid"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sign := func(parts ...[]byte) string {
+		return hex.EncodeToString(hmacSHA256(secret, parts...))
+	}
+
+	t.Run("provider=stripe", func(t *testing.T) {
+		header := "t=" + ts + ",v1=" + sign([]byte(ts+"."), body)
+		assert.NoError(t, VerifyStripeSignature(header, body, now, 0, other, secret))
+		assert.ErrorIs(t, VerifyStripeSignature(header, body, now, 0, other), ErrInvalidWebhookSignature)
+		assert.ErrorIs(t, VerifyStripeSignature(header, []byte("{}"), now, 0, secret), ErrInvalidWebhookSignature)
+		assert.ErrorIs(t, VerifyStripeSignature(header, body, now.Add(time.Hour), 0, secret), ErrWebhookExpired)
+		assert.NoError(t, VerifyStripeSignature(header, body, now.Add(time.Hour), 2*time.Hour, secret))
+		assert.ErrorIs(t, VerifyStripeSignature("v1="+sign(body), body, now, 0, secret), ErrInvalidWebhookSignature)
+	})
+
+	t.Run("provider=github", func(t *testing.T) {
+		assert.NoError(t, VerifyGitHubSignature("sha256="+sign(body), body, secret))
+		assert.ErrorIs(t, VerifyGitHubSignature(sign(body), body, secret), ErrInvalidWebhookSignature)
+		assert.ErrorIs(t, VerifyGitHubSignature("sha256="+sign(body), body, other), ErrInvalidWebhookSignature)
+	})
+
+	t.Run("provider=slack", func(t *testing.T) {
+		signature := "v0=" + sign([]byte("v0:"+ts+":"), body)
+		assert.NoError(t, VerifySlackSignature(signature, ts, body, now, 0, secret))
+		assert.ErrorIs(t, VerifySlackSignature(signature, "", body, now, 0, secret), ErrInvalidWebhookSignature)
+		assert.ErrorIs(t, VerifySlackSignature(signature, ts, body, now.Add(-time.Hour), 0, secret), ErrWebhookExpired)
+	})
+}
+
+func TestWebhookPolicy(t *testing.T) {
+	secret := []byte("secret")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}))
+	defer upstream.Close()
+
+	proxy := httptest.NewServer(New(func(_ context.Context, r *http.Request) (*HostConfig, error) {
+		policy := &WebhookPolicy{Provider: WebhookGitHub, Secrets: [][]byte{secret}, PathPrefixes: []string{"/hooks/"}}
+		if r.URL.Path == "/hooks/small" {
+			policy.MaxBodyBytes = 8
+		}
+		return &HostConfig{
+			UpstreamHost:   urlx.ParseOrPanic(upstream.URL).Host,
+			UpstreamScheme: "http",
+			Webhook:        policy,
+		}, nil
+	}))
+	defer proxy.Close()
+
+	send := func(path, signature string) (int, string) {
+		body := []byte(`{"action":"opened"}`)
+		req, err := http.NewRequest(http.MethodPost, proxy.URL+path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("X-Hub-Signature-256", signature)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		actual, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(actual)
+	}
+	valid := "sha256=" + hex.EncodeToString(hmacSHA256(secret, []byte(`{"action":"opened"}`)))
+
+	code, body := send("/hooks/github", valid)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"action":"opened"}`, body)
+
+	code, _ = send("/hooks/github", "sha256=00")
+	assert.Equal(t, http.StatusUnauthorized, code)
+
+	code, _ = send("/other", "")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = send("/hooks/small", valid)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, code, "the body exceeds MaxBodyBytes")
+}
+
+func TestWebhookMaxBodyBytes(t *testing.T) {
+	p := &WebhookPolicy{Provider: WebhookGitHub, Secrets: [][]byte{[]byte("secret")}}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, DefaultWebhookMaxBodyBytes+1)))
+	assert.ErrorIs(t, p.verify(httptest.NewRecorder(), r), ErrWebhookBodyTooLarge)
+
+	r = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(make([]byte, DefaultWebhookMaxBodyBytes)))
+	assert.ErrorIs(t, p.verify(httptest.NewRecorder(), r), ErrInvalidWebhookSignature, "bodies up to the limit are verified")
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// WebsocketStats describes a websocket tunnel (or any other upgraded connection) after it was closed.
+	WebsocketStats struct {
+		// Duration is the time the tunnel was open.
+		Duration time.Duration
+		// BytesFromUpstream is the number of bytes sent from the upstream to the client.
+		BytesFromUpstream int64
+		// BytesToUpstream is the number of bytes sent from the client to the upstream.
+		BytesToUpstream int64
+	}
+	websocketCallbacks struct {
+		onOpen  func(*http.Request, *HostConfig)
+		onClose func(*http.Request, *HostConfig, WebsocketStats)
+	}
+	websocketTunnel struct {
+		io.ReadWriteCloser
+		req       *http.Request
+		config    *HostConfig
+		callbacks *websocketCallbacks
+		start     time.Time
+		from, to  int64
+		closeOnce sync.Once
+	}
+)
+
+// WithOnWebsocketOpen sets a callback which is called when the upstream accepted a websocket upgrade.
+func WithOnWebsocketOpen(f func(req *http.Request, config *HostConfig)) Options {
+	return func(o *options) {
+		o.websocket.onOpen = f
+	}
+}
+
+// WithOnWebsocketClose sets a callback which is called once a websocket tunnel is closed,
+// e.g. to account for long-lived connections per tenant.
+func WithOnWebsocketClose(f func(req *http.Request, config *HostConfig, stats WebsocketStats)) Options {
+	return func(o *options) {
+		o.websocket.onClose = f
+	}
+}
+
+// track wraps the upgraded upstream connection to call the callbacks.
+func (cb *websocketCallbacks) track(r *http.Response, config *HostConfig, conn io.ReadWriteCloser) io.ReadWriteCloser {
+	if r.StatusCode != http.StatusSwitchingProtocols || (cb.onOpen == nil && cb.onClose == nil) {
+		return conn
+	}
+	if cb.onOpen != nil {
+		cb.onOpen(r.Request, config)
+	}
+	return &websocketTunnel{
+		ReadWriteCloser: conn,
+		req:             r.Request,
+		config:          config,
+		callbacks:       cb,
+		start:           time.Now(),
+	}
+}
+
+func (t *websocketTunnel) Read(p []byte) (int, error) {
+	n, err := t.ReadWriteCloser.Read(p)
+	atomic.AddInt64(&t.from, int64(n))
+	return n, err
+}
+
+func (t *websocketTunnel) Write(p []byte) (int, error) {
+	n, err := t.ReadWriteCloser.Write(p)
+	atomic.AddInt64(&t.to, int64(n))
+	return n, err
+}
+
+func (t *websocketTunnel) Close() error {
+	err := t.ReadWriteCloser.Close()
+	t.closeOnce.Do(func() {
+		if t.callbacks.onClose != nil {
+			t.callbacks.onClose(t.req, t.config, WebsocketStats{
+				Duration:          time.Since(t.start),
+				BytesFromUpstream: atomic.LoadInt64(&t.from),
+				BytesToUpstream:   atomic.LoadInt64(&t.to),
+			})
+		}
+	})
+	return err
+}
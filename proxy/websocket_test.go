@@ -0,0 +1,65 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/urlx"
+)
+
+func TestWebsocketCallbacks(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		mt, message, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		_ = c.WriteMessage(mt, append(message, message...))
+	}))
+	defer upstream.Close()
+
+	opened := make(chan string, 1)
+	closed := make(chan WebsocketStats, 1)
+	proxy := httptest.NewServer(New(func(context.Context, *http.Request) (*HostConfig, error) {
+		return &HostConfig{UpstreamHost: urlx.ParseOrPanic(upstream.URL).Host, UpstreamScheme: "http", CookieDomain: "tenant"}, nil
+	},
+		WithOnWebsocketOpen(func(_ *http.Request, c *HostConfig) {
+			opened <- c.CookieDomain
+		}),
+		WithOnWebsocketClose(func(_ *http.Request, _ *HostConfig, stats WebsocketStats) {
+			closed <- stats
+		})))
+	defer proxy.Close()
+
+	c, _, err := websocket.DefaultDialer.Dial((&url.URL{Scheme: "ws", Host: urlx.ParseOrPanic(proxy.URL).Host}).String(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant", <-opened)
+
+	require.NoError(t, c.WriteMessage(websocket.TextMessage, []byte("ping")))
+	_, message, err := c.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "pingping", string(message))
+	require.NoError(t, c.Close())
+
+	select {
+	case stats := <-closed:
+		assert.Greater(t, stats.Duration, time.Duration(0))
+		// client frames are masked and therefore four bytes longer
+		assert.EqualValues(t, 2+4+4, stats.BytesToUpstream)
+		assert.GreaterOrEqual(t, stats.BytesFromUpstream, int64(2+8))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the close callback was not called")
+	}
+}
@@ -0,0 +1,116 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultBackupPagesPerStep is the number of pages copied by each step of BackupSQLite if none is set.
+	DefaultBackupPagesPerStep = 256
+	// backupBusyWait is the time to wait if a step of the online backup could not copy pages, because the
+	// database is locked by a writer.
+	backupBusyWait = 10 * time.Millisecond
+)
+
+type (
+	backupOptions struct {
+		pagesPerStep int
+		stepDelay    time.Duration
+		progress     func(copied, total int)
+	}
+	// BackupOption configures BackupSQLite.
+	BackupOption func(*backupOptions)
+	// onlineBackup copies the database of the driver connection to the path using the backup API of the
+	// driver. It returns false if it does not support the driver connection.
+	onlineBackup func(ctx context.Context, driverConn interface{}, path string, o *backupOptions) (bool, error)
+)
+
+// onlineBackups are registered by the builds of the drivers supporting the backup API.
+var onlineBackups []onlineBackup
+
+// WithBackupProgress sets a callback which is called with the number of copied and total pages after every step.
+func WithBackupProgress(fn func(copied, total int)) BackupOption {
+	return func(o *backupOptions) {
+		o.progress = fn
+	}
+}
+
+// WithBackupStep sets the number of pages copied by each step of the backup API, and the delay between the
+// steps, which lets writers of the database proceed in between.
+// Default: DefaultBackupPagesPerStep and no delay
+func WithBackupStep(pages int, delay time.Duration) BackupOption {
+	return func(o *backupOptions) {
+		o.pagesPerStep, o.stepDelay = pages, delay
+	}
+}
+
+// BackupSQLite takes a consistent backup of the SQLite database into a new file at the path, while the database
+// is in use. With the sqlite build tag, the backup API of github.com/mattn/go-sqlite3 copies the database in
+// steps, so that writers are only blocked during each step; the backup restarts if the database is written by
+// another process meanwhile. Other drivers, e.g. with the sqlite_modernc build tag, run "VACUUM INTO", which
+// copies the database in a single read transaction, and report the progress once it completed.
+//
+// The backup is written to a temporary file next to the path, which is renamed once it is complete, so that the
+// path never contains a partial backup. It is an error if the path exists already.
+func BackupSQLite(ctx context.Context, db *sql.DB, path string, opts ...BackupOption) (err error) {
+	o := &backupOptions{pagesPerStep: DefaultBackupPagesPerStep}
+	for _, f := range opts {
+		f(o)
+	}
+	if o.pagesPerStep <= 0 {
+		o.pagesPerStep = DefaultBackupPagesPerStep
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return errors.Errorf("the backup file %s exists already", path)
+	} else if !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	tmp := path + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return HandleError(err)
+	}
+	defer conn.Close()
+
+	var done bool
+	if err := conn.Raw(func(driverConn interface{}) (err error) {
+		for _, backup := range onlineBackups {
+			if done, err = backup(ctx, driverConn, tmp, o); done || err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return HandleError(err)
+	}
+
+	if !done {
+		var pages int
+		if err := conn.QueryRowContext(ctx, "PRAGMA page_count").Scan(&pages); err != nil {
+			return HandleError(err)
+		}
+		if _, err := conn.ExecContext(ctx, "VACUUM INTO ?", tmp); err != nil {
+			return HandleError(err)
+		}
+		if o.progress != nil {
+			o.progress(pages, pages)
+		}
+	}
+
+	return errors.WithStack(os.Rename(tmp, path))
+}
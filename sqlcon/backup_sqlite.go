@@ -0,0 +1,71 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	onlineBackups = append(onlineBackups, backupSqlite)
+}
+
+// backupSqlite copies the database using the backup API of github.com/mattn/go-sqlite3.
+func backupSqlite(ctx context.Context, driverConn interface{}, path string, o *backupOptions) (_ bool, err error) {
+	src, ok := driverConn.(*sqlite3.SQLiteConn)
+	if !ok {
+		return false, nil
+	}
+
+	c, err := (&sqlite3.SQLiteDriver{}).Open(path)
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	dest := c.(*sqlite3.SQLiteConn)
+	defer func() {
+		if closeErr := dest.Close(); err == nil {
+			err = errors.WithStack(closeErr)
+		}
+	}()
+
+	b, err := dest.Backup("main", src, "main")
+	if err != nil {
+		return true, errors.WithStack(err)
+	}
+	defer func() {
+		if finishErr := b.Finish(); err == nil {
+			err = errors.WithStack(finishErr)
+		}
+	}()
+
+	for copied := 0; ; {
+		done, err := b.Step(o.pagesPerStep)
+		if err != nil {
+			return true, errors.WithStack(err)
+		}
+		previous := copied
+		copied = b.PageCount() - b.Remaining()
+		if o.progress != nil {
+			o.progress(copied, b.PageCount())
+		}
+		if done {
+			return true, nil
+		}
+
+		delay := o.stepDelay
+		// the step was blocked by a writer
+		if copied <= previous && delay < backupBusyWait {
+			delay = backupBusyWait
+		}
+		select {
+		case <-ctx.Done():
+			return true, errors.WithStack(ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,40 @@
+//go:build sqlite_modernc
+// +build sqlite_modernc
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestBackupModerncSqlite(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "db.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY); INSERT INTO identities (id) VALUES (1), (2)")
+	require.NoError(t, err)
+
+	var copied, total int
+	path := filepath.Join(dir, "backup.sqlite")
+	require.NoError(t, BackupSQLite(context.Background(), db, path, WithBackupProgress(func(c, t int) {
+		copied, total = c, t
+	})))
+	assert.Equal(t, total, copied)
+	assert.NotZero(t, total)
+
+	backup, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer backup.Close()
+	var count int
+	require.NoError(t, backup.QueryRow("SELECT COUNT(*) FROM identities").Scan(&count))
+	assert.Equal(t, 2, count)
+}
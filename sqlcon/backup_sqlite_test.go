@@ -0,0 +1,58 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupSQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(dir, "db.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY, traits TEXT NOT NULL)")
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err = db.Exec("INSERT INTO identities (traits) VALUES (?)", fmt.Sprintf("%01000d", i))
+		require.NoError(t, err)
+	}
+
+	var steps, copied, total int
+	path := filepath.Join(dir, "backup.sqlite")
+	require.NoError(t, BackupSQLite(ctx, db, path, WithBackupStep(10, 0), WithBackupProgress(func(c, t int) {
+		steps++
+		copied, total = c, t
+	})))
+	assert.Greater(t, steps, 1)
+	assert.Equal(t, total, copied)
+	assert.Greater(t, total, 10)
+
+	backup, err := sql.Open("sqlite3", "file:"+path)
+	require.NoError(t, err)
+	defer backup.Close()
+	var count int
+	require.NoError(t, backup.QueryRow("SELECT COUNT(*) FROM identities").Scan(&count))
+	assert.Equal(t, 100, count)
+
+	assert.EqualError(t, BackupSQLite(ctx, db, path), fmt.Sprintf("the backup file %s exists already", path))
+
+	t.Run("case=canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		path := filepath.Join(dir, "canceled.sqlite")
+		err := BackupSQLite(ctx, db, path, WithBackupStep(1, 0), WithBackupProgress(func(int, int) { cancel() }))
+		assert.ErrorIs(t, err, ErrCanceled)
+		assert.NoFileExists(t, path)
+		assert.NoFileExists(t, path+".tmp")
+	})
+}
@@ -0,0 +1,159 @@
+package sqlcon
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/logx"
+)
+
+const (
+	// DefaultBreakerThreshold is the number of consecutive connection failures after which a Breaker opens.
+	DefaultBreakerThreshold = 5
+	// DefaultBreakerCooldown is the time an open Breaker fails fast before it lets a trial operation through.
+	DefaultBreakerCooldown = 10 * time.Second
+)
+
+type (
+	// Breaker is a circuit breaker for database operations. After consecutive connection failures, it opens
+	// and fails operations fast with ErrCircuitOpen instead of letting each of them wait for the unreachable
+	// database. After the cooldown, a single trial operation is let through, which closes the breaker if it
+	// does not fail to connect.
+	//
+	// Only ErrConnectionFailed and ErrTooManyConnections count as failures, as classified by HandleError.
+	// Other errors mean the database responded, and canceled operations say nothing about it.
+	Breaker struct {
+		threshold int
+		cooldown  time.Duration
+		l         logx.Logger
+		now       func() time.Time
+
+		mu       sync.Mutex
+		failures int
+		openedAt time.Time
+		trial    bool
+		lastErr  error
+	}
+	// BreakerOption configures NewBreaker.
+	BreakerOption func(*Breaker)
+)
+
+// WithBreakerThreshold sets the number of consecutive connection failures after which the breaker opens.
+// Default: DefaultBreakerThreshold
+func WithBreakerThreshold(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.threshold = n
+	}
+}
+
+// WithBreakerCooldown sets the time the breaker fails fast before it lets a trial operation through.
+// Default: DefaultBreakerCooldown
+func WithBreakerCooldown(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.cooldown = d
+	}
+}
+
+// WithBreakerLogger logs when the breaker opens and closes at warn and info level.
+func WithBreakerLogger(l logx.Logger) BreakerOption {
+	return func(b *Breaker) {
+		b.l = l
+	}
+}
+
+// NewBreaker returns a closed breaker.
+func NewBreaker(opts ...BreakerOption) *Breaker {
+	b := &Breaker{threshold: DefaultBreakerThreshold, cooldown: DefaultBreakerCooldown, now: time.Now}
+	for _, f := range opts {
+		f(b)
+	}
+	if b.threshold <= 0 {
+		b.threshold = DefaultBreakerThreshold
+	}
+	return b
+}
+
+func isBreakerFailure(err error) bool {
+	return errors.Is(err, ErrConnectionFailed) || errors.Is(err, ErrTooManyConnections)
+}
+
+// Open returns true if the breaker fails operations fast.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures >= b.threshold
+}
+
+// allow returns nil if the operation may run. It returns true for the trial operation of an open breaker.
+func (b *Breaker) allow() (trial bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return false, nil
+	}
+	if !b.trial && !b.now().Before(b.openedAt.Add(b.cooldown)) {
+		b.trial = true
+		return true, nil
+	}
+	return false, errors.WithStack(ErrCircuitOpen.WithWrap(b.lastErr))
+}
+
+// record updates the breaker with the error of an operation, which was handled by HandleError.
+func (b *Breaker) record(err error, trial bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+	switch {
+	case isBreakerFailure(err):
+		b.failures++
+		b.lastErr = err
+		// a failed trial restarts the cooldown
+		if b.failures == b.threshold || trial {
+			b.openedAt = b.now()
+			if b.l != nil {
+				b.l.Warn("opened the database circuit breaker", "failures", b.failures, "cooldown", b.cooldown, "error", err)
+			}
+		}
+	case errors.Is(err, ErrCanceled), errors.Is(err, ErrTimeout):
+	default:
+		if b.failures >= b.threshold && b.l != nil {
+			b.l.Info("closed the database circuit breaker")
+		}
+		b.failures, b.lastErr = 0, nil
+	}
+}
+
+// Do runs fn unless the breaker is open, in which case ErrCircuitOpen is returned, which wraps the last
+// connection failure. Errors are handled by HandleError.
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	trial, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = HandleError(fn(ctx))
+	b.record(err, trial)
+	return err
+}
+
+// ReadyChecker runs the check, e.g. PingChecker, regardless of the state of the breaker, and records its
+// result, so that the breaker closes as soon as the check succeeds. Register it with
+// healthx.Registry.AddReadyCheck to keep the breaker and the readiness of the service in sync:
+//
+//	registry.AddReadyCheck("database", breaker.ReadyChecker(sqlcon.PingChecker(db)))
+func (b *Breaker) ReadyChecker(check healthx.ReadyChecker) healthx.ReadyChecker {
+	return func(r *http.Request) error {
+		err := HandleError(check(r))
+		b.record(err, false)
+		return err
+	}
+}
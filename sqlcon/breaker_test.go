@@ -0,0 +1,65 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	b := NewBreaker(WithBreakerThreshold(2), WithBreakerCooldown(time.Minute))
+	b.now = func() time.Time { return now }
+
+	calls := 0
+	op := func(err error) func(context.Context) error {
+		return func(context.Context) error {
+			calls++
+			return err
+		}
+	}
+
+	t.Run("case=opens after consecutive connection failures", func(t *testing.T) {
+		assert.ErrorIs(t, b.Do(ctx, op(driver.ErrBadConn)), ErrConnectionFailed)
+		assert.NoError(t, b.Do(ctx, op(nil)))
+		assert.ErrorIs(t, b.Do(ctx, op(driver.ErrBadConn)), ErrConnectionFailed)
+		assert.ErrorIs(t, b.Do(ctx, op(context.Canceled)), ErrCanceled)
+		assert.False(t, b.Open())
+		assert.ErrorIs(t, b.Do(ctx, op(driver.ErrBadConn)), ErrConnectionFailed)
+		assert.True(t, b.Open())
+
+		calls = 0
+		err := b.Do(ctx, op(nil))
+		assert.ErrorIs(t, err, ErrCircuitOpen)
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("case=lets a trial operation through after the cooldown", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		assert.ErrorIs(t, b.Do(ctx, op(driver.ErrBadConn)), ErrConnectionFailed)
+		assert.ErrorIs(t, b.Do(ctx, op(nil)), ErrCircuitOpen, "the failed trial restarts the cooldown")
+
+		now = now.Add(time.Minute)
+		assert.EqualError(t, b.Do(ctx, op(errors.New("the database responded"))), "the database responded")
+		assert.False(t, b.Open())
+		assert.NoError(t, b.Do(ctx, op(nil)))
+	})
+
+	t.Run("case=closes when the ready check succeeds", func(t *testing.T) {
+		check := b.ReadyChecker(func(*http.Request) error { return driver.ErrBadConn })
+		require.ErrorIs(t, check(nil), ErrConnectionFailed)
+		require.ErrorIs(t, check(nil), ErrConnectionFailed)
+		assert.ErrorIs(t, b.Do(ctx, op(nil)), ErrCircuitOpen)
+
+		assert.NoError(t, b.ReadyChecker(func(*http.Request) error { return nil })(nil))
+		assert.NoError(t, b.Do(ctx, op(nil)))
+	})
+}
@@ -0,0 +1,190 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxRowsSQLServer is the maximum number of rows of a VALUES clause in SQL Server.
+const maxRowsSQLServer = 1000
+
+type (
+	// BulkInsert inserts many rows with as few statements as possible, in batches which stay below the
+	// parameter limit of the dialect, see Dialect.MaxParameters:
+	//
+	//	err := (&sqlcon.BulkInsert{
+	//		Dialect: sqlcon.DialectPostgres,
+	//		Table:   "identity_credentials",
+	//		Columns: []string{"id", "identity_id", "type"},
+	//	}).Exec(ctx, db, rows...)
+	BulkInsert struct {
+		// Dialect determines the syntax and the parameter limit.
+		Dialect Dialect
+		// Table is the table to insert into, optionally qualified by the schema.
+		Table string
+		// Columns are the inserted columns, in the order of the values of each row.
+		Columns []string
+		// BatchSize is the maximum number of rows inserted by a statement.
+		// Default: as many rows as the parameter limit of the dialect allows
+		BatchSize int
+		// TransactionOptions configure the transaction the batches are inserted in. It is optional.
+		TransactionOptions *TransactionOptions
+	}
+	// BatchError is the error of a batch of BulkInsert, which inserted the rows First to Last, counted
+	// from 0 and including Last.
+	BatchError struct {
+		First, Last int
+		Err         error
+	}
+	// BulkInsertError joins the errors of the batches of BulkInsert.
+	BulkInsertError struct {
+		Batches []*BatchError
+	}
+)
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("unable to insert rows %d to %d: %s", e.First, e.Last, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+func (e *BulkInsertError) Error() string {
+	msgs := make([]string, len(e.Batches))
+	for i, b := range e.Batches {
+		msgs[i] = b.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the batches, so that HandleError classifies the error by the first batch error.
+func (e *BulkInsertError) Unwrap() []error {
+	errs := make([]error, len(e.Batches))
+	for i, b := range e.Batches {
+		errs[i] = b
+	}
+	return errs
+}
+
+// rowErrors are the errors caused by the values of a batch, after which the following batches are still inserted.
+var rowErrors = []error{ErrUniqueViolation, ErrForeignKeyViolation, ErrNotNullViolation, ErrCheckViolation, ErrValueTooLong, ErrNumericOverflow}
+
+func isRowError(err error) bool {
+	for _, class := range rowErrors {
+		if errors.Is(err, class) {
+			return true
+		}
+	}
+	return false
+}
+
+// batchSize returns the number of rows per statement.
+func (b *BulkInsert) batchSize() int {
+	size := b.Dialect.MaxParameters() / len(b.Columns)
+	if b.Dialect == DialectSQLServer && size > maxRowsSQLServer {
+		size = maxRowsSQLServer
+	}
+	if b.BatchSize > 0 && b.BatchSize < size {
+		size = b.BatchSize
+	}
+	return size
+}
+
+// Exec inserts the rows in a transaction started by WithTransaction, so either all or none of the rows are
+// inserted, and the transaction is retried as configured by the TransactionOptions. Each row must have a
+// value for each of the Columns.
+//
+// If a batch fails because of its values, e.g. with ErrUniqueViolation, it is rolled back to a savepoint and
+// the following batches are still inserted, so that the returned *BulkInsertError reports all failed row
+// ranges before the transaction is rolled back. Other errors abort immediately. Errors are handled by
+// HandleError, so the error class of the first failed batch can be matched with errors.Is, and the
+// *BulkInsertError and *BatchError with errors.As.
+func (b *BulkInsert) Exec(ctx context.Context, db TxBeginner, rows ...[]interface{}) error {
+	if b.Dialect.MaxParameters() == 0 {
+		return errors.Errorf("unknown dialect %q", b.Dialect)
+	} else if len(b.Columns) == 0 {
+		return errors.New("no columns to insert")
+	} else if len(b.Columns) > b.Dialect.MaxParameters() {
+		return errors.Errorf("the dialect %q does not support inserting more than %d columns", b.Dialect, b.Dialect.MaxParameters())
+	}
+	for i, row := range rows {
+		if len(row) != len(b.Columns) {
+			return errors.Errorf("row %d has %d values, but %d columns are inserted", i, len(row), len(b.Columns))
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	table, err := quoteIdentifier(b.Dialect, b.Table)
+	if err != nil {
+		return err
+	}
+	columns, err := quoteIdentifiers(b.Dialect, b.Columns)
+	if err != nil {
+		return err
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	size := b.batchSize()
+	savepoints := b.Dialect.Capabilities().Savepoints
+
+	return WithTransaction(ctx, db, b.TransactionOptions, func(ctx context.Context, tx *sql.Tx) error {
+		bulkErr := new(BulkInsertError)
+		for first := 0; first < len(rows); first += size {
+			last := first + size - 1
+			if last >= len(rows) {
+				last = len(rows) - 1
+			}
+			query, args := b.build(prefix, rows[first:last+1])
+
+			exec := func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, query, args...)
+				return errors.WithStack(err)
+			}
+			if savepoints {
+				err = WithTransaction(ctx, db, nil, exec)
+			} else {
+				err = HandleError(exec(ctx, tx))
+			}
+			if err == nil {
+				continue
+			}
+
+			bulkErr.Batches = append(bulkErr.Batches, &BatchError{First: first, Last: last, Err: err})
+			if !savepoints || !isRowError(err) {
+				break
+			}
+		}
+		if len(bulkErr.Batches) > 0 {
+			return errors.WithStack(bulkErr)
+		}
+		return nil
+	})
+}
+
+// build returns the statement inserting the rows and their values as the arguments.
+func (b *BulkInsert) build(prefix string, rows [][]interface{}) (string, []interface{}) {
+	var q strings.Builder
+	args := make([]interface{}, 0, len(rows)*len(b.Columns))
+	q.WriteString(prefix)
+	for i, row := range rows {
+		if i > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				q.WriteString(", ")
+			}
+			args = append(args, v)
+			q.WriteString(b.Dialect.Placeholder(len(args)))
+		}
+		q.WriteString(")")
+	}
+	return q.String(), args
+}
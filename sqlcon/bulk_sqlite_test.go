@@ -0,0 +1,69 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkInsertSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY, state TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	rows := make([][]interface{}, 25)
+	for i := range rows {
+		rows[i] = []interface{}{i, "active"}
+	}
+	b := &BulkInsert{Dialect: DialectSQLite, Table: "identities", Columns: []string{"id", "state"}, BatchSize: 10}
+	require.NoError(t, b.Exec(ctx, db, rows...))
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM identities").Scan(&count))
+	assert.Equal(t, 25, count)
+
+	t.Run("case=row errors are attributed to their batches", func(t *testing.T) {
+		rows := make([][]interface{}, 25)
+		for i := range rows {
+			rows[i] = []interface{}{100 + i, "active"}
+		}
+		rows[3][0] = 1
+		rows[22][1] = nil
+
+		err := b.Exec(ctx, db, rows...)
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+		assert.ErrorIs(t, err, ErrNotNullViolation)
+
+		var bulkErr *BulkInsertError
+		require.ErrorAs(t, err, &bulkErr)
+		require.Len(t, bulkErr.Batches, 2)
+		assert.Equal(t, 0, bulkErr.Batches[0].First)
+		assert.Equal(t, 9, bulkErr.Batches[0].Last)
+		assert.Equal(t, 20, bulkErr.Batches[1].First)
+		assert.Equal(t, 24, bulkErr.Batches[1].Last)
+
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM identities").Scan(&count))
+		assert.Equal(t, 25, count, "the transaction is rolled back")
+	})
+
+	t.Run("case=nested in a transaction", func(t *testing.T) {
+		err := WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			return b.Exec(ctx, db, []interface{}{200, "active"}, []interface{}{201, "active"})
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM identities").Scan(&count))
+		assert.Equal(t, 27, count)
+	})
+}
@@ -0,0 +1,57 @@
+package sqlcon
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkInsertBatchSize(t *testing.T) {
+	for _, tc := range []struct {
+		insert   BulkInsert
+		expected int
+	}{
+		{insert: BulkInsert{Dialect: DialectPostgres, Columns: []string{"a", "b", "c"}}, expected: 21845},
+		{insert: BulkInsert{Dialect: DialectSQLite, Columns: []string{"a", "b"}}, expected: 16383},
+		{insert: BulkInsert{Dialect: DialectSQLServer, Columns: []string{"a"}}, expected: 1000},
+		{insert: BulkInsert{Dialect: DialectSQLServer, Columns: []string{"a", "b", "c"}}, expected: 699},
+		{insert: BulkInsert{Dialect: DialectMySQL, Columns: []string{"a"}, BatchSize: 100}, expected: 100},
+	} {
+		t.Run("dialect="+tc.insert.Dialect.String(), func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.insert.batchSize())
+		})
+	}
+}
+
+func TestBulkInsertBuild(t *testing.T) {
+	b := &BulkInsert{Dialect: DialectPostgres, Columns: []string{"id", "state"}}
+	query, args := b.build("INSERT INTO t (id, state) VALUES ", [][]interface{}{{1, "active"}, {2, "inactive"}})
+	assert.Equal(t, "INSERT INTO t (id, state) VALUES ($1, $2), ($3, $4)", query)
+	assert.Equal(t, []interface{}{1, "active", 2, "inactive"}, args)
+}
+
+func TestBulkInsertInvalid(t *testing.T) {
+	ctx := context.Background()
+	assert.EqualError(t, (&BulkInsert{Dialect: "oracle", Table: "t", Columns: []string{"id"}}).Exec(ctx, nil), `unknown dialect "oracle"`)
+	assert.EqualError(t, (&BulkInsert{Dialect: DialectSQLite, Table: "t"}).Exec(ctx, nil), "no columns to insert")
+	assert.EqualError(t, (&BulkInsert{Dialect: DialectSQLite, Table: "t", Columns: make([]string, 40000)}).Exec(ctx, nil), `the dialect "sqlite" does not support inserting more than 32766 columns`)
+	assert.EqualError(t, (&BulkInsert{Dialect: DialectSQLite, Table: "t", Columns: []string{"id"}}).Exec(ctx, nil, []interface{}{1, 2}), "row 0 has 2 values, but 1 columns are inserted")
+	assert.EqualError(t, (&BulkInsert{Dialect: DialectSQLite, Table: "t;", Columns: []string{"id"}}).Exec(ctx, nil, []interface{}{1}), `invalid identifier "t;"`)
+	assert.NoError(t, (&BulkInsert{Dialect: DialectSQLite, Table: "t", Columns: []string{"id"}}).Exec(ctx, nil))
+}
+
+func TestBulkInsertError(t *testing.T) {
+	err := &BulkInsertError{Batches: []*BatchError{
+		{First: 0, Last: 9, Err: ErrUniqueViolation},
+		{First: 20, Last: 24, Err: ErrNotNullViolation},
+	}}
+	assert.EqualError(t, err, "unable to insert rows 0 to 9: "+ErrUniqueViolation.Error()+"; unable to insert rows 20 to 24: "+ErrNotNullViolation.Error())
+
+	handled := HandleError(err)
+	assert.ErrorIs(t, handled, ErrUniqueViolation)
+	assert.ErrorIs(t, handled, ErrNotNullViolation)
+	var bulkErr *BulkInsertError
+	assert.ErrorAs(t, handled, &bulkErr)
+	assert.Len(t, bulkErr.Batches, 2)
+}
@@ -0,0 +1,309 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/logx"
+)
+
+// DefaultReaderRetryInterval is the time a failed reader is skipped if no interval is set.
+const DefaultReaderRetryInterval = 30 * time.Second
+
+type (
+	// Connection holds the connection pools of a writer and any number of readers, e.g. read replicas.
+	// Writes, and reads which must see them, use the writer, while ReadQueryContext and read-only
+	// transactions use the readers in turn. Readers which fail to connect are skipped for the retry
+	// interval. Without a healthy reader, the writer is used. Errors are handled by HandleError.
+	//
+	// If the writer DSN lists several PostgreSQL servers, e.g. "postgres://db-1:5432,db-2:5432/db", the writer
+	// follows failovers: its connections are only opened to the primary, and once the writer reports
+	// ErrReadOnly or ErrConnectionFailed, or PingContext finds it connected to a standby, its idle connections
+	// are closed, so that the next ones are opened to the new primary.
+	//
+	// With WithMaxReplicationLag, PingContext also skips readers which lag behind the primary, so that stale
+	// replicas are removed from the readers until they caught up.
+	Connection struct {
+		writer     *sql.DB
+		writerPool PoolOptions
+		failover   bool
+		failoverMu sync.Mutex
+		readers    []*reader
+		next       uint32
+
+		retryInterval time.Duration
+		lagDialect    Dialect
+		maxLag        time.Duration
+		l             logx.Logger
+		now           func() time.Time
+	}
+	reader struct {
+		db *sql.DB
+
+		mu        sync.Mutex
+		skipUntil time.Time
+	}
+	connectionOptions struct {
+		retryInterval time.Duration
+		lagDialect    Dialect
+		maxLag        time.Duration
+		l             logx.Logger
+	}
+	// ConnectionOption configures NewConnection.
+	ConnectionOption func(*connectionOptions)
+)
+
+// WithReaderRetryInterval sets the time a reader is skipped after it failed to connect.
+// Default: DefaultReaderRetryInterval
+func WithReaderRetryInterval(d time.Duration) ConnectionOption {
+	return func(o *connectionOptions) {
+		o.retryInterval = d
+	}
+}
+
+// WithMaxReplicationLag makes PingContext skip readers which lag behind the primary by more than max or do
+// not replicate, see ReplicationLag, for the reader retry interval. The dialect must be DialectPostgres or
+// DialectMySQL.
+func WithMaxReplicationLag(dialect Dialect, max time.Duration) ConnectionOption {
+	return func(o *connectionOptions) {
+		o.lagDialect, o.maxLag = dialect, max
+	}
+}
+
+// WithConnectionLogger sets the logger reporting failed readers and writers, e.g. logx.FromLogrus(l).
+func WithConnectionLogger(l logx.Logger) ConnectionOption {
+	return func(o *connectionOptions) {
+		o.l = l
+	}
+}
+
+// NewConnection opens the writer and reader DSNs with the driver. The pool of each DSN is configured
+// by its query parameters, see ParsePoolOptions. Writer DSNs listing several PostgreSQL servers connect
+// to the primary by setting "target_session_attrs=read-write", unless the parameter is set already.
+func NewConnection(driverName, writerDSN string, readerDSNs []string, opts ...ConnectionOption) (*Connection, error) {
+	o := &connectionOptions{retryInterval: DefaultReaderRetryInterval}
+	for _, f := range opts {
+		f(o)
+	}
+	if o.l == nil {
+		o.l = logx.FromLogrus(logrusx.New("", ""))
+	}
+	if o.maxLag > 0 && o.lagDialect != DialectPostgres && o.lagDialect != DialectMySQL {
+		return nil, errors.WithStack(&UnsupportedError{Dialect: o.lagDialect, Feature: "replication lag"})
+	}
+
+	c := &Connection{retryInterval: o.retryInterval, lagDialect: o.lagDialect, maxLag: o.maxLag, l: o.l, now: time.Now}
+	open := func(dsn string) (*sql.DB, PoolOptions, error) {
+		pool, cleaned := ParsePoolOptions(logrusx.New("", ""), dsn)
+		db, err := sql.Open(driverName, cleaned)
+		if err != nil {
+			_ = c.Close()
+			return nil, pool, errors.Wrapf(err, "unable to open %s", RedactDSN(dsn))
+		}
+		pool.Apply(db)
+		return db, pool, nil
+	}
+
+	var err error
+	writerDSN, c.failover = failoverDSN(writerDSN)
+	if c.writer, c.writerPool, err = open(writerDSN); err != nil {
+		return nil, err
+	}
+	for _, dsn := range readerDSNs {
+		db, _, err := open(dsn)
+		if err != nil {
+			return nil, err
+		}
+		c.readers = append(c.readers, &reader{db: db})
+	}
+	return c, nil
+}
+
+// failoverDSN sets "target_session_attrs=read-write" for PostgreSQL DSNs with several hosts. It returns
+// false if the DSN has a single host.
+func failoverDSN(dsn string) (string, bool) {
+	d, err := ParseDSN(dsn)
+	if err != nil || d.Driver != "postgres" || len(d.Hosts()) < 2 {
+		return dsn, false
+	}
+	if d.Query.Get("target_session_attrs") == "" {
+		d.Query.Set("target_session_attrs", "read-write")
+	}
+	return d.String(), true
+}
+
+// reconnectWriter closes the idle connections of the writer, so that the next ones are opened to the new
+// primary. Connections in use are kept until they fail themselves.
+func (c *Connection) reconnectWriter(err error) {
+	c.failoverMu.Lock()
+	defer c.failoverMu.Unlock()
+
+	c.l.Warn("The SQL writer is not connected to the primary, reconnecting to the new primary.", "error", err)
+	c.writer.SetMaxIdleConns(0)
+	c.writer.SetMaxIdleConns(c.writerPool.MaxIdleConns)
+}
+
+// writerError handles the error of the writer and reconnects the writer if it indicates a failover.
+func (c *Connection) writerError(err error) error {
+	err = HandleError(err)
+	if c.failover && (errors.Is(err, ErrReadOnly) || errors.Is(err, ErrConnectionFailed)) {
+		c.reconnectWriter(err)
+	}
+	return err
+}
+
+// checkPrimary reconnects the writer if it is connected to a standby, e.g. to a demoted primary.
+func (c *Connection) checkPrimary(ctx context.Context) error {
+	for reconnected := false; ; reconnected = true {
+		var readOnly string
+		if err := c.writer.QueryRowContext(ctx, "SHOW transaction_read_only").Scan(&readOnly); err != nil {
+			return c.writerError(err)
+		}
+		if readOnly != "on" {
+			return nil
+		}
+		err := errors.WithStack(ErrReadOnly.WithReason("The SQL writer is connected to a standby."))
+		if reconnected {
+			return err
+		}
+		c.reconnectWriter(err)
+	}
+}
+
+// Writer returns the pool of the writer.
+func (c *Connection) Writer() *sql.DB {
+	return c.writer
+}
+
+func (r *reader) skipped(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return now.Before(r.skipUntil)
+}
+
+func (r *reader) skip(until time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.skipUntil = until
+}
+
+// healthyReaders returns the readers which are not skipped, starting with the next one in turn.
+func (c *Connection) healthyReaders() []*reader {
+	if len(c.readers) == 0 {
+		return nil
+	}
+
+	now := c.now()
+	start := int(atomic.AddUint32(&c.next, 1) % uint32(len(c.readers)))
+	healthy := make([]*reader, 0, len(c.readers))
+	for i := range c.readers {
+		if r := c.readers[(start+i)%len(c.readers)]; !r.skipped(now) {
+			healthy = append(healthy, r)
+		}
+	}
+	return healthy
+}
+
+// Reader returns the pool of the next healthy reader, or the writer if there is none.
+func (c *Connection) Reader() *sql.DB {
+	if readers := c.healthyReaders(); len(readers) > 0 {
+		return readers[0].db
+	}
+	return c.writer
+}
+
+func (c *Connection) skip(r *reader, err error) {
+	c.l.Warn("Unable to connect to SQL reader, skipping it.", "retry_interval", c.retryInterval, "error", err)
+	r.skip(c.now().Add(c.retryInterval))
+}
+
+// read runs fn with the healthy readers until one does not fail to connect, and then with the writer.
+func (c *Connection) read(fn func(db *sql.DB) error) error {
+	for _, r := range c.healthyReaders() {
+		err := HandleError(fn(r.db))
+		if !errors.Is(err, ErrConnectionFailed) {
+			return err
+		}
+		c.skip(r, err)
+	}
+	return HandleError(fn(c.writer))
+}
+
+// ReadQueryContext runs the query on a reader. The query may not see recent writes if the readers are replicas.
+func (c *Connection) ReadQueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = c.read(func(db *sql.DB) (err error) {
+		rows, err = db.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryContext runs the query on the writer.
+func (c *Connection) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := c.writer.QueryContext(ctx, query, args...)
+	return rows, c.writerError(err)
+}
+
+// ExecContext executes the statement on the writer.
+func (c *Connection) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := c.writer.ExecContext(ctx, query, args...)
+	return res, c.writerError(err)
+}
+
+// BeginTx starts a transaction on a reader if it is read-only, and on the writer otherwise.
+// It implements TxBeginner, so it can be used with WithTransaction and Retry.
+func (c *Connection) BeginTx(ctx context.Context, opts *sql.TxOptions) (tx *sql.Tx, err error) {
+	if opts == nil || !opts.ReadOnly {
+		tx, err = c.writer.BeginTx(ctx, opts)
+		return tx, c.writerError(err)
+	}
+
+	err = c.read(func(db *sql.DB) (err error) {
+		tx, err = db.BeginTx(ctx, opts)
+		return err
+	})
+	return tx, err
+}
+
+// PingContext pings the writer and all readers. Readers which fail to connect, or lag behind the primary by more
+// than WithMaxReplicationLag, are skipped, so only an error of the writer is returned. If the writer follows
+// failovers, it is also checked to be connected to the primary.
+func (c *Connection) PingContext(ctx context.Context) error {
+	for _, r := range c.readers {
+		if err := HandleError(r.db.PingContext(ctx)); errors.Is(err, ErrConnectionFailed) {
+			c.skip(r, err)
+		} else if err == nil && c.maxLag > 0 {
+			if err := checkReplicationLag(ctx, r.db, c.lagDialect, c.maxLag); errors.Is(err, ErrReplicationLag) {
+				c.l.Warn("The SQL reader lags behind the primary, skipping it.", "retry_interval", c.retryInterval, "error", err)
+				r.skip(c.now().Add(c.retryInterval))
+			}
+		}
+	}
+	if err := c.writer.PingContext(ctx); err != nil {
+		return c.writerError(err)
+	}
+	if c.failover {
+		return c.checkPrimary(ctx)
+	}
+	return nil
+}
+
+// Close closes the writer and all readers.
+func (c *Connection) Close() error {
+	var err error
+	if c.writer != nil {
+		err = c.writer.Close()
+	}
+	for _, r := range c.readers {
+		if rerr := r.db.Close(); err == nil {
+			err = rerr
+		}
+	}
+	return errors.WithStack(err)
+}
@@ -0,0 +1,295 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// fakeDriver serves the DSN as the only row of every query. DSNs set down fail to connect. The connections
+	// opened before a failover of their DSN are connected to a standby, which rejects writes. The replication
+	// lag queries return the lag set for the DSN, or 0.
+	fakeDriver struct {
+		mu        sync.Mutex
+		down      map[string]bool
+		failovers map[string]int
+		lags      map[string]driver.Value
+		execs     []string
+	}
+	fakeConn struct {
+		d        *fakeDriver
+		dsn      string
+		failover int
+	}
+	fakeTx   struct{}
+	fakeRows struct {
+		dsn     string
+		done    bool
+		columns []string
+		values  []driver.Value
+	}
+)
+
+var testDriver = &fakeDriver{down: map[string]bool{}, failovers: map[string]int{}, lags: map[string]driver.Value{}}
+
+func init() {
+	sql.Register("sqlcon-fake", testDriver)
+}
+
+func (d *fakeDriver) setDown(dsn string, down bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.down[dsn] = down
+}
+
+func (d *fakeDriver) isDown(dsn string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.down[dsn]
+}
+
+func (d *fakeDriver) failover(dsn string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.failovers[dsn]++
+}
+
+func (d *fakeDriver) setLag(dsn string, lag driver.Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.lags[dsn] = lag
+}
+
+func (d *fakeDriver) lag(dsn string) driver.Value {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if lag, ok := d.lags[dsn]; ok {
+		return lag
+	}
+	return int64(0)
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	if d.isDown(dsn) {
+		return nil, driver.ErrBadConn
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &fakeConn{d: d, dsn: dsn, failover: d.failovers[dsn]}, nil
+}
+
+func (c *fakeConn) standby() bool {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	return c.failover < c.d.failovers[c.dsn]
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	if c.d.isDown(c.dsn) {
+		return nil, driver.ErrBadConn
+	}
+	return fakeTx{}, nil
+}
+func (c *fakeConn) Ping(context.Context) error {
+	if c.d.isDown(c.dsn) {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if c.d.isDown(c.dsn) {
+		return nil, driver.ErrBadConn
+	} else if c.standby() {
+		return nil, &pgconnv5.PgError{Code: "25006", Message: "cannot execute INSERT in a read-only transaction"}
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.execs = append(c.d.execs, query)
+	return driver.RowsAffected(0), nil
+}
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if c.d.isDown(c.dsn) {
+		return nil, driver.ErrBadConn
+	} else if query == "SHOW transaction_read_only" {
+		readOnly := "off"
+		if c.standby() {
+			readOnly = "on"
+		}
+		return &fakeRows{dsn: readOnly}, nil
+	} else if query == postgresLagQuery {
+		return &fakeRows{columns: []string{"lag"}, values: []driver.Value{c.d.lag(c.dsn)}}, nil
+	} else if query == "SHOW REPLICA STATUS" {
+		return &fakeRows{columns: []string{"Replica_IO_State", "Seconds_Behind_Source"}, values: []driver.Value{"Waiting for source to send event", c.d.lag(c.dsn)}}, nil
+	}
+	return &fakeRows{dsn: c.dsn}, nil
+}
+func (fakeTx) Commit() error     { return nil }
+func (fakeTx) Rollback() error   { return nil }
+func (r *fakeRows) Close() error { return nil }
+func (r *fakeRows) Columns() []string {
+	if r.columns != nil {
+		return r.columns
+	}
+	return []string{"dsn"}
+}
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	if r.values != nil {
+		copy(dest, r.values)
+		return nil
+	}
+	dest[0] = r.dsn
+	return nil
+}
+
+func servedBy(t *testing.T, rows *sql.Rows, err error) string {
+	require.NoError(t, err)
+	defer rows.Close()
+	require.True(t, rows.Next())
+	var dsn string
+	require.NoError(t, rows.Scan(&dsn))
+	return dsn
+}
+
+func TestConnection(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewConnection("sqlcon-fake", "writer?max_conns=3", []string{"reader-a", "reader-b"}, WithReaderRetryInterval(time.Minute))
+	require.NoError(t, err)
+	defer c.Close()
+
+	assert.Equal(t, 3, c.Writer().Stats().MaxOpenConnections)
+
+	t.Run("case=routes by intent", func(t *testing.T) {
+		rows, err := c.QueryContext(ctx, "SELECT dsn")
+		assert.Equal(t, "writer?", servedBy(t, rows, err))
+
+		served := map[string]bool{}
+		for i := 0; i < 4; i++ {
+			rows, err := c.ReadQueryContext(ctx, "SELECT dsn")
+			served[servedBy(t, rows, err)] = true
+		}
+		assert.Equal(t, map[string]bool{"reader-a": true, "reader-b": true}, served)
+
+		tx, err := c.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		require.NoError(t, err)
+		rows, err = tx.QueryContext(ctx, "SELECT dsn")
+		assert.Contains(t, servedBy(t, rows, err), "reader-")
+		require.NoError(t, tx.Rollback())
+
+		tx, err = c.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		rows, err = tx.QueryContext(ctx, "SELECT dsn")
+		assert.Equal(t, "writer?", servedBy(t, rows, err))
+		require.NoError(t, tx.Rollback())
+	})
+
+	t.Run("case=fails over to the other reader and the writer", func(t *testing.T) {
+		now := time.Now()
+		c.now = func() time.Time { return now }
+		defer func() { c.now = time.Now }()
+
+		testDriver.setDown("reader-a", true)
+		defer testDriver.setDown("reader-a", false)
+		for i := 0; i < 4; i++ {
+			rows, err := c.ReadQueryContext(ctx, "SELECT dsn")
+			assert.Equal(t, "reader-b", servedBy(t, rows, err))
+		}
+
+		testDriver.setDown("reader-b", true)
+		defer testDriver.setDown("reader-b", false)
+		assert.NoError(t, c.PingContext(ctx))
+		rows, err := c.ReadQueryContext(ctx, "SELECT dsn")
+		assert.Equal(t, "writer?", servedBy(t, rows, err))
+		assert.Equal(t, c.Writer(), c.Reader())
+
+		// the readers are used again after the retry interval
+		testDriver.setDown("reader-a", false)
+		testDriver.setDown("reader-b", false)
+		c.now = func() time.Time { return now.Add(time.Minute) }
+		rows, err = c.ReadQueryContext(ctx, "SELECT dsn")
+		assert.Contains(t, servedBy(t, rows, err), "reader-")
+	})
+
+	t.Run("case=maps errors of the writer", func(t *testing.T) {
+		testDriver.setDown("writer?", true)
+		defer testDriver.setDown("writer?", false)
+
+		_, err := c.QueryContext(ctx, "SELECT dsn")
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+		assert.ErrorIs(t, c.PingContext(ctx), ErrConnectionFailed)
+	})
+}
+
+func TestConnectionWriterFailover(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewConnection("sqlcon-fake", "postgres://db-1:5432,db-2:5432/db", nil)
+	require.NoError(t, err)
+	defer c.Close()
+
+	const writer = "postgres://db-1:5432,db-2:5432/db?target_session_attrs=read-write"
+	rows, err := c.QueryContext(ctx, "SELECT dsn")
+	require.Equal(t, writer, servedBy(t, rows, err))
+
+	t.Run("case=reconnects after writes are rejected", func(t *testing.T) {
+		testDriver.failover(writer)
+		_, err := c.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = c.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=reconnects when pinged", func(t *testing.T) {
+		testDriver.failover(writer)
+		assert.NoError(t, c.PingContext(ctx))
+		_, err := c.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		assert.NoError(t, err)
+	})
+
+	t.Run("case=keeps the DSN of single hosts", func(t *testing.T) {
+		for _, dsn := range []string{"postgres://db-1:5432/db", "postgres://db-1:5432,db-2:5432/db?target_session_attrs=any"} {
+			actual, failover := failoverDSN(dsn)
+			assert.Equal(t, dsn, actual)
+			assert.Equal(t, strings.Contains(dsn, ","), failover, dsn)
+		}
+	})
+}
+
+func TestConnectionReplicationLag(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewConnection("sqlcon-fake", "writer", nil, WithMaxReplicationLag(DialectCockroach, time.Second))
+	var unsupported *UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+
+	c, err := NewConnection("sqlcon-fake", "lag-writer", []string{"lag-reader-a", "lag-reader-b"}, WithMaxReplicationLag(DialectPostgres, time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+
+	testDriver.setLag("lag-reader-a", 0.5)
+	testDriver.setLag("lag-reader-b", 30.0)
+	require.NoError(t, c.PingContext(ctx))
+	for i := 0; i < 4; i++ {
+		rows, err := c.ReadQueryContext(ctx, "SELECT dsn")
+		assert.Equal(t, "lag-reader-a", servedBy(t, rows, err))
+	}
+
+	testDriver.setLag("lag-reader-a", nil)
+	require.NoError(t, c.PingContext(ctx))
+	rows, err := c.ReadQueryContext(ctx, "SELECT dsn")
+	assert.Equal(t, "lag-writer", servedBy(t, rows, err))
+}
@@ -36,6 +36,7 @@ func cleanURLQuery(in url.Values) (out url.Values) {
 	out.Del("max_idle_conns")
 	out.Del("max_conn_lifetime")
 	out.Del("max_idle_conn_time")
+	out.Del("max_conn_idle_time")
 	out.Del("parseTime")
 	return out
 }
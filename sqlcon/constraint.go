@@ -0,0 +1,174 @@
+package sqlcon
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgconn"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+
+	"github.com/ory/herodot"
+)
+
+// ConstraintViolation is returned by HandleError for constraint violations. It matches the error class, e.g.
+// ErrForeignKeyViolation, using errors.Is, and carries the violated constraint as far as the driver reports it:
+//
+//	var violation *sqlcon.ConstraintViolation
+//	if errors.As(err, &violation) {
+//		fmt.Println(violation.Constraint(), violation.Table(), violation.Columns())
+//	}
+//
+// The constraint is not part of the error message or details, so it is not exposed to API clients.
+type ConstraintViolation struct {
+	*herodot.DefaultError
+
+	constraint string
+	table      string
+	columns    []string
+}
+
+// Constraint returns the name of the violated constraint or index, or an empty string if it is unknown.
+func (e *ConstraintViolation) Constraint() string {
+	return e.constraint
+}
+
+// Table returns the name of the table the statement operated on, or an empty string if it is unknown.
+func (e *ConstraintViolation) Table() string {
+	return e.table
+}
+
+// Columns returns the columns covered by the constraint, if known.
+func (e *ConstraintViolation) Columns() []string {
+	return e.columns
+}
+
+// IsUniqueViolationOf returns true if err is a unique violation of one of the constraints, so that tables with
+// several unique indexes can tell them apart:
+//
+//	if sqlcon.IsUniqueViolationOf(err, "identities_email_key") {
+//		return ErrEmailTaken
+//	}
+//
+// Without constraints, it returns true for any unique violation. The error is handled by HandleError if it was
+// not already. The constraint is the name of the index or constraint as reported by the database, e.g. "PRIMARY"
+// for primary keys in MySQL. SQLite does not report it, so its unique violations never match any constraint.
+func IsUniqueViolationOf(err error, constraints ...string) bool {
+	var violation *ConstraintViolation
+	if err == nil || !errors.As(HandleError(err), &violation) || !errors.Is(violation, ErrUniqueViolation) {
+		return false
+	}
+	if len(constraints) == 0 {
+		return true
+	}
+	for _, c := range constraints {
+		if c != "" && c == violation.constraint {
+			return true
+		}
+	}
+	return false
+}
+
+func constraintViolation(class *herodot.DefaultError, err error, constraint, table string, columns []string) error {
+	return errors.WithStack(&ConstraintViolation{
+		DefaultError: class.WithWrap(err),
+		constraint:   constraint,
+		table:        table,
+		columns:      columns,
+	})
+}
+
+// postgresKeyDetail matches details like `Key (parent_id)=(2) is not present in table "parents".`
+var postgresKeyDetail = regexp.MustCompile(`^Key \((.+?)\)=`)
+
+// postgresConstraint returns the constraint fields of PostgreSQL errors of lib/pq and pgx.
+func postgresConstraint(err error) (constraint, table string, columns []string) {
+	var detail, column string
+	if e := new(pq.Error); errors.As(err, &e) {
+		constraint, table, column, detail = e.Constraint, e.Table, e.Column, e.Detail
+	} else if e := new(pgconn.PgError); errors.As(err, &e) {
+		constraint, table, column, detail = e.ConstraintName, e.TableName, e.ColumnName, e.Detail
+	} else if e := new(pgconnv5.PgError); errors.As(err, &e) {
+		constraint, table, column, detail = e.ConstraintName, e.TableName, e.ColumnName, e.Detail
+	}
+
+	if column != "" {
+		columns = []string{column}
+	} else if m := postgresKeyDetail.FindStringSubmatch(detail); m != nil {
+		columns = splitColumns(m[1])
+	}
+	return constraint, table, columns
+}
+
+// mysqlForeignKey matches messages like "... a foreign key constraint fails (`db`.`children`, CONSTRAINT `children_ibfk_1`
+// FOREIGN KEY (`parent_id`) REFERENCES `parents` (`id`))"
+var mysqlForeignKey = regexp.MustCompile("`([^`]+)`, CONSTRAINT `([^`]+)` FOREIGN KEY \\(([^)]+)\\)")
+
+func mysqlForeignKeyConstraint(message string) (constraint, table string, columns []string) {
+	m := mysqlForeignKey.FindStringSubmatch(message)
+	if m == nil {
+		return "", "", nil
+	}
+	return m[2], m[1], splitColumns(m[3])
+}
+
+var (
+	// mysqlDuplicate matches messages like "Duplicate entry 'foo' for key 'users.email'" and, before MySQL 8,
+	// "Duplicate entry 'foo' for key 'email'"
+	mysqlDuplicate = regexp.MustCompile(`for key '(?:([^'.]+)\.)?([^'.]+)'$`)
+	// mysqlColumn matches messages like "Column 'name' cannot be null" and "Field 'name' doesn't have a default value"
+	mysqlColumn = regexp.MustCompile(`^(?:Column|Field) '([^']+)'`)
+	// mysqlCheck matches messages like "Check constraint 'name' is violated." and MariaDB's "CONSTRAINT `name` failed for ..."
+	mysqlCheck = regexp.MustCompile("^(?:Check constraint '([^']+)'|CONSTRAINT `([^`]+)`)")
+)
+
+func mysqlDuplicateKey(message string) (constraint, table string) {
+	if m := mysqlDuplicate.FindStringSubmatch(message); m != nil {
+		return m[2], m[1]
+	}
+	return "", ""
+}
+
+func mysqlColumns(message string) []string {
+	if m := mysqlColumn.FindStringSubmatch(message); m != nil {
+		return []string{m[1]}
+	}
+	return nil
+}
+
+func mysqlCheckConstraint(message string) string {
+	if m := mysqlCheck.FindStringSubmatch(message); m != nil {
+		return m[1] + m[2]
+	}
+	return ""
+}
+
+var (
+	// sqliteColumn matches the columns of messages like "UNIQUE constraint failed: table.a, table.b"
+	sqliteColumn = regexp.MustCompile(`(?:constraint failed: |, )(\w+)\.(\w+)`)
+	// sqliteCheck matches messages like "CHECK constraint failed: name"
+	sqliteCheck = regexp.MustCompile(`CHECK constraint failed: (\w+)`)
+)
+
+func sqliteColumns(message string) (table string, columns []string) {
+	for _, m := range sqliteColumn.FindAllStringSubmatch(message, -1) {
+		table, columns = m[1], append(columns, m[2])
+	}
+	return table, columns
+}
+
+func sqliteCheckConstraint(message string) string {
+	if m := sqliteCheck.FindStringSubmatch(message); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func splitColumns(list string) []string {
+	columns := strings.Split(list, ",")
+	for i, c := range columns {
+		columns[i] = strings.Trim(strings.TrimSpace(c), "`\"")
+	}
+	return columns
+}
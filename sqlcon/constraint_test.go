@@ -0,0 +1,124 @@
+package sqlcon
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintViolation(t *testing.T) {
+	assertViolation := func(t *testing.T, err error, class error, constraint, table string, columns []string) {
+		require.ErrorIs(t, err, class)
+		var violation *ConstraintViolation
+		require.ErrorAs(t, err, &violation)
+		assert.Equal(t, constraint, violation.Constraint())
+		assert.Equal(t, table, violation.Table())
+		assert.Equal(t, columns, violation.Columns())
+	}
+
+	t.Run("case=postgres foreign key", func(t *testing.T) {
+		const detail = `Key (parent_id)=(2) is not present in table "parents".`
+		for name, err := range map[string]error{
+			"pgx v5": &pgconnv5.PgError{Code: "23503", ConstraintName: "children_parent_id_fkey", TableName: "children", Detail: detail},
+			"pgx v4": &pgconn.PgError{Code: "23503", ConstraintName: "children_parent_id_fkey", TableName: "children", Detail: detail},
+			"lib/pq": &pq.Error{Code: "23503", Constraint: "children_parent_id_fkey", Table: "children", Detail: detail},
+		} {
+			t.Run("driver="+name, func(t *testing.T) {
+				handled := HandleError(err)
+				assertViolation(t, handled, ErrForeignKeyViolation, "children_parent_id_fkey", "children", []string{"parent_id"})
+				assert.ErrorIs(t, handled, err)
+			})
+		}
+	})
+
+	t.Run("case=mysql foreign key", func(t *testing.T) {
+		err := HandleError(&mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`test`.`children`, CONSTRAINT `children_ibfk_1` FOREIGN KEY (`tenant_id`, `parent_id`) REFERENCES `parents` (`tenant_id`, `id`))"})
+		assertViolation(t, err, ErrForeignKeyViolation, "children_ibfk_1", "children", []string{"tenant_id", "parent_id"})
+
+		err = HandleError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row: a foreign key constraint fails"})
+		assertViolation(t, err, ErrForeignKeyViolation, "", "", nil)
+	})
+
+	t.Run("case=postgres not null and check", func(t *testing.T) {
+		err := HandleError(&pgconnv5.PgError{Code: "23502", TableName: "users", ColumnName: "email"})
+		assertViolation(t, err, ErrNotNullViolation, "", "users", []string{"email"})
+
+		err = HandleError(&pq.Error{Code: "23514", Table: "users", Constraint: "users_age_check"})
+		assertViolation(t, err, ErrCheckViolation, "users_age_check", "users", nil)
+	})
+
+	t.Run("case=mysql not null and check", func(t *testing.T) {
+		err := HandleError(&mysql.MySQLError{Number: 1048, Message: "Column 'email' cannot be null"})
+		assertViolation(t, err, ErrNotNullViolation, "", "", []string{"email"})
+
+		err = HandleError(&mysql.MySQLError{Number: 1364, Message: "Field 'email' doesn't have a default value"})
+		assertViolation(t, err, ErrNotNullViolation, "", "", []string{"email"})
+
+		err = HandleError(&mysql.MySQLError{Number: 3819, Message: "Check constraint 'users_chk_1' is violated."})
+		assertViolation(t, err, ErrCheckViolation, "users_chk_1", "", nil)
+
+		err = HandleError(&mysql.MySQLError{Number: 4025, Message: "CONSTRAINT `age` failed for `test`.`users`"})
+		assertViolation(t, err, ErrCheckViolation, "age", "", nil)
+	})
+
+	t.Run("case=postgres unique", func(t *testing.T) {
+		err := HandleError(&pgconnv5.PgError{Code: "23505", ConstraintName: "users_tenant_email_key", TableName: "users", Detail: "Key (tenant_id, email)=(1, foo@bar.com) already exists."})
+		assertViolation(t, err, ErrUniqueViolation, "users_tenant_email_key", "users", []string{"tenant_id", "email"})
+	})
+
+	t.Run("case=mysql unique", func(t *testing.T) {
+		err := HandleError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'foo@bar.com' for key 'users.email_idx'"})
+		assertViolation(t, err, ErrUniqueViolation, "email_idx", "users", nil)
+
+		err = HandleError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'foo@bar.com' for key 'email_idx'"})
+		assertViolation(t, err, ErrUniqueViolation, "email_idx", "", nil)
+	})
+
+	t.Run("case=is not exposed in the message", func(t *testing.T) {
+		err := HandleError(&pgconnv5.PgError{Code: "23503", ConstraintName: "children_parent_id_fkey"})
+		assert.Equal(t, ErrForeignKeyViolation.ErrorField, err.Error())
+		assert.False(t, errors.Is(err, ErrUniqueViolation))
+	})
+}
+
+func TestIsUniqueViolationOf(t *testing.T) {
+	email := &pgconnv5.PgError{Code: "23505", ConstraintName: "identities_email_key", TableName: "identities"}
+	for name, err := range map[string]error{
+		"driver error":  email,
+		"handled error": HandleError(email),
+		"wrapped error": fmt.Errorf("unable to create identity: %w", HandleError(email)),
+	} {
+		t.Run("case="+name, func(t *testing.T) {
+			assert.True(t, IsUniqueViolationOf(err))
+			assert.True(t, IsUniqueViolationOf(err, "identities_email_key"))
+			assert.True(t, IsUniqueViolationOf(err, "identities_username_key", "identities_email_key"))
+			assert.False(t, IsUniqueViolationOf(err, "identities_username_key"))
+		})
+	}
+
+	t.Run("case=mysql", func(t *testing.T) {
+		err := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'identities.PRIMARY'"}
+		assert.True(t, IsUniqueViolationOf(err, "PRIMARY"))
+		assert.False(t, IsUniqueViolationOf(err, "identities_email_key"))
+	})
+
+	t.Run("case=unknown constraint", func(t *testing.T) {
+		err := &pgconnv5.PgError{Code: "23505"}
+		assert.True(t, IsUniqueViolationOf(err))
+		assert.False(t, IsUniqueViolationOf(err, ""))
+		assert.False(t, IsUniqueViolationOf(err, "identities_email_key"))
+	})
+
+	t.Run("case=other errors", func(t *testing.T) {
+		assert.False(t, IsUniqueViolationOf(nil))
+		assert.False(t, IsUniqueViolationOf(errors.New("foo")))
+		assert.False(t, IsUniqueViolationOf(&pgconnv5.PgError{Code: "23503", ConstraintName: "identities_email_key"}, "identities_email_key"))
+	})
+}
@@ -0,0 +1,163 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Dialect is a SQL dialect, named like DSN.Driver.
+type Dialect string
+
+const (
+	DialectPostgres  Dialect = "postgres"
+	DialectCockroach Dialect = "cockroach"
+	DialectMySQL     Dialect = "mysql"
+	DialectSQLite    Dialect = "sqlite"
+	DialectSQLServer Dialect = "sqlserver"
+)
+
+// Capabilities are the features supported by a dialect.
+type Capabilities struct {
+	// Returning is true if INSERT, UPDATE and DELETE statements support RETURNING clauses.
+	Returning bool
+	// Savepoints is true if transactions support SAVEPOINT.
+	Savepoints bool
+	// SkipLocked is true if SELECT ... FOR UPDATE supports SKIP LOCKED. The SkipLocked builder also supports SQL Server.
+	SkipLocked bool
+	// Upsert is true if the dialect is supported by Upsert.
+	Upsert bool
+	// NamedLocks is true if the dialect is supported by AcquireLock.
+	NamedLocks bool
+	// ReadOnlyTransactions is true if the drivers of the dialect support read-only transactions, see Dialect.TxOptions.
+	ReadOnlyTransactions bool
+}
+
+var (
+	// capabilities assumes at least PostgreSQL 9.5, CockroachDB 22.2, MySQL 8, SQLite 3.35 and SQL Server 2016
+	capabilities = map[Dialect]Capabilities{
+		DialectPostgres:  {Returning: true, Savepoints: true, SkipLocked: true, Upsert: true, NamedLocks: true, ReadOnlyTransactions: true},
+		DialectCockroach: {Returning: true, Savepoints: true, SkipLocked: true, Upsert: true, ReadOnlyTransactions: true},
+		DialectMySQL:     {Savepoints: true, SkipLocked: true, Upsert: true, NamedLocks: true, ReadOnlyTransactions: true},
+		DialectSQLite:    {Returning: true, Savepoints: true, Upsert: true, NamedLocks: true},
+		DialectSQLServer: {Savepoints: true},
+	}
+	// driverPackages maps the packages of the known drivers to their dialect.
+	driverPackages = map[string]Dialect{
+		"github.com/lib/pq":                DialectPostgres,
+		"github.com/jackc/pgx/v4/stdlib":   DialectPostgres,
+		"github.com/jackc/pgx/v5/stdlib":   DialectPostgres,
+		"github.com/go-sql-driver/mysql":   DialectMySQL,
+		"github.com/mattn/go-sqlite3":      DialectSQLite,
+		"modernc.org/sqlite":               DialectSQLite,
+		"github.com/microsoft/go-mssqldb":  DialectSQLServer,
+		"github.com/denisenkom/go-mssqldb": DialectSQLServer,
+	}
+	// versionQueries identify the dialect of unknown, e.g. instrumented, drivers.
+	versionQueries = []struct {
+		query   string
+		dialect func(version string) Dialect
+	}{
+		{query: "SELECT sqlite_version()", dialect: func(string) Dialect { return DialectSQLite }},
+		{query: "SELECT @@VERSION", dialect: func(version string) Dialect {
+			if strings.Contains(version, "Microsoft SQL Server") {
+				return DialectSQLServer
+			}
+			return DialectMySQL
+		}},
+		{query: "SELECT version()", dialect: postgresDialect},
+	}
+)
+
+// ParseDialect returns the dialect of the name, which may be an alias like the DSN schemes of ParseDSN.
+func ParseDialect(name string) (Dialect, error) {
+	if alias, ok := driverAliases[name]; ok {
+		name = alias
+	}
+	if d := Dialect(name); d.Valid() {
+		return d, nil
+	}
+	return "", errors.Errorf("unknown dialect %q", name)
+}
+
+// Valid returns true for the dialects known to sqlcon.
+func (d Dialect) Valid() bool {
+	_, ok := capabilities[d]
+	return ok
+}
+
+// Capabilities returns the features supported by the dialect, which are all false for unknown dialects.
+func (d Dialect) Capabilities() Capabilities {
+	return capabilities[d]
+}
+
+func (d Dialect) String() string {
+	return string(d)
+}
+
+// Placeholder returns the n-th bind parameter of a statement, starting at 1, e.g. "$1" for PostgreSQL and "?" for MySQL.
+func (d Dialect) Placeholder(n int) string {
+	switch d {
+	case DialectPostgres, DialectCockroach:
+		return fmt.Sprintf("$%d", n)
+	case DialectSQLServer:
+		return fmt.Sprintf("@p%d", n)
+	}
+	return "?"
+}
+
+// MaxParameters returns the maximum number of bind parameters of a statement, or 0 for unknown dialects.
+func (d Dialect) MaxParameters() int {
+	switch d {
+	case DialectPostgres, DialectCockroach, DialectMySQL:
+		return 65535
+	case DialectSQLite:
+		// SQLITE_MAX_VARIABLE_NUMBER since SQLite 3.32
+		return 32766
+	case DialectSQLServer:
+		// 2100 parameters, of which sp_executesql uses two for the statement and the parameter definitions
+		return 2098
+	}
+	return 0
+}
+
+func postgresDialect(version string) Dialect {
+	if strings.Contains(version, "CockroachDB") {
+		return DialectCockroach
+	}
+	return DialectPostgres
+}
+
+// DialectOf detects the dialect of the database. It is derived from the driver, but PostgreSQL and CockroachDB
+// share the drivers, so their version is queried. For unknown drivers, e.g. wrapped ones, the version functions
+// of the dialects are queried until one succeeds.
+func DialectOf(ctx context.Context, db *sql.DB) (Dialect, error) {
+	t := reflect.TypeOf(db.Driver())
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var version string
+	switch d, ok := driverPackages[t.PkgPath()]; {
+	case ok && d == DialectPostgres:
+		if err := db.QueryRowContext(ctx, "SELECT version()").Scan(&version); err != nil {
+			return "", HandleError(err)
+		}
+		return postgresDialect(version), nil
+	case ok:
+		return d, nil
+	}
+
+	for _, q := range versionQueries {
+		if err := db.QueryRowContext(ctx, q.query).Scan(&version); err == nil {
+			return q.dialect(version), nil
+		} else if errors.Is(HandleError(err), ErrConnectionFailed) {
+			return "", HandleError(err)
+		}
+	}
+	return "", errors.Errorf("unable to detect the dialect of the driver %s", t)
+}
@@ -0,0 +1,54 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialect(t *testing.T) {
+	t.Run("case=parse", func(t *testing.T) {
+		for name, expected := range map[string]Dialect{
+			"postgres":    DialectPostgres,
+			"postgresql":  DialectPostgres,
+			"cockroachdb": DialectCockroach,
+			"mysql":       DialectMySQL,
+			"sqlite3":     DialectSQLite,
+			"mssql":       DialectSQLServer,
+		} {
+			actual, err := ParseDialect(name)
+			require.NoError(t, err, name)
+			assert.Equal(t, expected, actual, name)
+		}
+
+		_, err := ParseDialect("oracle")
+		assert.Error(t, err)
+	})
+
+	t.Run("case=capabilities", func(t *testing.T) {
+		assert.True(t, DialectPostgres.Capabilities().Returning)
+		assert.False(t, DialectMySQL.Capabilities().Returning)
+		assert.False(t, DialectSQLite.Capabilities().SkipLocked)
+		assert.False(t, DialectCockroach.Capabilities().NamedLocks)
+		assert.Equal(t, Capabilities{}, Dialect("oracle").Capabilities())
+	})
+
+	t.Run("case=postgres versions", func(t *testing.T) {
+		assert.Equal(t, DialectPostgres, postgresDialect("PostgreSQL 14.5 on x86_64-pc-linux-musl"))
+		assert.Equal(t, DialectCockroach, postgresDialect("CockroachDB CCL v22.2.0 (x86_64-pc-linux-gnu)"))
+	})
+
+	t.Run("case=unknown drivers are queried", func(t *testing.T) {
+		// the fake driver answers every query, so the first version query succeeds
+		db, err := sql.Open("sqlcon-fake", "3.39.2")
+		require.NoError(t, err)
+		defer db.Close()
+
+		d, err := DialectOf(context.Background(), db)
+		require.NoError(t, err)
+		assert.Equal(t, DialectSQLite, d)
+	})
+}
@@ -0,0 +1,287 @@
+package dockertest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"net"
+	"regexp"
+	"sync"
+	"syscall"
+	"testing"
+
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// The operations faults are injected into.
+const (
+	FaultExec   FaultOp = "exec"
+	FaultQuery  FaultOp = "query"
+	FaultBegin  FaultOp = "begin"
+	FaultCommit FaultOp = "commit"
+)
+
+type (
+	// FaultOp is an operation of a connection.
+	FaultOp string
+	// Fault fails the matching operations with an error, e.g. the second exec with a deadlock.
+	Fault struct {
+		// Op is the operation which fails. If it is empty, execs and queries fail.
+		Op FaultOp
+		// Statement selects the execs and queries which fail. It is optional.
+		Statement *regexp.Regexp
+		// Nth is the matching call which fails, counting from 1. If it is 0, all matching calls fail.
+		Nth int
+		// Err is returned by the failing call. It is returned as is, so use the classified driver errors, e.g.
+		// InjectedDeadlock, to exercise code built on the error classes of sqlcon.
+		Err error
+
+		calls int
+	}
+	// Faults injects faults into the connections of a database opened by OpenWithFaults. It is safe for
+	// concurrent use.
+	Faults struct {
+		mu     sync.Mutex
+		faults []*Fault
+	}
+	faultConnector struct {
+		driver driver.Driver
+		dsn    string
+		faults *Faults
+	}
+	faultConn struct {
+		driver.Conn
+		faults *Faults
+	}
+	faultStmt struct {
+		driver.Stmt
+		query  string
+		faults *Faults
+	}
+	faultTx struct {
+		driver.Tx
+		faults *Faults
+	}
+)
+
+// InjectedDeadlock returns the error of PostgreSQL for a deadlock, which sqlcon classifies as ErrDeadlock.
+func InjectedDeadlock() error {
+	return &pgconnv5.PgError{Severity: "ERROR", Code: "40P01", Message: "deadlock detected (injected)"}
+}
+
+// InjectedSerializationFailure returns the error of PostgreSQL for a serialization failure, which sqlcon
+// classifies as ErrSerializationFailure.
+func InjectedSerializationFailure() error {
+	return &pgconnv5.PgError{Severity: "ERROR", Code: "40001", Message: "could not serialize access due to concurrent update (injected)"}
+}
+
+// InjectedLockTimeout returns the error of PostgreSQL for a lock which is not available, which sqlcon
+// classifies as ErrLockTimeout.
+func InjectedLockTimeout() error {
+	return &pgconnv5.PgError{Severity: "ERROR", Code: "55P03", Message: "could not obtain lock (injected)"}
+}
+
+// InjectedUniqueViolation returns the error of PostgreSQL for a violation of the unique constraint, which
+// sqlcon classifies as ErrUniqueViolation.
+func InjectedUniqueViolation(constraint string) error {
+	return &pgconnv5.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        "duplicate key value violates unique constraint \"" + constraint + "\" (injected)",
+		ConstraintName: constraint,
+	}
+}
+
+// InjectedConnectionFailure returns a refused connection, which sqlcon classifies as ErrConnectionFailed.
+func InjectedConnectionFailure() error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+}
+
+// OpenWithFaults opens the DSN with the driver, which must be registered, and returns the faults injected into
+// its connections. The database is closed when the test finishes.
+//
+//	db, faults := dockertest.OpenWithFaults(t, "sqlite3", dockertest.NewTestSQLiteDSN(t, "sqlite3"))
+//	faults.Inject(&dockertest.Fault{Op: dockertest.FaultExec, Nth: 1, Err: dockertest.InjectedDeadlock()})
+//	require.NoError(t, sqlcon.Retry(ctx, db, fn)) // the first attempt fails, the second one succeeds
+func OpenWithFaults(t testing.TB, driverName, dsn string) (*sql.DB, *Faults) {
+	registered, err := sql.Open(driverName, dsn)
+	require.NoError(t, err)
+	d := registered.Driver()
+	require.NoError(t, registered.Close())
+
+	faults := new(Faults)
+	db := sql.OpenDB(&faultConnector{driver: d, dsn: dsn, faults: faults})
+	t.Cleanup(func() { _ = db.Close() })
+	return db, faults
+}
+
+// Inject adds the faults. The first matching fault of a call wins.
+func (f *Faults) Inject(faults ...*Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = append(f.faults, faults...)
+}
+
+// Reset removes all faults.
+func (f *Faults) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.faults = nil
+}
+
+// fail returns the error of the first fault which fails the call, counting the call for all matching faults.
+func (f *Faults) fail(op FaultOp, query string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var err error
+	for _, fault := range f.faults {
+		if !fault.matches(op, query) {
+			continue
+		}
+		fault.calls++
+		if err == nil && (fault.Nth == 0 || fault.Nth == fault.calls) {
+			err = fault.Err
+		}
+	}
+	return err
+}
+
+func (f *Fault) matches(op FaultOp, query string) bool {
+	if f.Op == "" && op != FaultExec && op != FaultQuery || f.Op != "" && f.Op != op {
+		return false
+	}
+	return f.Statement == nil || f.Statement.MatchString(query)
+}
+
+func (c *faultConnector) Connect(context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &faultConn{Conn: conn, faults: c.faults}, nil
+}
+
+func (c *faultConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+func (c *faultConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &faultStmt{Stmt: stmt, query: query, faults: c.faults}, nil
+}
+
+func (c *faultConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *faultConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql prepares the statement instead, whose exec is injected
+		return nil, driver.ErrSkip
+	}
+	if err := c.faults.fail(FaultExec, query); err != nil {
+		return nil, err
+	}
+	return e.ExecContext(ctx, query, args)
+}
+
+func (c *faultConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		// database/sql prepares the statement instead, whose query is injected
+		return nil, driver.ErrSkip
+	}
+	if err := c.faults.fail(FaultQuery, query); err != nil {
+		return nil, err
+	}
+	return q.QueryContext(ctx, query, args)
+}
+
+func (c *faultConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.faults.fail(FaultBegin, ""); err != nil {
+		return nil, err
+	}
+
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else {
+		//nolint:staticcheck // the fallback for drivers without BeginTx
+		tx, err = c.Conn.Begin()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &faultTx{Tx: tx, faults: c.faults}, nil
+}
+
+func (c *faultConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (t *faultTx) Commit() error {
+	if err := t.faults.fail(FaultCommit, ""); err != nil {
+		// the transaction must not stay open, as the connection is reused
+		_ = t.Tx.Rollback()
+		return err
+	}
+	return t.Tx.Commit()
+}
+
+func (s *faultStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.faults.fail(FaultExec, s.query); err != nil {
+		return nil, err
+	}
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		return e.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:staticcheck // the fallback for drivers without ExecContext
+	return s.Stmt.Exec(values)
+}
+
+func (s *faultStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.faults.fail(FaultQuery, s.query); err != nil {
+		return nil, err
+	}
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return q.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:staticcheck // the fallback for drivers without QueryContext
+	return s.Stmt.Query(values)
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("the driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,87 @@
+//go:build sqlite
+// +build sqlite
+
+package dockertest
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+)
+
+func TestOpenWithFaults(t *testing.T) {
+	ctx := context.Background()
+	db, faults := OpenWithFaults(t, "sqlite3", NewTestSQLiteDSN(t, "sqlite3"))
+	_, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	insert := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		return err
+	}
+	count := func() (n int) {
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n))
+		return n
+	}
+
+	t.Run("case=retries an injected deadlock", func(t *testing.T) {
+		t.Cleanup(faults.Reset)
+		faults.Inject(&Fault{Op: FaultExec, Nth: 1, Err: InjectedDeadlock()})
+
+		var attempts int
+		require.NoError(t, sqlcon.Retry(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			return insert(ctx, tx)
+		}, sqlcon.WithBackoff(time.Millisecond, time.Millisecond)))
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("case=fails the matching statements", func(t *testing.T) {
+		t.Cleanup(faults.Reset)
+		faults.Inject(&Fault{Statement: regexp.MustCompile(`^INSERT INTO items`), Err: InjectedUniqueViolation("items_pkey")})
+
+		_, err := db.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		assert.ErrorIs(t, sqlcon.HandleError(err), sqlcon.ErrUniqueViolation)
+		var e *sqlcon.ConstraintViolation
+		require.ErrorAs(t, sqlcon.HandleError(err), &e)
+		assert.Equal(t, "items_pkey", e.Constraint())
+
+		stmt, err := db.PrepareContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		require.NoError(t, err)
+		defer stmt.Close()
+		_, err = stmt.ExecContext(ctx)
+		assert.ErrorIs(t, sqlcon.HandleError(err), sqlcon.ErrUniqueViolation, "prepared statements fail as well")
+
+		assert.Equal(t, 1, count(), "other statements succeed")
+	})
+
+	t.Run("case=fails commits", func(t *testing.T) {
+		t.Cleanup(faults.Reset)
+		faults.Inject(&Fault{Op: FaultCommit, Nth: 1, Err: InjectedSerializationFailure()})
+
+		var attempts int
+		require.NoError(t, sqlcon.Retry(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			return insert(ctx, tx)
+		}, sqlcon.WithBackoff(time.Millisecond, time.Millisecond)))
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 2, count(), "the failed commit was rolled back")
+	})
+
+	t.Run("case=fails to begin", func(t *testing.T) {
+		t.Cleanup(faults.Reset)
+		faults.Inject(&Fault{Op: FaultBegin, Err: InjectedConnectionFailure()})
+
+		err := sqlcon.WithTransaction(ctx, db, nil, insert)
+		assert.ErrorIs(t, err, sqlcon.ErrConnectionFailed)
+	})
+}
@@ -0,0 +1,49 @@
+package dockertest
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sqliteDatabases    uint64
+	sqliteUnsafeInName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+)
+
+// NewTestSQLiteDSN returns the DSN of an in-memory SQLite database which is unique to the test. Unlike
+// ":memory:", which opens a new, empty database for every connection of the pool, all connections opened with
+// the DSN share the database. The driver is either "sqlite3" (github.com/mattn/go-sqlite3) or "sqlite"
+// (modernc.org/sqlite), which differ in how foreign keys are enabled.
+func NewTestSQLiteDSN(t testing.TB, driverName string) string {
+	name := fmt.Sprintf("%s-%d", sqliteUnsafeInName.ReplaceAllString(t.Name(), "_"), atomic.AddUint64(&sqliteDatabases, 1))
+	foreignKeys := "_fk=true"
+	if driverName != "sqlite3" {
+		foreignKeys = "_pragma=foreign_keys(1)"
+	}
+	return fmt.Sprintf("file:%s?mode=memory&cache=shared&%s", name, foreignKeys)
+}
+
+// OpenTestSQLite opens an in-memory SQLite database which is unique to the test, see NewTestSQLiteDSN, and
+// applies the migrations if set. The database is closed, and thereby deleted, when the test finishes. Do not
+// disable idle connections of the pool, as the database is deleted when its last connection is closed.
+// The driver must be registered, e.g. by importing github.com/mattn/go-sqlite3 in the test.
+func OpenTestSQLite(t testing.TB, driverName string, migrate func(db *sql.DB) error) *sql.DB {
+	db, err := sql.Open(driverName, NewTestSQLiteDSN(t, driverName))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	// the database is deleted when its last connection is closed, so idle connections must not expire
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	require.NoError(t, db.Ping())
+
+	if migrate != nil {
+		require.NoError(t, migrate(db))
+	}
+	return db
+}
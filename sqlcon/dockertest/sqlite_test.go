@@ -0,0 +1,53 @@
+//go:build sqlite
+// +build sqlite
+
+package dockertest
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlcon"
+)
+
+func migrateTestSQLite(db *sql.DB) error {
+	_, err := db.Exec(`CREATE TABLE parents (id INTEGER PRIMARY KEY);
+CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL REFERENCES parents(id))`)
+	return err
+}
+
+func TestOpenTestSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := OpenTestSQLite(t, "sqlite3", migrateTestSQLite)
+
+	t.Run("case=connections share the database", func(t *testing.T) {
+		first, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer first.Close()
+		second, err := db.Conn(ctx)
+		require.NoError(t, err)
+		defer second.Close()
+
+		_, err = first.ExecContext(ctx, "INSERT INTO parents (id) VALUES (1)")
+		require.NoError(t, err)
+		var count int
+		require.NoError(t, second.QueryRowContext(ctx, "SELECT COUNT(*) FROM parents").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("case=foreign keys are enforced", func(t *testing.T) {
+		_, err := db.Exec("INSERT INTO children (id, parent_id) VALUES (1, 2)")
+		assert.ErrorIs(t, sqlcon.HandleError(err), sqlcon.ErrForeignKeyViolation)
+	})
+
+	t.Run("case=tests are isolated", func(t *testing.T) {
+		other := OpenTestSQLite(t, "sqlite3", nil)
+		_, err := other.Exec("SELECT * FROM parents")
+		assert.ErrorIs(t, sqlcon.HandleError(err), sqlcon.ErrNoSuchTable)
+	})
+}
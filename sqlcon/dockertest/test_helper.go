@@ -24,6 +24,7 @@ import (
 	dc "github.com/ory/dockertest/v3/docker"
 	"github.com/ory/x/logrusx"
 	"github.com/ory/x/resilience"
+	"github.com/ory/x/sqlcon"
 	"github.com/ory/x/stringsx"
 )
 
@@ -119,7 +120,7 @@ func connect(dialect, driver, dsn string) (db *sqlx.DB, err error) {
 		},
 	)
 	if err != nil {
-		return nil, errors.Errorf("Unable to connect to %s (%s): %s", driver, dsn, err)
+		return nil, errors.Errorf("Unable to connect to %s (%s): %s", driver, sqlcon.RedactDSN(dsn), err)
 	}
 	log.Printf("Connected to database %s", driver)
 	return db, nil
@@ -1,13 +1,19 @@
 package sqlcon
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"net"
 	"net/http"
+	"net/url"
 
 	"google.golang.org/grpc/codes"
 
-	"github.com/go-sql-driver/mysql"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	pgxv5 "github.com/jackc/pgx/v5"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
@@ -22,7 +28,8 @@ var (
 		StatusField:   http.StatusText(http.StatusConflict),
 		ErrorField:    "Unable to insert or update resource because a resource with that value exists already",
 	}
-	// ErrNoRows is returned when a SQL SELECT statement returns no rows.
+	// ErrNoRows is returned when a SQL SELECT statement returns no rows. HandleError wraps sql.ErrNoRows and
+	// the ErrNoRows of pgx with it, so that it is the only error to check for.
 	ErrNoRows = &herodot.DefaultError{
 		CodeField:     http.StatusNotFound,
 		GRPCCodeField: codes.NotFound,
@@ -36,59 +43,312 @@ var (
 		StatusField:   http.StatusText(http.StatusBadRequest),
 		ErrorField:    "Unable to serialize access due to a concurrent update in another session",
 	}
+	// ErrSerializationFailure is returned when a transaction can not be serialized with concurrent transactions,
+	// e.g. CockroachDB's retryable errors. It wraps ErrConcurrentUpdate.
+	ErrSerializationFailure = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.Aborted,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to serialize the transaction with concurrent transactions",
+	}
+	// ErrDeadlock is returned when a transaction was aborted to resolve a deadlock with concurrent transactions.
+	// It wraps ErrConcurrentUpdate.
+	ErrDeadlock = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.Aborted,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to complete the transaction because of a deadlock with a concurrent transaction",
+	}
+	// ErrAmbiguousResult is returned when the database is unable to tell whether a statement or commit succeeded,
+	// e.g. CockroachDB's "result is ambiguous" errors. The write may or may not have been applied, so it is not
+	// retried; reconcile the state instead, e.g. by reading it back or using idempotent writes.
+	ErrAmbiguousResult = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.Unknown,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "Unable to determine whether the database operation was applied",
+	}
 	ErrNoSuchTable = &herodot.DefaultError{
 		CodeField:     http.StatusInternalServerError,
 		GRPCCodeField: codes.Internal,
 		StatusField:   http.StatusText(http.StatusInternalServerError),
 		ErrorField:    "Unable to locate the table",
 	}
+	// ErrNoSuchColumn is returned when a statement references a column which does not exist, e.g. because
+	// the schema is not migrated.
+	ErrNoSuchColumn = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.Internal,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "Unable to locate the column",
+	}
+	// ErrValueTooLong is returned when a SQL INSERT / UPDATE command sets a value which exceeds the size of
+	// its column, e.g. a string longer than a VARCHAR.
+	ErrValueTooLong = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.InvalidArgument,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to insert or update resource because a value is too long",
+	}
+	// ErrNumericOverflow is returned when a number is out of the range of its column or an arithmetic
+	// operation overflows.
+	ErrNumericOverflow = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.InvalidArgument,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to insert or update resource because a number is out of range",
+	}
+	// ErrForeignKeyViolation is returned when a SQL INSERT / UPDATE / DELETE command violates a foreign key constraint.
+	ErrForeignKeyViolation = &herodot.DefaultError{
+		CodeField:     http.StatusConflict,
+		GRPCCodeField: codes.FailedPrecondition,
+		StatusField:   http.StatusText(http.StatusConflict),
+		ErrorField:    "Unable to insert, update or delete resource because a related resource does not exist or still references it",
+	}
+	// ErrNotNullViolation is returned when a SQL INSERT / UPDATE command sets a NOT NULL column to NULL.
+	ErrNotNullViolation = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.InvalidArgument,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to insert or update resource because a required value is missing",
+	}
+	// ErrCheckViolation is returned when a SQL INSERT / UPDATE command violates a CHECK constraint.
+	ErrCheckViolation = &herodot.DefaultError{
+		CodeField:     http.StatusBadRequest,
+		GRPCCodeField: codes.InvalidArgument,
+		StatusField:   http.StatusText(http.StatusBadRequest),
+		ErrorField:    "Unable to insert or update resource because a value is invalid",
+	}
+	// ErrLockTimeout is returned when the database is unable to acquire a lock held by another session in time.
+	ErrLockTimeout = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.Unavailable,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to acquire a lock held by another session in time",
+	}
+	// ErrStatementTimeout is returned when the database canceled a statement because it exceeded its timeout,
+	// e.g. as set by ApplyStatementTimeout.
+	ErrStatementTimeout = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.DeadlineExceeded,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to complete the statement in time",
+	}
+	// ErrAuthenticationFailed is returned when the database rejects the credentials. It wraps ErrPermissionDenied.
+	ErrAuthenticationFailed = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.Internal,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "Unable to authenticate with the database",
+	}
+	// ErrPermissionDenied is returned when the database denies the privileges to run a statement, or rejects
+	// the credentials. Unlike transient failures, it is caused by misconfigured credentials or grants, so
+	// retrying does not help.
+	ErrPermissionDenied = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.Internal,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "The database denied the permission to run the statement",
+	}
+	// ErrConnectionFailed is returned when the database can not be reached or the connection was lost.
+	ErrConnectionFailed = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.Unavailable,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to connect to the database",
+	}
+	// ErrReadOnly is returned when a statement writes to a read-only transaction or database, e.g. to a
+	// primary which was demoted to a standby by a failover.
+	ErrReadOnly = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.Unavailable,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to write because the database is read-only",
+	}
+	// ErrSchemaOutdated is returned by CheckSchemaVersion if migrations are pending, including if the schema
+	// was never migrated.
+	ErrSchemaOutdated = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.FailedPrecondition,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "The database schema is outdated, apply the migrations",
+	}
+	// ErrSchemaAhead is returned by CheckSchemaVersion if the schema was migrated by a newer version of the
+	// service, so this version does not know the applied migrations.
+	ErrSchemaAhead = &herodot.DefaultError{
+		CodeField:     http.StatusInternalServerError,
+		GRPCCodeField: codes.FailedPrecondition,
+		StatusField:   http.StatusText(http.StatusInternalServerError),
+		ErrorField:    "The database schema is newer than this version of the service supports",
+	}
+	// ErrTimeout is returned when an operation was aborted because the deadline of its context was exceeded.
+	// Unlike ErrStatementTimeout, the deadline is set by the caller, not the database.
+	ErrTimeout = &herodot.DefaultError{
+		CodeField:     http.StatusGatewayTimeout,
+		GRPCCodeField: codes.DeadlineExceeded,
+		StatusField:   http.StatusText(http.StatusGatewayTimeout),
+		ErrorField:    "Unable to complete the database operation before the deadline",
+	}
+	// ErrCanceled is returned when an operation was aborted because its context was canceled, e.g. because
+	// the client closed the request. It does not indicate a problem with the database.
+	ErrCanceled = &herodot.DefaultError{
+		CodeField:     499,
+		GRPCCodeField: codes.Canceled,
+		StatusField:   "Client Closed Request",
+		ErrorField:    "The database operation was canceled",
+	}
+	// ErrCircuitOpen is returned by Breaker.Do without running the operation, because the database failed to
+	// connect repeatedly. It wraps the last connection failure, so it matches ErrConnectionFailed as well.
+	ErrCircuitOpen = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.Unavailable,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to connect to the database because it failed repeatedly",
+	}
+	// ErrTooManyConnections is returned when the database rejects a connection because its connection limit is
+	// reached. Callers should back off or shed load, as retrying immediately adds to the exhaustion.
+	ErrTooManyConnections = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.ResourceExhausted,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "Unable to connect to the database because it has too many connections",
+	}
+	// ErrReplicationLag is returned by ReplicationLagChecker if a replica lags behind the primary by more than the
+	// tolerated lag, or does not replicate at all.
+	ErrReplicationLag = &herodot.DefaultError{
+		CodeField:     http.StatusServiceUnavailable,
+		GRPCCodeField: codes.Unavailable,
+		StatusField:   http.StatusText(http.StatusServiceUnavailable),
+		ErrorField:    "The database replica lags behind the primary",
+	}
 )
 
 func handlePostgres(err error, sqlState string) error {
 	switch sqlState {
 	case "23505": // "unique_violation"
-		return errors.WithStack(ErrUniqueViolation.WithWrap(err))
+		constraint, table, columns := postgresConstraint(err)
+		return constraintViolation(ErrUniqueViolation, err, constraint, table, columns)
 	case "40001": // "serialization_failure" in CRDB
 		fallthrough
 	case "CR000": // "serialization_failure"
-		return errors.WithStack(ErrConcurrentUpdate.WithWrap(err))
+		return concurrentUpdate(ErrSerializationFailure, err)
+	case "40P01": // "deadlock_detected"
+		return concurrentUpdate(ErrDeadlock, err)
+	case "40003": // "statement_completion_unknown", e.g. CockroachDB's "result is ambiguous"
+		return errors.WithStack(ErrAmbiguousResult.WithWrap(err))
 	case "42P01": // "no such table"
 		return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+	case "42703": // "undefined_column"
+		return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+	case "23503": // "foreign_key_violation"
+		constraint, table, columns := postgresConstraint(err)
+		return constraintViolation(ErrForeignKeyViolation, err, constraint, table, columns)
+	case "23502": // "not_null_violation"
+		constraint, table, columns := postgresConstraint(err)
+		return constraintViolation(ErrNotNullViolation, err, constraint, table, columns)
+	case "23514": // "check_violation"
+		constraint, table, columns := postgresConstraint(err)
+		return constraintViolation(ErrCheckViolation, err, constraint, table, columns)
+	case "22001": // "string_data_right_truncation"
+		return errors.WithStack(ErrValueTooLong.WithWrap(err))
+	case "22003": // "numeric_value_out_of_range"
+		return errors.WithStack(ErrNumericOverflow.WithWrap(err))
+	case "25006": // "read_only_sql_transaction", also reported by standbys
+		return errors.WithStack(ErrReadOnly.WithWrap(err))
+	case "55P03": // "lock_not_available"
+		return errors.WithStack(ErrLockTimeout.WithWrap(err))
+	case "57014": // "query_canceled", e.g. by statement_timeout
+		return errors.WithStack(ErrStatementTimeout.WithWrap(err))
+	case "42501": // "insufficient_privilege"
+		return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+	case "28000", "28P01": // "invalid_authorization_specification", "invalid_password"
+		return permissionDenied(ErrAuthenticationFailed, err)
+	case "53300": // "too_many_connections", including "remaining connection slots are reserved"
+		return errors.WithStack(ErrTooManyConnections.WithWrap(err))
+	case "08000", "08001", "08003", "08004", "08006": // "connection_exception" and its subclasses
+		return errors.WithStack(ErrConnectionFailed.WithWrap(err))
 	}
 	return errors.WithStack(err)
 }
 
+// concurrentUpdate returns the error class wrapping ErrConcurrentUpdate, which wraps err.
+func concurrentUpdate(class *herodot.DefaultError, err error) error {
+	return errors.WithStack(class.WithWrap(ErrConcurrentUpdate.WithWrap(err)))
+}
+
+// permissionDenied returns the error class wrapping ErrPermissionDenied, which wraps err.
+func permissionDenied(class *herodot.DefaultError, err error) error {
+	return errors.WithStack(class.WithWrap(ErrPermissionDenied.WithWrap(err)))
+}
+
+// isConnectionError returns true if the database can not be reached. Timeouts are not considered,
+// as they are reported for slow queries as well.
+func isConnectionError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	if e := new(net.OpError); errors.As(err, &e) {
+		return true
+	}
+	if e := new(net.DNSError); errors.As(err, &e) {
+		return true
+	}
+	return false
+}
+
 type stater interface {
 	SQLState() string
 }
 
-// HandleError returns the right sqlcon.Err* depending on the input error.
+// HandleError returns the right sqlcon.Err* depending on the input error. Passwords of DSNs
+// which failed to parse are redacted. Errors of further drivers are handled by the handlers
+// added with RegisterErrorHandler. Joined errors, e.g. of batch operations, are classified by
+// the first of them which belongs to an error class. Operations aborted by their context are
+// reported as ErrCanceled or ErrTimeout, not as ErrConnectionFailed.
 func HandleError(err error) error {
 	if err == nil {
 		return nil
 	}
 
+	if e := new(url.Error); errors.As(err, &e) {
+		e.URL = RedactDSN(e.URL)
+	}
+
+	if err := handleJoined(err); err != nil {
+		return err
+	}
+
 	var st stater
-	if errors.Is(err, sql.ErrNoRows) {
-		return errors.WithStack(ErrNoRows)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) || errors.Is(err, pgxv5.ErrNoRows) {
+		return errors.WithStack(ErrNoRows.WithWrap(err))
 	} else if errors.As(err, &st) {
 		return handlePostgres(err, st.SQLState())
 	} else if e := new(pq.Error); errors.As(err, &e) {
 		return handlePostgres(err, string(e.Code))
 	} else if e := new(pgconn.PgError); errors.As(err, &e) {
 		return handlePostgres(err, e.Code)
-	} else if e := new(mysql.MySQLError); errors.As(err, &e) {
-		switch e.Number {
-		case 1062:
-			return errors.WithStack(ErrUniqueViolation.WithWrap(err))
-		case 1146:
-			return errors.WithStack(ErrNoSuchTable.WithWrap(e))
-		}
+	} else if e := new(pgconnv5.PgError); errors.As(err, &e) {
+		return handlePostgres(err, e.Code)
+	}
+
+	if err := handleMySQL(err); err != nil {
+		return err
 	}
 
-	if err := handleSqlite(err); err != nil {
+	if err := handleRegistered(err); err != nil {
 		return err
 	}
 
+	// drivers report the context error of interrupted operations, possibly wrapped in a network error
+	if errors.Is(err, context.Canceled) {
+		return errors.WithStack(ErrCanceled.WithWrap(err))
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		return errors.WithStack(ErrTimeout.WithWrap(err))
+	}
+
+	if isConnectionError(err) {
+		return errors.WithStack(ErrConnectionFailed.WithWrap(err))
+	}
+
 	return errors.WithStack(err)
 }
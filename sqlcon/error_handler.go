@@ -0,0 +1,61 @@
+package sqlcon
+
+import "sync"
+
+// ErrorHandler classifies the errors of a driver, e.g. by wrapping them with the error classes of this package.
+// It returns nil if the error does not belong to the driver.
+type ErrorHandler func(err error) error
+
+type registeredErrorHandler struct {
+	name    string
+	handler ErrorHandler
+}
+
+var (
+	errorHandlersMu sync.RWMutex
+	errorHandlers   []registeredErrorHandler
+)
+
+// RegisterErrorHandler registers the error handler of a driver, so that drivers which are not supported by
+// this package, e.g. ClickHouse or Spanner, can be classified by HandleError as well. The handlers are tried in
+// the order of their registration, after the built-in PostgreSQL and MySQL handlers. This package registers the
+// handlers of libsql, SQLite (build tag sqlite or sqlite_modernc) and SQL Server (build tag sqlserver) first, in
+// this order. Registering a name again replaces the handler. It is usually called in an init function:
+//
+//	func init() {
+//		sqlcon.RegisterErrorHandler("clickhouse", func(err error) error {
+//			var e *clickhouse.Exception
+//			if !errors.As(err, &e) {
+//				return nil
+//			}
+//			if e.Code == 60 { // UNKNOWN_TABLE
+//				return errors.WithStack(sqlcon.ErrNoSuchTable.WithWrap(err))
+//			}
+//			return errors.WithStack(err)
+//		})
+//	}
+func RegisterErrorHandler(name string, handler ErrorHandler) {
+	errorHandlersMu.Lock()
+	defer errorHandlersMu.Unlock()
+
+	for i, h := range errorHandlers {
+		if h.name == name {
+			errorHandlers[i].handler = handler
+			return
+		}
+	}
+	errorHandlers = append(errorHandlers, registeredErrorHandler{name: name, handler: handler})
+}
+
+// handleRegistered tries the registered error handlers.
+func handleRegistered(err error) error {
+	errorHandlersMu.RLock()
+	defer errorHandlersMu.RUnlock()
+
+	for _, h := range errorHandlers {
+		if handled := h.handler(err); handled != nil {
+			return handled
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package sqlcon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type exception struct {
+	code int
+}
+
+func (e *exception) Error() string { return fmt.Sprintf("code: %d", e.code) }
+
+func TestRegisterErrorHandler(t *testing.T) {
+	errorHandlersMu.RLock()
+	registered := errorHandlers
+	errorHandlersMu.RUnlock()
+	t.Cleanup(func() {
+		errorHandlersMu.Lock()
+		errorHandlers = registered
+		errorHandlersMu.Unlock()
+	})
+
+	handler := func(code int, class error) ErrorHandler {
+		return func(err error) error {
+			if e := new(exception); errors.As(err, &e) && e.code == code {
+				return errors.WithStack(errors.Wrap(class, err.Error()))
+			}
+			return nil
+		}
+	}
+
+	err := &exception{code: 60}
+	assert.NotErrorIs(t, HandleError(err), ErrNoSuchTable)
+
+	RegisterErrorHandler("test", handler(60, ErrUniqueViolation))
+	assert.ErrorIs(t, HandleError(fmt.Errorf("wrapped: %w", err)), ErrUniqueViolation)
+
+	RegisterErrorHandler("test", handler(60, ErrNoSuchTable))
+	assert.ErrorIs(t, HandleError(err), ErrNoSuchTable, "registering the name again replaces the handler")
+	other := &exception{code: 1}
+	assert.ErrorIs(t, HandleError(other), other)
+}
@@ -0,0 +1,76 @@
+package sqlcon
+
+import "github.com/pkg/errors"
+
+// classifiedErrors are joined errors, classified by the first of them which belongs to an error class.
+type classifiedErrors struct {
+	class error
+	err   error
+	errs  []error
+}
+
+func (e *classifiedErrors) Error() string {
+	return e.class.Error()
+}
+
+// Unwrap returns the error class.
+func (e *classifiedErrors) Unwrap() error {
+	return e.class
+}
+
+// Is matches all joined errors, also if the Go version does not support joined errors.
+func (e *classifiedErrors) Is(target error) bool {
+	for _, err := range e.errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As matches the multi-error, e.g. a *BulkInsertError, and all joined errors, also if the Go version does not
+// support joined errors.
+func (e *classifiedErrors) As(target interface{}) bool {
+	if errors.As(e.err, target) {
+		return true
+	}
+	for _, err := range e.errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinedErrors returns the errors joined by the first multi-error in the chain of err, e.g. by errors.Join,
+// go.uber.org/multierr or github.com/hashicorp/go-multierror.
+func joinedErrors(err error) []error {
+	for ; err != nil; err = errors.Unwrap(err) {
+		switch e := err.(type) {
+		case interface{ Unwrap() []error }:
+			return e.Unwrap()
+		case interface{ Errors() []error }:
+			return e.Errors()
+		case interface{ WrappedErrors() []error }:
+			return e.WrappedErrors()
+		}
+	}
+	return nil
+}
+
+// handleJoined handles the joined errors of err. It returns nil if none belongs to an error class.
+func handleJoined(err error) error {
+	errs := joinedErrors(err)
+	for _, joined := range errs {
+		if joined == nil {
+			continue
+		}
+		handled := HandleError(joined)
+		// all error classes are herodot errors
+		var class interface{ StatusCode() int }
+		if errors.As(handled, &class) {
+			return errors.WithStack(&classifiedErrors{class: handled, err: err, errs: errs})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package sqlcon
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// libsqlNumericCode matches the extended result code of the embedded libsql driver (github.com/tursodatabase/go-libsql),
+	// e.g. "failed to execute query INSERT ...\nerror code = 2067: UNIQUE constraint failed: users.email"
+	libsqlNumericCode = regexp.MustCompile(`error code = (\d+):`)
+	// libsqlNamedCode matches the result code reported by the server in HTTP mode (github.com/tursodatabase/libsql-client-go),
+	// e.g. "failed to execute SQL: INSERT ...\nSQLITE_CONSTRAINT_PRIMARYKEY: SQLite error: UNIQUE constraint failed: users.id"
+	libsqlNamedCode = regexp.MustCompile(`\bSQLITE_[A-Z_]+\b`)
+
+	libsqlCodes = map[int]string{
+		3:    "SQLITE_PERM",
+		5:    "SQLITE_BUSY",
+		6:    "SQLITE_LOCKED",
+		8:    "SQLITE_READONLY",
+		18:   "SQLITE_TOOBIG",
+		19:   "SQLITE_CONSTRAINT",
+		23:   "SQLITE_AUTH",
+		275:  "SQLITE_CONSTRAINT_CHECK",
+		787:  "SQLITE_CONSTRAINT_FOREIGNKEY",
+		1299: "SQLITE_CONSTRAINT_NOTNULL",
+		1555: "SQLITE_CONSTRAINT_PRIMARYKEY",
+		2067: "SQLITE_CONSTRAINT_UNIQUE",
+	}
+	// libsqlConstraintMessages is used if the server only reports the primary result code SQLITE_CONSTRAINT
+	libsqlConstraintMessages = map[string]string{
+		"UNIQUE constraint failed":      "SQLITE_CONSTRAINT_UNIQUE",
+		"FOREIGN KEY constraint failed": "SQLITE_CONSTRAINT_FOREIGNKEY",
+		"NOT NULL constraint failed":    "SQLITE_CONSTRAINT_NOTNULL",
+		"CHECK constraint failed":       "SQLITE_CONSTRAINT_CHECK",
+	}
+)
+
+// libsqlCode returns the result code of libsql errors by name. The libsql drivers do not export typed errors,
+// so the code is parsed from the message.
+func libsqlCode(message string) (string, bool) {
+	var code string
+	if m := libsqlNumericCode.FindStringSubmatch(message); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return "", false
+		}
+		if code = libsqlCodes[n]; code == "" {
+			// the primary result code is stored in the lowest byte of the extended code
+			code = libsqlCodes[n&0xff]
+		}
+	} else if m := libsqlNamedCode.FindString(message); m != "" {
+		code = m
+	} else {
+		return "", false
+	}
+
+	if code == "SQLITE_CONSTRAINT" {
+		for substr, extended := range libsqlConstraintMessages {
+			if strings.Contains(message, substr) {
+				return extended, true
+			}
+		}
+	}
+	return code, true
+}
+
+func init() {
+	RegisterErrorHandler("libsql", handleLibSQL)
+}
+
+// handleLibSQL classifies the errors of libsql, both of the embedded driver and in HTTP mode. It returns nil for
+// other errors and for result codes it does not classify, so that e.g. a canceled context is still reported as
+// ErrCanceled.
+func handleLibSQL(err error) error {
+	message := err.Error()
+	code, ok := libsqlCode(message)
+	if !ok {
+		return nil
+	}
+
+	switch {
+	case code == "SQLITE_CONSTRAINT_UNIQUE", code == "SQLITE_CONSTRAINT_PRIMARYKEY":
+		table, columns := sqliteColumns(message)
+		return constraintViolation(ErrUniqueViolation, err, "", table, columns)
+	case code == "SQLITE_CONSTRAINT_FOREIGNKEY":
+		// SQLite does not report which foreign key failed
+		return constraintViolation(ErrForeignKeyViolation, err, "", "", nil)
+	case code == "SQLITE_CONSTRAINT_NOTNULL":
+		table, columns := sqliteColumns(message)
+		return constraintViolation(ErrNotNullViolation, err, "", table, columns)
+	case code == "SQLITE_CONSTRAINT_CHECK":
+		return constraintViolation(ErrCheckViolation, err, sqliteCheckConstraint(message), "", nil)
+	case strings.HasPrefix(code, "SQLITE_BUSY"), strings.HasPrefix(code, "SQLITE_LOCKED"):
+		return errors.WithStack(ErrLockTimeout.WithWrap(err))
+	case strings.HasPrefix(code, "SQLITE_READONLY"):
+		// e.g. the database file is not writable
+		return permissionDenied(ErrReadOnly, err)
+	case code == "SQLITE_PERM", code == "SQLITE_AUTH":
+		return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+	case strings.HasPrefix(code, "SQLITE_TOOBIG"):
+		return errors.WithStack(ErrValueTooLong.WithWrap(err))
+	case strings.Contains(message, "integer overflow"):
+		return errors.WithStack(ErrNumericOverflow.WithWrap(err))
+	case strings.Contains(message, "no such table"):
+		return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+	case strings.Contains(message, "no such column"):
+		return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+	}
+
+	return nil
+}
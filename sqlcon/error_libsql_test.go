@@ -0,0 +1,81 @@
+package sqlcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleLibSQL(t *testing.T) {
+	for _, tc := range []struct {
+		message  string
+		expected error
+	}{
+		{message: "failed to execute query INSERT INTO users (email) VALUES (?)\nerror code = 2067: UNIQUE constraint failed: users.email", expected: ErrUniqueViolation},
+		{message: "failed to execute query INSERT INTO users (id) VALUES (?)\nerror code = 1555: UNIQUE constraint failed: users.id", expected: ErrUniqueViolation},
+		{message: "failed to execute query INSERT INTO children (parent_id) VALUES (?)\nerror code = 787: FOREIGN KEY constraint failed", expected: ErrForeignKeyViolation},
+		{message: "failed to execute query INSERT INTO users (id) VALUES (?)\nerror code = 1299: NOT NULL constraint failed: users.email", expected: ErrNotNullViolation},
+		{message: "failed to execute query INSERT INTO users (age) VALUES (?)\nerror code = 275: CHECK constraint failed: age_positive", expected: ErrCheckViolation},
+		{message: "failed to execute query SELECT 1\nerror code = 5: database is locked", expected: ErrLockTimeout},
+		{message: "failed to execute query SELECT 1\nerror code = 517: database is locked", expected: ErrLockTimeout},
+		{message: "failed to execute query SELECT * FROM users\nerror code = 1: no such table: users", expected: ErrNoSuchTable},
+		{message: "failed to execute query SELECT nickname FROM users\nerror code = 1: no such column: nickname", expected: ErrNoSuchColumn},
+		{message: "failed to execute SQL: INSERT INTO users (email) VALUES (?)\nSQLITE_CONSTRAINT_UNIQUE: SQLite error: UNIQUE constraint failed: users.email", expected: ErrUniqueViolation},
+		{message: "failed to execute SQL: INSERT INTO users (email) VALUES (?)\nSQLITE_CONSTRAINT: SQLite error: UNIQUE constraint failed: users.email", expected: ErrUniqueViolation},
+		{message: "failed to execute SQL: INSERT INTO children (parent_id) VALUES (?)\nSQLITE_CONSTRAINT: SQLite error: FOREIGN KEY constraint failed", expected: ErrForeignKeyViolation},
+		{message: "failed to execute SQL: INSERT INTO users (id) VALUES (?)\nSQLITE_CONSTRAINT_NOTNULL: SQLite error: NOT NULL constraint failed: users.email", expected: ErrNotNullViolation},
+		{message: "failed to execute query INSERT INTO users (email) VALUES (?)\nerror code = 8: attempt to write a readonly database", expected: ErrReadOnly},
+		{message: "failed to execute query INSERT INTO users (email) VALUES (?)\nerror code = 8: attempt to write a readonly database", expected: ErrPermissionDenied},
+		{message: "failed to execute SQL: DELETE FROM users\nSQLITE_AUTH: SQLite error: not authorized", expected: ErrPermissionDenied},
+		{message: "failed to execute query INSERT INTO files (content) VALUES (?)\nerror code = 18: string or blob too big", expected: ErrValueTooLong},
+		{message: "failed to execute SQL: SELECT sum(amount) FROM payments\nSQLITE_ERROR: SQLite error: integer overflow", expected: ErrNumericOverflow},
+		{message: "failed to execute SQL: SELECT 1\nSQLITE_BUSY: SQLite error: database is locked", expected: ErrLockTimeout},
+		{message: "failed to execute SQL: SELECT * FROM users\nSQLITE_UNKNOWN: SQLite error: no such table: users", expected: ErrNoSuchTable},
+	} {
+		t.Run("message="+tc.message, func(t *testing.T) {
+			err := errors.New(tc.message)
+			actual := HandleError(fmt.Errorf("wrapped: %w", err))
+			assert.ErrorIs(t, actual, tc.expected)
+			assert.ErrorIs(t, actual, err)
+		})
+	}
+
+	t.Run("case=constraint metadata", func(t *testing.T) {
+		var violation *ConstraintViolation
+		require.ErrorAs(t, HandleError(errors.New("failed to execute SQL: INSERT INTO users (tenant, email) VALUES (?, ?)\nSQLITE_CONSTRAINT_UNIQUE: SQLite error: UNIQUE constraint failed: users.tenant, users.email")), &violation)
+		assert.Equal(t, "users", violation.Table())
+		assert.Equal(t, []string{"tenant", "email"}, violation.Columns())
+
+		require.ErrorAs(t, HandleError(errors.New("failed to execute query INSERT INTO users (age) VALUES (?)\nerror code = 275: CHECK constraint failed: age_positive")), &violation)
+		assert.Equal(t, "age_positive", violation.Constraint())
+	})
+
+	t.Run("case=other errors are not classified", func(t *testing.T) {
+		err := errors.New("connection refused")
+		actual := HandleError(err)
+		assert.ErrorIs(t, actual, err)
+		assert.Equal(t, err.Error(), actual.Error())
+		assert.Nil(t, handleLibSQL(err))
+		assert.Nil(t, handleLibSQL(errors.New("failed to execute query SELECT 1\nerror code = 1: SQL logic error")), "the code is not classified")
+	})
+
+	t.Run("case=unclassified codes fall through", func(t *testing.T) {
+		actual := HandleError(fmt.Errorf("failed to execute query SELECT 1\nerror code = 1: interrupted: %w", context.Canceled))
+		assert.ErrorIs(t, actual, ErrCanceled)
+
+		actual = HandleError(fmt.Errorf("SQLITE_X: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}))
+		assert.ErrorIs(t, actual, ErrConnectionFailed)
+	})
+
+	t.Run("case=registered", func(t *testing.T) {
+		errorHandlersMu.RLock()
+		defer errorHandlersMu.RUnlock()
+		require.NotEmpty(t, errorHandlers)
+		assert.Equal(t, "libsql", errorHandlers[0].name)
+	})
+}
@@ -0,0 +1,60 @@
+package sqlcon
+
+import (
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+)
+
+// handleMySQL handles the error iff (if and only if) it is a MySQL or MariaDB error
+func handleMySQL(err error) error {
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return errors.WithStack(ErrConnectionFailed.WithWrap(err))
+	}
+
+	if e := new(mysql.MySQLError); errors.As(err, &e) {
+		switch e.Number {
+		case 1062: // ER_DUP_ENTRY
+			constraint, table := mysqlDuplicateKey(e.Message)
+			return constraintViolation(ErrUniqueViolation, err, constraint, table, nil)
+		case 1146: // ER_NO_SUCH_TABLE
+			return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+		case 1054: // ER_BAD_FIELD_ERROR
+			return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+		case 1213: // ER_LOCK_DEADLOCK
+			return concurrentUpdate(ErrDeadlock, err)
+		case 1048, 1364: // ER_BAD_NULL_ERROR, ER_NO_DEFAULT_FOR_FIELD
+			return constraintViolation(ErrNotNullViolation, err, "", "", mysqlColumns(e.Message))
+		case 3819, 4025: // ER_CHECK_CONSTRAINT_VIOLATED, MariaDB's ER_CONSTRAINT_FAILED
+			return constraintViolation(ErrCheckViolation, err, mysqlCheckConstraint(e.Message), "", nil)
+		case 1406: // ER_DATA_TOO_LONG
+			return errors.WithStack(ErrValueTooLong.WithWrap(err))
+		case 1264, 1690: // ER_WARN_DATA_OUT_OF_RANGE, ER_DATA_OUT_OF_RANGE
+			return errors.WithStack(ErrNumericOverflow.WithWrap(err))
+		case 1792: // ER_CANT_EXECUTE_IN_READ_ONLY_TRANSACTION
+			return errors.WithStack(ErrReadOnly.WithWrap(err))
+		case 1290: // ER_OPTION_PREVENTS_STATEMENT, e.g. "... running with the --read-only option ..."
+			if strings.Contains(e.Message, "read-only") {
+				return errors.WithStack(ErrReadOnly.WithWrap(err))
+			}
+		case 1205: // ER_LOCK_WAIT_TIMEOUT
+			return errors.WithStack(ErrLockTimeout.WithWrap(err))
+		case 3024: // ER_QUERY_TIMEOUT
+			return errors.WithStack(ErrStatementTimeout.WithWrap(err))
+		case 1040, 1203: // ER_CON_COUNT_ERROR, ER_TOO_MANY_USER_CONNECTIONS
+			return errors.WithStack(ErrTooManyConnections.WithWrap(err))
+		case 1044, 1045: // ER_DBACCESS_DENIED_ERROR, ER_ACCESS_DENIED_ERROR
+			return permissionDenied(ErrAuthenticationFailed, err)
+		case 1142, 1143, 1227: // ER_TABLEACCESS_DENIED_ERROR, ER_COLUMNACCESS_DENIED_ERROR, ER_SPECIFIC_ACCESS_DENIED_ERROR
+			return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+		case 1451, 1452: // ER_ROW_IS_REFERENCED_2, ER_NO_REFERENCED_ROW_2
+			constraint, table, columns := mysqlForeignKeyConstraint(e.Message)
+			return constraintViolation(ErrForeignKeyViolation, err, constraint, table, columns)
+		}
+
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package sqlcon
+
+import (
+	"regexp"
+
+	"github.com/ory/herodot"
+	"github.com/pkg/errors"
+)
+
+// ErrorPattern classifies the errors whose message matches the pattern as the error class.
+type ErrorPattern struct {
+	Pattern *regexp.Regexp
+	Class   *herodot.DefaultError
+}
+
+// DefaultErrorPatterns match common messages of databases and drivers. They are heuristics for drivers without
+// structured error codes and may misclassify errors, so prefer patterns tailored to the driver.
+var DefaultErrorPatterns = []ErrorPattern{
+	{Pattern: regexp.MustCompile(`(?i)duplicate (key|entry)|unique constraint|unique key`), Class: ErrUniqueViolation},
+	{Pattern: regexp.MustCompile(`(?i)foreign key constraint`), Class: ErrForeignKeyViolation},
+	{Pattern: regexp.MustCompile(`(?i)not[- ]null constraint|null value in column|cannot be null|can't be null`), Class: ErrNotNullViolation},
+	{Pattern: regexp.MustCompile(`(?i)check constraint`), Class: ErrCheckViolation},
+	{Pattern: regexp.MustCompile(`(?i)deadlock`), Class: ErrDeadlock},
+	{Pattern: regexp.MustCompile(`(?i)could not serialize|serialization failure`), Class: ErrSerializationFailure},
+	{Pattern: regexp.MustCompile(`(?i)lock wait timeout|lock timeout|lock request time ?out`), Class: ErrLockTimeout},
+	{Pattern: regexp.MustCompile(`(?i)statement timeout|query timeout|timeout exceeded`), Class: ErrStatementTimeout},
+	{Pattern: regexp.MustCompile(`(?i)no such table|unknown table|(table|relation) \S+ (does not exist|doesn't exist)`), Class: ErrNoSuchTable},
+	{Pattern: regexp.MustCompile(`(?i)no such column|unknown column|column \S+ (does not exist|doesn't exist)`), Class: ErrNoSuchColumn},
+	{Pattern: regexp.MustCompile(`(?i)authentication failed|login failed|invalid password`), Class: ErrAuthenticationFailed},
+	{Pattern: regexp.MustCompile(`(?i)permission denied|access denied|not enough privileges|insufficient privileges`), Class: ErrPermissionDenied},
+	{Pattern: regexp.MustCompile(`(?i)read[- ]only`), Class: ErrReadOnly},
+	{Pattern: regexp.MustCompile(`(?i)too many connections`), Class: ErrTooManyConnections},
+	{Pattern: regexp.MustCompile(`(?i)connection refused|connection reset|broken pipe`), Class: ErrConnectionFailed},
+}
+
+// PatternErrorHandler returns an ErrorHandler which classifies errors by their message, for drivers without
+// structured error codes, e.g. some ODBC or ClickHouse setups. The first matching pattern wins; errors
+// matching none of them are left to the other handlers. It is opt-in, as it matches the errors of all drivers
+// which were not classified by their code before:
+//
+//	sqlcon.RegisterErrorHandler("patterns", sqlcon.PatternErrorHandler(append([]sqlcon.ErrorPattern{
+//		{Pattern: regexp.MustCompile(`Code: 60\b`), Class: sqlcon.ErrNoSuchTable},
+//	}, sqlcon.DefaultErrorPatterns...)...))
+//
+// As with the built-in handlers, deadlocks and serialization failures match ErrConcurrentUpdate as well, and
+// failed authentications match ErrPermissionDenied.
+func PatternErrorHandler(patterns ...ErrorPattern) ErrorHandler {
+	return func(err error) error {
+		message := err.Error()
+		for _, p := range patterns {
+			if !p.Pattern.MatchString(message) {
+				continue
+			}
+			switch p.Class {
+			case ErrDeadlock, ErrSerializationFailure:
+				return concurrentUpdate(p.Class, err)
+			case ErrAuthenticationFailed:
+				return permissionDenied(p.Class, err)
+			}
+			return errors.WithStack(p.Class.WithWrap(err))
+		}
+		return nil
+	}
+}
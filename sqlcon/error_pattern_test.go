@@ -0,0 +1,73 @@
+package sqlcon
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPatternErrorHandler(t *testing.T) {
+	handle := PatternErrorHandler(DefaultErrorPatterns...)
+
+	for _, tc := range []struct {
+		message  string
+		expected []error
+	}{
+		{message: "Duplicate entry 'foo' for key 'PRIMARY'", expected: []error{ErrUniqueViolation}},
+		{message: "violates foreign key constraint fk_identity", expected: []error{ErrForeignKeyViolation}},
+		{message: "Column 'name' cannot be null", expected: []error{ErrNotNullViolation}},
+		{message: "Deadlock found when trying to get lock", expected: []error{ErrDeadlock, ErrConcurrentUpdate}},
+		{message: "could not serialize access due to concurrent update", expected: []error{ErrSerializationFailure, ErrConcurrentUpdate}},
+		{message: "Lock wait timeout exceeded; try restarting transaction", expected: []error{ErrLockTimeout}},
+		{message: "Code: 159. DB::Exception: Timeout exceeded: elapsed 5.0 seconds", expected: []error{ErrStatementTimeout}},
+		{message: "Code: 60. DB::Exception: Table default.identities doesn't exist", expected: []error{ErrNoSuchTable}},
+		{message: "[Microsoft][ODBC Driver] Login failed for user 'kratos'", expected: []error{ErrAuthenticationFailed, ErrPermissionDenied}},
+		{message: "Code: 497. DB::Exception: kratos: Not enough privileges", expected: []error{ErrPermissionDenied}},
+		{message: "cannot execute INSERT in a read-only transaction", expected: []error{ErrReadOnly}},
+		{message: "dial tcp 127.0.0.1:9000: connect: connection refused", expected: []error{ErrConnectionFailed}},
+	} {
+		t.Run("message="+tc.message, func(t *testing.T) {
+			err := errors.New(tc.message)
+			handled := handle(fmt.Errorf("wrapped: %w", err))
+			require.Error(t, handled)
+			for _, expected := range tc.expected {
+				assert.ErrorIs(t, handled, expected)
+			}
+			assert.ErrorIs(t, handled, err)
+		})
+	}
+
+	t.Run("case=ignores unknown messages", func(t *testing.T) {
+		assert.NoError(t, handle(errors.New("something else went wrong")))
+	})
+
+	t.Run("case=the first matching pattern wins", func(t *testing.T) {
+		handle := PatternErrorHandler(append([]ErrorPattern{
+			{Pattern: regexp.MustCompile(`Code: 60\b`), Class: ErrNoSuchColumn},
+		}, DefaultErrorPatterns...)...)
+		assert.ErrorIs(t, handle(errors.New("Code: 60. DB::Exception: Table default.identities doesn't exist")), ErrNoSuchColumn)
+	})
+
+	t.Run("case=classifies with HandleError once registered", func(t *testing.T) {
+		errorHandlersMu.RLock()
+		registered := errorHandlers
+		errorHandlersMu.RUnlock()
+		t.Cleanup(func() {
+			errorHandlersMu.Lock()
+			errorHandlers = registered
+			errorHandlersMu.Unlock()
+		})
+
+		err := errors.New("Code: 60. DB::Exception: Table default.identities doesn't exist")
+		assert.NotErrorIs(t, HandleError(err), ErrNoSuchTable)
+
+		RegisterErrorHandler("patterns", handle)
+		assert.ErrorIs(t, HandleError(err), ErrNoSuchTable)
+		assert.ErrorIs(t, HandleError(context.Canceled), ErrCanceled)
+	})
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/pkg/errors"
 )
 
+func init() {
+	RegisterErrorHandler("sqlite3", handleSqlite)
+}
+
 // handleSqlite handles the error iff (if and only if) it is an sqlite error
 func handleSqlite(err error) error {
 	if e := new(sqlite3.Error); errors.As(err, e) {
@@ -17,13 +21,35 @@ func handleSqlite(err error) error {
 		case sqlite3.ErrConstraintUnique:
 			fallthrough
 		case sqlite3.ErrConstraintPrimaryKey:
-			return errors.WithStack(ErrUniqueViolation.WithWrap(err))
+			table, columns := sqliteColumns(err.Error())
+			return constraintViolation(ErrUniqueViolation, err, "", table, columns)
+		case sqlite3.ErrConstraintForeignKey:
+			// SQLite does not report which foreign key failed
+			return constraintViolation(ErrForeignKeyViolation, err, "", "", nil)
+		case sqlite3.ErrConstraintNotNull:
+			table, columns := sqliteColumns(err.Error())
+			return constraintViolation(ErrNotNullViolation, err, "", table, columns)
+		case sqlite3.ErrConstraintCheck:
+			return constraintViolation(ErrCheckViolation, err, sqliteCheckConstraint(err.Error()), "", nil)
 		}
 
 		switch e.Code {
+		case sqlite3.ErrBusy, sqlite3.ErrLocked:
+			return errors.WithStack(ErrLockTimeout.WithWrap(err))
+		case sqlite3.ErrReadonly:
+			// e.g. the database file is not writable
+			return permissionDenied(ErrReadOnly, err)
+		case sqlite3.ErrPerm, sqlite3.ErrAuth:
+			return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+		case sqlite3.ErrTooBig:
+			return errors.WithStack(ErrValueTooLong.WithWrap(err))
 		case sqlite3.ErrError:
 			if strings.Contains(err.Error(), "no such table") {
 				return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+			} else if strings.Contains(err.Error(), "no such column") {
+				return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+			} else if strings.Contains(err.Error(), "integer overflow") {
+				return errors.WithStack(ErrNumericOverflow.WithWrap(err))
 			}
 		}
 
@@ -0,0 +1,62 @@
+//go:build sqlite_modernc
+// +build sqlite_modernc
+
+package sqlcon
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+func init() {
+	RegisterErrorHandler("sqlite_modernc", handleModerncSqlite)
+}
+
+// handleModerncSqlite handles the error iff (if and only if) it is a modernc.org/sqlite error.
+// The driver does not need CGO, so use the sqlite_modernc build tag instead of sqlite where CGO is unavailable.
+func handleModerncSqlite(err error) error {
+	var e *sqlite.Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+
+	switch e.Code() {
+	case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+		table, columns := sqliteColumns(err.Error())
+		return constraintViolation(ErrUniqueViolation, err, "", table, columns)
+	case sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY:
+		// SQLite does not report which foreign key failed
+		return constraintViolation(ErrForeignKeyViolation, err, "", "", nil)
+	case sqlite3.SQLITE_CONSTRAINT_NOTNULL:
+		table, columns := sqliteColumns(err.Error())
+		return constraintViolation(ErrNotNullViolation, err, "", table, columns)
+	case sqlite3.SQLITE_CONSTRAINT_CHECK:
+		return constraintViolation(ErrCheckViolation, err, sqliteCheckConstraint(err.Error()), "", nil)
+	}
+
+	// the primary result code is stored in the lowest byte of the extended code
+	switch e.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return errors.WithStack(ErrLockTimeout.WithWrap(err))
+	case sqlite3.SQLITE_READONLY:
+		// e.g. the database file is not writable
+		return permissionDenied(ErrReadOnly, err)
+	case sqlite3.SQLITE_PERM, sqlite3.SQLITE_AUTH:
+		return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+	case sqlite3.SQLITE_TOOBIG:
+		return errors.WithStack(ErrValueTooLong.WithWrap(err))
+	case sqlite3.SQLITE_ERROR:
+		if strings.Contains(err.Error(), "no such table") {
+			return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+		} else if strings.Contains(err.Error(), "no such column") {
+			return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+		} else if strings.Contains(err.Error(), "integer overflow") {
+			return errors.WithStack(ErrNumericOverflow.WithWrap(err))
+		}
+	}
+
+	return errors.WithStack(err)
+}
@@ -0,0 +1,70 @@
+//go:build sqlite_modernc
+// +build sqlite_modernc
+
+package sqlcon
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestHandleModerncSqlite(t *testing.T) {
+	db, err := sql.Open("sqlite", "file::memory:?_pragma=foreign_keys(1)")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE parents (id INTEGER PRIMARY KEY);
+CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL REFERENCES parents(id), age INTEGER CONSTRAINT age_positive CHECK (age > 0));
+CREATE TABLE users (tenant TEXT, email TEXT, UNIQUE (tenant, email));
+INSERT INTO parents (id) VALUES (1);
+INSERT INTO users (tenant, email) VALUES ('a', 'foo@bar.com')`)
+	require.NoError(t, err)
+
+	var violation *ConstraintViolation
+	_, err = db.Exec("INSERT INTO parents (id) VALUES (1)")
+	assert.ErrorIs(t, HandleError(err), ErrUniqueViolation)
+	require.ErrorAs(t, HandleError(err), &violation)
+	assert.Equal(t, "parents", violation.Table())
+	assert.Equal(t, []string{"id"}, violation.Columns())
+
+	_, err = db.Exec("INSERT INTO users (tenant, email) VALUES ('a', 'foo@bar.com')")
+	require.ErrorAs(t, HandleError(err), &violation)
+	assert.Equal(t, "users", violation.Table())
+	assert.Equal(t, []string{"tenant", "email"}, violation.Columns())
+
+	_, err = db.Exec("INSERT INTO children (id, parent_id) VALUES (1, 2)")
+	assert.ErrorIs(t, HandleError(err), ErrForeignKeyViolation)
+
+	_, err = db.Exec("INSERT INTO children (id) VALUES (1)")
+	assert.ErrorIs(t, HandleError(err), ErrNotNullViolation)
+	require.ErrorAs(t, HandleError(err), &violation)
+	assert.Equal(t, []string{"parent_id"}, violation.Columns())
+
+	_, err = db.Exec("INSERT INTO children (id, parent_id, age) VALUES (1, 1, -1)")
+	assert.ErrorIs(t, HandleError(err), ErrCheckViolation)
+	require.ErrorAs(t, HandleError(err), &violation)
+	assert.Equal(t, "age_positive", violation.Constraint())
+
+	_, err = db.Exec("SELECT * FROM does_not_exist")
+	assert.ErrorIs(t, HandleError(err), ErrNoSuchTable)
+
+	_, err = db.Exec("SELECT does_not_exist FROM users")
+	assert.ErrorIs(t, HandleError(err), ErrNoSuchColumn)
+
+	_, err = db.Exec("SELECT zeroblob(2000000000)")
+	assert.ErrorIs(t, HandleError(err), ErrValueTooLong)
+
+	_, err = db.Exec("SELECT sum(v) FROM (SELECT 9223372036854775807 AS v UNION ALL SELECT 1)")
+	assert.ErrorIs(t, HandleError(err), ErrNumericOverflow)
+
+	_, err = db.Exec("PRAGMA query_only = ON")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO parents (id) VALUES (2)")
+	assert.ErrorIs(t, HandleError(err), ErrReadOnly)
+	assert.ErrorIs(t, HandleError(err), ErrPermissionDenied)
+}
@@ -0,0 +1,93 @@
+//go:build sqlserver
+// +build sqlserver
+
+package sqlcon
+
+import (
+	"regexp"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/pkg/errors"
+)
+
+func init() {
+	RegisterErrorHandler("sqlserver", handleSQLServer)
+}
+
+// handleSQLServer handles the error iff (if and only if) it is a SQL Server error
+func handleSQLServer(err error) error {
+	if e := new(mssql.Error); errors.As(err, e) {
+		switch e.Number {
+		case 2601, 2627: // duplicate key in unique index, violation of unique or primary key constraint
+			constraint, table := sqlServerDuplicateKey(e.Message)
+			return constraintViolation(ErrUniqueViolation, err, constraint, table, nil)
+		case 208: // invalid object name
+			return errors.WithStack(ErrNoSuchTable.WithWrap(err))
+		case 207: // invalid column name
+			return errors.WithStack(ErrNoSuchColumn.WithWrap(err))
+		case 515: // cannot insert the value NULL into column
+			return constraintViolation(ErrNotNullViolation, err, "", "", sqlServerColumns(e.Message))
+		case 547: // conflicted with a FOREIGN KEY or CHECK constraint
+			class := ErrForeignKeyViolation
+			if strings.Contains(e.Message, "CHECK constraint") {
+				class = ErrCheckViolation
+			}
+			return constraintViolation(class, err, sqlServerConstraintName(e.Message), "", nil)
+		case 8152, 2628: // string or binary data would be truncated
+			return errors.WithStack(ErrValueTooLong.WithWrap(err))
+		case 220, 8115: // arithmetic overflow error
+			return errors.WithStack(ErrNumericOverflow.WithWrap(err))
+		case 3906: // failed to update database because the database is read-only
+			return errors.WithStack(ErrReadOnly.WithWrap(err))
+		case 1205: // chosen as deadlock victim
+			return concurrentUpdate(ErrDeadlock, err)
+		case 18456: // login failed
+			return permissionDenied(ErrAuthenticationFailed, err)
+		case 229, 230, 262: // the permission was denied on the object, column or database
+			return errors.WithStack(ErrPermissionDenied.WithWrap(err))
+		case 17809: // the maximum number of user connections has already been reached
+			return errors.WithStack(ErrTooManyConnections.WithWrap(err))
+		}
+
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
+
+var (
+	// sqlServerObject matches the table of messages like "Cannot insert duplicate key row in object 'dbo.users' ..."
+	sqlServerObject = regexp.MustCompile(`in object '([^']+)'`)
+	// sqlServerIndex matches the index or constraint of messages like "... with unique index 'ix_email'." and
+	// "Violation of UNIQUE KEY constraint 'uq_email'."
+	sqlServerIndex = regexp.MustCompile(`(?:index|constraint) '([^']+)'`)
+	// sqlServerColumn matches messages like "Cannot insert the value NULL into column 'name', table 'db.dbo.table'; ..."
+	sqlServerColumn = regexp.MustCompile(`column '([^']+)'`)
+	// sqlServerConstraint matches messages like `... conflicted with the CHECK constraint "name". ...`
+	sqlServerConstraint = regexp.MustCompile(`constraint "([^"]+)"`)
+)
+
+func sqlServerColumns(message string) []string {
+	if m := sqlServerColumn.FindStringSubmatch(message); m != nil {
+		return []string{m[1]}
+	}
+	return nil
+}
+
+func sqlServerConstraintName(message string) string {
+	if m := sqlServerConstraint.FindStringSubmatch(message); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func sqlServerDuplicateKey(message string) (constraint, table string) {
+	if m := sqlServerIndex.FindStringSubmatch(message); m != nil {
+		constraint = m[1]
+	}
+	if m := sqlServerObject.FindStringSubmatch(message); m != nil {
+		table = m[1]
+	}
+	return constraint, table
+}
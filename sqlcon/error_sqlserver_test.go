@@ -0,0 +1,65 @@
+//go:build sqlserver
+// +build sqlserver
+
+package sqlcon
+
+import (
+	"fmt"
+	"testing"
+
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleSQLServer(t *testing.T) {
+	for number, expected := range map[int32]error{
+		2601:  ErrUniqueViolation,
+		2627:  ErrUniqueViolation,
+		208:   ErrNoSuchTable,
+		207:   ErrNoSuchColumn,
+		8152:  ErrValueTooLong,
+		2628:  ErrValueTooLong,
+		8115:  ErrNumericOverflow,
+		3906:  ErrReadOnly,
+		17809: ErrTooManyConnections,
+		18456: ErrPermissionDenied,
+		229:   ErrPermissionDenied,
+		262:   ErrPermissionDenied,
+		1205:  ErrDeadlock,
+	} {
+		err := mssql.Error{Number: number}
+		actual := HandleError(fmt.Errorf("wrapped: %w", err))
+		assert.ErrorIs(t, actual, expected, "%d", number)
+		var e mssql.Error
+		assert.ErrorAs(t, actual, &e, "%d", number)
+		assert.Equal(t, number, e.Number)
+	}
+
+	var e mssql.Error
+	assert.ErrorAs(t, HandleError(mssql.Error{Number: 8152}), &e)
+
+	var violation *ConstraintViolation
+	err := HandleError(mssql.Error{Number: 515, Message: "Cannot insert the value NULL into column 'email', table 'test.dbo.users'; column does not allow nulls. INSERT fails."})
+	assert.ErrorIs(t, err, ErrNotNullViolation)
+	assert.ErrorAs(t, err, &violation)
+	assert.Equal(t, []string{"email"}, violation.Columns())
+
+	err = HandleError(mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the CHECK constraint "age_positive". The conflict occurred in database "test", table "dbo.users", column 'age'.`})
+	assert.ErrorIs(t, err, ErrCheckViolation)
+	assert.ErrorAs(t, err, &violation)
+	assert.Equal(t, "age_positive", violation.Constraint())
+
+	err = HandleError(mssql.Error{Number: 2601, Message: "Cannot insert duplicate key row in object 'dbo.users' with unique index 'ix_email'. The duplicate key value is (foo@bar.com)."})
+	assert.ErrorAs(t, err, &violation)
+	assert.Equal(t, "ix_email", violation.Constraint())
+	assert.Equal(t, "dbo.users", violation.Table())
+
+	err = HandleError(mssql.Error{Number: 2627, Message: "Violation of UNIQUE KEY constraint 'uq_email'. Cannot insert duplicate key in object 'dbo.users'. The duplicate key value is (foo@bar.com)."})
+	assert.ErrorAs(t, err, &violation)
+	assert.Equal(t, "uq_email", violation.Constraint())
+	assert.Equal(t, "dbo.users", violation.Table())
+
+	err = HandleError(mssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "fk_parent". The conflict occurred in database "test", table "dbo.parents", column 'id'.`})
+	assert.ErrorIs(t, err, ErrForeignKeyViolation)
+	assert.True(t, IsRetryable(mssql.Error{Number: 1205}))
+}
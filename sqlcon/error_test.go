@@ -0,0 +1,205 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	pgxv5 "github.com/jackc/pgx/v5"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type (
+	// joinError is implemented like the errors of errors.Join, which requires Go 1.20.
+	joinError []error
+	// multiError is implemented like the errors of go.uber.org/multierr.
+	multiError []error
+)
+
+func (e joinError) Error() string    { return fmt.Sprintf("%v", []error(e)) }
+func (e joinError) Unwrap() []error  { return e }
+func (e multiError) Error() string   { return fmt.Sprintf("%v", []error(e)) }
+func (e multiError) Errors() []error { return e }
+
+func TestHandleError(t *testing.T) {
+	assert.NoError(t, HandleError(nil))
+	for _, err := range []error{sql.ErrNoRows, pgx.ErrNoRows, pgxv5.ErrNoRows} {
+		actual := HandleError(fmt.Errorf("wrapped: %w", err))
+		assert.ErrorIs(t, actual, ErrNoRows, "%s", err)
+		assert.ErrorIs(t, actual, err, "%s", err)
+	}
+
+	for _, tc := range []struct {
+		code     string
+		expected error
+	}{
+		{code: "23505", expected: ErrUniqueViolation},
+		{code: "42P01", expected: ErrNoSuchTable},
+		{code: "42703", expected: ErrNoSuchColumn},
+		{code: "40003", expected: ErrAmbiguousResult},
+		{code: "22001", expected: ErrValueTooLong},
+		{code: "22003", expected: ErrNumericOverflow},
+		{code: "25006", expected: ErrReadOnly},
+		{code: "40001", expected: ErrConcurrentUpdate},
+		{code: "40001", expected: ErrSerializationFailure},
+		{code: "CR000", expected: ErrSerializationFailure},
+		{code: "40P01", expected: ErrConcurrentUpdate},
+		{code: "40P01", expected: ErrDeadlock},
+		{code: "23503", expected: ErrForeignKeyViolation},
+		{code: "55P03", expected: ErrLockTimeout},
+		{code: "57014", expected: ErrStatementTimeout},
+		{code: "28P01", expected: ErrAuthenticationFailed},
+		{code: "28P01", expected: ErrPermissionDenied},
+		{code: "42501", expected: ErrPermissionDenied},
+		{code: "53300", expected: ErrTooManyConnections},
+		{code: "08006", expected: ErrConnectionFailed},
+	} {
+		t.Run("code="+tc.code, func(t *testing.T) {
+			for name, err := range map[string]error{
+				"pgx v5": &pgconnv5.PgError{Code: tc.code},
+				"pgx v4": &pgconn.PgError{Code: tc.code},
+				"lib/pq": &pq.Error{Code: pq.ErrorCode(tc.code)},
+			} {
+				actual := HandleError(fmt.Errorf("wrapped: %w", err))
+				assert.ErrorIs(t, actual, tc.expected, name)
+				assert.ErrorIs(t, actual, err, name)
+			}
+		})
+	}
+
+	t.Run("case=mysql", func(t *testing.T) {
+		for number, expected := range map[uint16]error{
+			1062: ErrUniqueViolation,
+			1146: ErrNoSuchTable,
+			1054: ErrNoSuchColumn,
+			1406: ErrValueTooLong,
+			1264: ErrNumericOverflow,
+			1690: ErrNumericOverflow,
+			1792: ErrReadOnly,
+			1213: ErrDeadlock,
+			1205: ErrLockTimeout,
+			1451: ErrForeignKeyViolation,
+			1452: ErrForeignKeyViolation,
+			1045: ErrAuthenticationFailed,
+			1044: ErrPermissionDenied,
+			1142: ErrPermissionDenied,
+			1143: ErrPermissionDenied,
+			3024: ErrStatementTimeout,
+			1040: ErrTooManyConnections,
+			1203: ErrTooManyConnections,
+		} {
+			err := &mysql.MySQLError{Number: number}
+			actual := HandleError(fmt.Errorf("wrapped: %w", err))
+			assert.ErrorIs(t, actual, expected, "%d", number)
+			assert.ErrorIs(t, actual, err, "%d", number)
+		}
+
+		err := &mysql.MySQLError{Number: 1406}
+		assert.ErrorIs(t, HandleError(err), err)
+
+		err = &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --read-only option so it cannot execute this statement"}
+		assert.ErrorIs(t, HandleError(err), ErrReadOnly)
+		err = &mysql.MySQLError{Number: 1290, Message: "The MySQL server is running with the --secure-file-priv option so it cannot execute this statement"}
+		assert.NotErrorIs(t, HandleError(err), ErrReadOnly)
+	})
+
+	t.Run("case=permission denied is distinguishable from authentication failures", func(t *testing.T) {
+		err := HandleError(&pgconnv5.PgError{Code: "42501", Message: "permission denied for table identities"})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		assert.NotErrorIs(t, err, ErrAuthenticationFailed)
+		assert.EqualError(t, err, ErrPermissionDenied.Error())
+
+		err = HandleError(&mysql.MySQLError{Number: 1045})
+		assert.EqualError(t, err, ErrAuthenticationFailed.Error())
+	})
+
+	t.Run("case=ambiguous results are not retryable", func(t *testing.T) {
+		err := &pgconnv5.PgError{Code: "40003", Message: "result is ambiguous (error=rpc error: code = Unavailable)"}
+		assert.ErrorIs(t, HandleError(err), ErrAmbiguousResult)
+		assert.NotErrorIs(t, HandleError(err), ErrConcurrentUpdate)
+		assert.False(t, IsRetryable(err))
+	})
+
+	t.Run("case=concurrency errors are distinguishable", func(t *testing.T) {
+		err := HandleError(&pgconnv5.PgError{Code: "40P01"})
+		assert.ErrorIs(t, err, ErrDeadlock)
+		assert.NotErrorIs(t, err, ErrSerializationFailure)
+		assert.Equal(t, ErrDeadlock.ErrorField, err.Error())
+
+		err = HandleError(&mysql.MySQLError{Number: 1213})
+		assert.ErrorIs(t, err, ErrConcurrentUpdate)
+		assert.NotErrorIs(t, err, ErrSerializationFailure)
+	})
+
+	t.Run("case=unknown codes are passed through", func(t *testing.T) {
+		err := &pgconnv5.PgError{Code: "22001"}
+		actual := HandleError(err)
+		assert.ErrorIs(t, actual, err)
+		assert.NotErrorIs(t, actual, ErrUniqueViolation)
+	})
+
+	t.Run("case=connection errors", func(t *testing.T) {
+		for _, err := range []error{
+			driver.ErrBadConn,
+			mysql.ErrInvalidConn,
+			&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			&net.DNSError{Err: "no such host", Name: "db.internal"},
+		} {
+			actual := HandleError(fmt.Errorf("wrapped: %w", err))
+			assert.ErrorIs(t, actual, ErrConnectionFailed, "%s", err)
+			assert.ErrorIs(t, actual, err, "%s", err)
+		}
+
+		assert.NotErrorIs(t, HandleError(context.DeadlineExceeded), ErrConnectionFailed)
+	})
+
+	t.Run("case=context errors", func(t *testing.T) {
+		for expected, err := range map[error]error{
+			ErrCanceled: context.Canceled,
+			ErrTimeout:  context.DeadlineExceeded,
+		} {
+			for _, wrapped := range []error{
+				err,
+				fmt.Errorf("wrapped: %w", err),
+				&net.OpError{Op: "read", Net: "tcp", Err: err},
+			} {
+				actual := HandleError(wrapped)
+				assert.ErrorIs(t, actual, expected, "%s", wrapped)
+				assert.ErrorIs(t, actual, err, "%s", wrapped)
+				assert.NotErrorIs(t, actual, ErrConnectionFailed, "%s", wrapped)
+			}
+		}
+	})
+
+	t.Run("case=joined errors", func(t *testing.T) {
+		other := errors.New("unable to insert the next row")
+		unique := &pgconnv5.PgError{Code: "23505", ConstraintName: "identities_email_key"}
+		for name, err := range map[string]error{
+			"errors.Join": fmt.Errorf("batch: %w", joinError{other, unique}),
+			"multierr":    multiError{nil, other, &mysql.MySQLError{Number: 1062}},
+		} {
+			actual := HandleError(err)
+			assert.ErrorIs(t, actual, ErrUniqueViolation, name)
+			assert.ErrorIs(t, actual, other, name)
+			assert.Equal(t, ErrUniqueViolation.ErrorField, actual.Error(), name)
+		}
+
+		var violation *ConstraintViolation
+		require.ErrorAs(t, HandleError(joinError{other, unique}), &violation)
+		assert.Equal(t, "identities_email_key", violation.Constraint())
+
+		err := joinError{other, errors.New("foo")}
+		assert.ErrorIs(t, HandleError(err), other)
+		assert.NotErrorIs(t, HandleError(err), ErrUniqueViolation)
+	})
+}
@@ -0,0 +1,91 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/healthx"
+)
+
+// DefaultProbeTimeout is the timeout of PingWithTimeout and the probes if none is set.
+const DefaultProbeTimeout = 5 * time.Second
+
+type (
+	// Pinger is implemented by *sql.DB and *sql.Conn.
+	Pinger interface {
+		PingContext(ctx context.Context) error
+	}
+	// Querier is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+	Querier interface {
+		QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	}
+)
+
+// PingChecker returns a healthx.ReadyChecker which pings the database, e.g. to register it
+// using healthx.Registry.AddReadyCheck. Errors are handled by HandleError.
+func PingChecker(db Pinger) healthx.ReadyChecker {
+	return func(r *http.Request) error {
+		return HandleError(db.PingContext(r.Context()))
+	}
+}
+
+// withProbeTimeout runs the probe with the timeout and classifies its error. If the timeout
+// is exceeded, ErrConnectionFailed is returned.
+func withProbeTimeout(ctx context.Context, timeout time.Duration, probe func(ctx context.Context) error) error {
+	if timeout <= 0 {
+		timeout = DefaultProbeTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := probe(probeCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(probeCtx.Err(), context.DeadlineExceeded) {
+		return errors.WithStack(ErrConnectionFailed.WithWrap(err))
+	}
+	return HandleError(err)
+}
+
+// PingWithTimeout pings the database. Failures are classified by HandleError, e.g. as ErrAuthenticationFailed
+// or ErrConnectionFailed, which is also returned if the database does not respond within the timeout.
+func PingWithTimeout(ctx context.Context, db Pinger, timeout time.Duration) error {
+	return withProbeTimeout(ctx, timeout, db.PingContext)
+}
+
+// ReadProbe returns a healthx.ReadyChecker which runs the query, e.g. "SELECT 1 FROM schema_migration LIMIT 1".
+// Besides the failures of PingWithTimeout, it detects a missing schema as ErrNoSuchTable.
+func ReadProbe(db Querier, query string, timeout time.Duration) healthx.ReadyChecker {
+	return func(r *http.Request) error {
+		return withProbeTimeout(r.Context(), timeout, func(ctx context.Context) error {
+			rows, err := db.QueryContext(ctx, query)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+			}
+			return rows.Err()
+		})
+	}
+}
+
+// WriteProbe returns a healthx.ReadyChecker which executes the statement, e.g. "UPDATE health SET checked_at = CURRENT_TIMESTAMP",
+// in a transaction which is rolled back, so the probe does not change any data. It detects read-only replicas
+// as well as the failures of ReadProbe.
+func WriteProbe(db TxBeginner, statement string, timeout time.Duration) healthx.ReadyChecker {
+	return func(r *http.Request) error {
+		return withProbeTimeout(r.Context(), timeout, func(ctx context.Context) error {
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = tx.Rollback() }()
+
+			_, err = tx.ExecContext(ctx, statement)
+			return err
+		})
+	}
+}
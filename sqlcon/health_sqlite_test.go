@@ -0,0 +1,45 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbes(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	r := httptest.NewRequest("GET", "/health/ready", nil)
+
+	assert.ErrorIs(t, ReadProbe(db, "SELECT 1 FROM schema_migration LIMIT 1", 0)(r), ErrNoSuchTable)
+	assert.ErrorIs(t, WriteProbe(db, "INSERT INTO schema_migration (version) VALUES ('probe')", 0)(r), ErrNoSuchTable)
+
+	_, err = db.Exec("CREATE TABLE schema_migration (version TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	assert.NoError(t, ReadProbe(db, "SELECT 1 FROM schema_migration LIMIT 1", 0)(r))
+	assert.NoError(t, WriteProbe(db, "INSERT INTO schema_migration (version) VALUES ('probe')", 0)(r))
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM schema_migration").Scan(&count))
+	assert.Equal(t, 0, count, "the write probe must be rolled back")
+}
+
+func TestDialectOfSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+
+	d, err := DialectOf(context.Background(), db)
+	require.NoError(t, err)
+	assert.Equal(t, DialectSQLite, d)
+}
@@ -0,0 +1,55 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql/driver"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) PingContext(ctx context.Context) error { return f(ctx) }
+
+func TestPingChecker(t *testing.T) {
+	r := httptest.NewRequest("GET", "/health/ready", nil)
+
+	assert.NoError(t, PingChecker(pingerFunc(func(context.Context) error { return nil }))(r))
+
+	err := PingChecker(pingerFunc(func(context.Context) error { return driver.ErrBadConn }))(r)
+	assert.ErrorIs(t, err, driver.ErrBadConn)
+}
+
+func TestPingWithTimeout(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, PingWithTimeout(ctx, pingerFunc(func(context.Context) error { return nil }), 0))
+
+	err := PingWithTimeout(ctx, pingerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), time.Millisecond)
+	assert.ErrorIs(t, err, ErrConnectionFailed)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = PingWithTimeout(canceled, pingerFunc(func(ctx context.Context) error { return ctx.Err() }), time.Minute)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, ErrCanceled)
+	assert.NotErrorIs(t, err, ErrConnectionFailed)
+
+	for expected, pingErr := range map[error]error{
+		ErrAuthenticationFailed: &pgconn.PgError{Code: "28P01"},
+		ErrConnectionFailed:     &net.OpError{Op: "dial", Net: "tcp", Err: &net.AddrError{Err: "connection refused"}},
+	} {
+		pingErr := pingErr
+		err := PingWithTimeout(ctx, pingerFunc(func(context.Context) error { return pingErr }), time.Minute)
+		assert.ErrorIs(t, err, expected)
+		assert.ErrorIs(t, err, pingErr)
+	}
+}
@@ -0,0 +1,172 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/rds/auth"
+	"github.com/pkg/errors"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// DefaultTokenRefreshMargin is the time before the expiry of a token at which it is refreshed.
+	DefaultTokenRefreshMargin = time.Minute
+	// rdsTokenLifetime is the validity of AWS RDS IAM authentication tokens.
+	rdsTokenLifetime = 15 * time.Minute
+	// cloudSQLLoginScope is the OAuth2 scope of Cloud SQL IAM database authentication.
+	cloudSQLLoginScope = "https://www.googleapis.com/auth/sqlservice.login"
+)
+
+type (
+	// Token is a short-lived password, e.g. an IAM authentication token.
+	Token struct {
+		// Value is the password.
+		Value string
+		// Expiry is the time the token expires. The zero value means it does not expire.
+		Expiry time.Time
+	}
+	// TokenSource returns the password used for new connections.
+	TokenSource interface {
+		Token(ctx context.Context) (*Token, error)
+	}
+	// TokenSourceFunc implements TokenSource.
+	TokenSourceFunc   func(ctx context.Context) (*Token, error)
+	cachedTokenSource struct {
+		source TokenSource
+		margin time.Duration
+		now    func() time.Time
+
+		mu    sync.Mutex
+		token *Token
+	}
+	tokenConnector struct {
+		driver driver.Driver
+		dsn    *DSN
+		source TokenSource
+	}
+)
+
+// Token implements TokenSource.
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// CachedTokenSource returns the token of the source until it expires within the margin, which defaults to
+// DefaultTokenRefreshMargin.
+func CachedTokenSource(source TokenSource, margin time.Duration) TokenSource {
+	if margin <= 0 {
+		margin = DefaultTokenRefreshMargin
+	}
+	return &cachedTokenSource{source: source, margin: margin, now: time.Now}
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != nil && (s.token.Expiry.IsZero() || s.now().Add(s.margin).Before(s.token.Expiry)) {
+		return s.token, nil
+	}
+	token, err := s.source.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.token = token
+	return token, nil
+}
+
+// NewTokenConnector returns a connector which opens the DSN with the driver, using the token of the source as
+// the password. The tokens are cached and refreshed before they expire, see CachedTokenSource. Open the pool
+// with sql.OpenDB:
+//
+//	source := sqlcon.NewRDSTokenSource("db.example.us-east-1.rds.amazonaws.com:5432", "us-east-1", "kratos", cfg.Credentials)
+//	connector, err := sqlcon.NewTokenConnector(stdlib.GetDefaultDriver(), "postgres://kratos@db.example.us-east-1.rds.amazonaws.com:5432/kratos", source)
+//	db := sql.OpenDB(connector)
+//
+// Tokens are only checked when connecting, so established connections outlive them. Use
+// PoolOptions.MaxConnLifetime if connections must be reauthenticated.
+func NewTokenConnector(d driver.Driver, dsn string, source TokenSource) (driver.Connector, error) {
+	parsed, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenConnector{driver: d, dsn: parsed, source: CachedTokenSource(source, 0)}, nil
+}
+
+// withPassword returns the DSN with the password, which is escaped for URL DSNs.
+func (c *tokenConnector) withPassword(password string) string {
+	dsn := *c.dsn
+	dsn.Password = password
+	if dsn.Driver != "" {
+		// the user info is escaped like by url.UserPassword
+		userinfo := url.UserPassword(dsn.User, password).String()
+		dsn.Password = userinfo[strings.Index(userinfo, ":")+1:]
+	}
+	return dsn.String()
+}
+
+// Connect implements driver.Connector.
+func (c *tokenConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	token, err := c.source.Token(ctx)
+	if err != nil {
+		return nil, errors.WithMessage(err, "unable to get the database authentication token")
+	}
+
+	dsn := c.withPassword(token.Value)
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return connector.Connect(ctx)
+	}
+	return c.driver.Open(dsn)
+}
+
+// Driver implements driver.Connector.
+func (c *tokenConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// NewRDSTokenSource returns a source of AWS RDS IAM authentication tokens for the user of the database at
+// the endpoint, which includes the port, e.g. "db.example.us-east-1.rds.amazonaws.com:5432".
+func NewRDSTokenSource(endpoint, region, user string, credentials aws.CredentialsProvider) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		// the token is valid from the time it is signed
+		expiry := time.Now().Add(rdsTokenLifetime)
+		token, err := auth.BuildAuthToken(ctx, endpoint, region, user, credentials)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &Token{Value: token, Expiry: expiry}, nil
+	})
+}
+
+// NewCloudSQLTokenSource adapts the OAuth2 access tokens of the source for Cloud SQL IAM database
+// authentication. The source must request the scope "https://www.googleapis.com/auth/sqlservice.login".
+func NewCloudSQLTokenSource(source oauth2.TokenSource) TokenSource {
+	return TokenSourceFunc(func(context.Context) (*Token, error) {
+		token, err := source.Token()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return &Token{Value: token.AccessToken, Expiry: token.Expiry}, nil
+	})
+}
+
+// DefaultCloudSQLTokenSource returns a source of Cloud SQL IAM database authentication tokens using the
+// application default credentials, see google.DefaultTokenSource.
+func DefaultCloudSQLTokenSource(ctx context.Context) (TokenSource, error) {
+	source, err := google.DefaultTokenSource(ctx, cloudSQLLoginScope)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return NewCloudSQLTokenSource(source), nil
+}
@@ -0,0 +1,84 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestCachedTokenSource(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	calls := 0
+	source := CachedTokenSource(TokenSourceFunc(func(context.Context) (*Token, error) {
+		calls++
+		return &Token{Value: "token", Expiry: now.Add(10 * time.Minute)}, nil
+	}), time.Minute).(*cachedTokenSource)
+	source.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		_, err := source.Token(ctx)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+
+	now = now.Add(9*time.Minute + time.Second)
+	_, err := source.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "the token must be refreshed before it expires")
+}
+
+func TestTokenConnector(t *testing.T) {
+	tokens := []string{"first/token?with=chars", "second"}
+	connector, err := NewTokenConnector(testDriver, "postgres://kratos@db:5432/kratos?sslmode=verify-full", TokenSourceFunc(func(context.Context) (*Token, error) {
+		token := &Token{Value: tokens[0], Expiry: time.Now()}
+		tokens = tokens[1:]
+		return token, nil
+	}))
+	require.NoError(t, err)
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	db.SetMaxIdleConns(0)
+
+	for _, expected := range []string{"first/token?with=chars", "second"} {
+		rows, err := db.QueryContext(context.Background(), "SELECT dsn")
+		u, perr := url.Parse(servedBy(t, rows, err))
+		require.NoError(t, perr)
+		password, _ := u.User.Password()
+		assert.Equal(t, expected, password)
+		assert.Equal(t, "verify-full", u.Query().Get("sslmode"))
+	}
+
+	connector, err = NewTokenConnector(testDriver, "kratos@tcp(db:3306)/kratos", TokenSourceFunc(func(context.Context) (*Token, error) {
+		return &Token{Value: "a&b=c"}, nil
+	}))
+	require.NoError(t, err)
+	rows, err := sql.OpenDB(connector).QueryContext(context.Background(), "SELECT dsn")
+	assert.Equal(t, "kratos:a&b=c@tcp(db:3306)/kratos", servedBy(t, rows, err))
+}
+
+func TestTokenSources(t *testing.T) {
+	ctx := context.Background()
+
+	credentials := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+	})
+	token, err := NewRDSTokenSource("db.example.us-east-1.rds.amazonaws.com:5432", "us-east-1", "kratos", credentials).Token(ctx)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token.Value, "db.example.us-east-1.rds.amazonaws.com:5432?Action=connect&DBUser=kratos&"), token.Value)
+	assert.Contains(t, token.Value, "X-Amz-Signature=")
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), token.Expiry, time.Minute)
+
+	expiry := time.Now().Add(time.Hour)
+	token, err = NewCloudSQLTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "access", Expiry: expiry})).Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &Token{Value: "access", Expiry: expiry}, token)
+}
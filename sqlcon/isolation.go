@@ -0,0 +1,45 @@
+package sqlcon
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// TxOptions returns the options of transactions with the isolation level, which is one of sql.LevelDefault,
+// sql.LevelReadCommitted, sql.LevelRepeatableRead and sql.LevelSerializable, and access mode of the dialect:
+//
+//	txOpts, err := sqlcon.DialectMySQL.TxOptions(sql.LevelRepeatableRead, true)
+//	if err != nil {
+//		return err
+//	}
+//	return sqlcon.WithTransaction(ctx, db, &sqlcon.TransactionOptions{TxOptions: txOpts}, fn)
+//
+// The level is raised to the one the database actually runs the transaction with, if it only supports stronger
+// levels: CockroachDB runs all transactions as SERIALIZABLE, and SQLite serializes all transactions, while its
+// drivers reject or ignore isolation levels. Read-only transactions are not supported by SQLite and SQL Server,
+// as their drivers ignore or reject them, so an error is returned instead of silently allowing writes.
+func (d Dialect) TxOptions(isolation sql.IsolationLevel, readOnly bool) (*sql.TxOptions, error) {
+	switch isolation {
+	case sql.LevelDefault, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSerializable:
+	default:
+		return nil, errors.Errorf("the isolation level %s is not supported", isolation)
+	}
+
+	switch d {
+	case DialectPostgres, DialectMySQL, DialectSQLServer:
+	case DialectCockroach:
+		if isolation != sql.LevelDefault {
+			isolation = sql.LevelSerializable
+		}
+	case DialectSQLite:
+		isolation = sql.LevelDefault
+	default:
+		return nil, errors.Errorf("unknown dialect %q", d)
+	}
+
+	if readOnly && !d.Capabilities().ReadOnlyTransactions {
+		return nil, errors.Errorf("read-only transactions are not supported by %s", d)
+	}
+	return &sql.TxOptions{Isolation: isolation, ReadOnly: readOnly}, nil
+}
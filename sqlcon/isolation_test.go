@@ -0,0 +1,44 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialectTxOptions(t *testing.T) {
+	for _, tc := range []struct {
+		dialect   Dialect
+		isolation sql.IsolationLevel
+		readOnly  bool
+		expected  sql.IsolationLevel
+	}{
+		{dialect: DialectPostgres, isolation: sql.LevelReadCommitted, expected: sql.LevelReadCommitted},
+		{dialect: DialectPostgres, isolation: sql.LevelSerializable, readOnly: true, expected: sql.LevelSerializable},
+		{dialect: DialectMySQL, isolation: sql.LevelRepeatableRead, readOnly: true, expected: sql.LevelRepeatableRead},
+		{dialect: DialectSQLServer, isolation: sql.LevelReadCommitted, expected: sql.LevelReadCommitted},
+		{dialect: DialectCockroach, isolation: sql.LevelReadCommitted, readOnly: true, expected: sql.LevelSerializable},
+		{dialect: DialectCockroach, isolation: sql.LevelDefault, expected: sql.LevelDefault},
+		{dialect: DialectSQLite, isolation: sql.LevelRepeatableRead, expected: sql.LevelDefault},
+	} {
+		t.Run(fmt.Sprintf("dialect=%s/isolation=%s/read_only=%t", tc.dialect, tc.isolation, tc.readOnly), func(t *testing.T) {
+			opts, err := tc.dialect.TxOptions(tc.isolation, tc.readOnly)
+			require.NoError(t, err)
+			assert.Equal(t, &sql.TxOptions{Isolation: tc.expected, ReadOnly: tc.readOnly}, opts)
+		})
+	}
+
+	t.Run("case=unsupported", func(t *testing.T) {
+		_, err := DialectSQLite.TxOptions(sql.LevelDefault, true)
+		assert.EqualError(t, err, "read-only transactions are not supported by sqlite")
+		_, err = DialectSQLServer.TxOptions(sql.LevelSerializable, true)
+		assert.EqualError(t, err, "read-only transactions are not supported by sqlserver")
+		_, err = DialectPostgres.TxOptions(sql.LevelLinearizable, false)
+		assert.EqualError(t, err, "the isolation level Linearizable is not supported")
+		_, err = Dialect("oracle").TxOptions(sql.LevelDefault, false)
+		assert.EqualError(t, err, `unknown dialect "oracle"`)
+	})
+}
@@ -0,0 +1,127 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/healthx"
+)
+
+// postgresLagQuery returns the seconds since the last replayed transaction, which is 0 if the standby replayed
+// everything it received, and for primaries.
+const postgresLagQuery = `SELECT CASE WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END`
+
+// ReplicationLag returns how far the replica lags behind its primary, which is 0 if the database is not a
+// replica:
+//
+//   - postgres: the time since the last transaction replayed by the standby, unless it replayed all WAL it
+//     received, based on pg_last_wal_replay_lsn and pg_last_xact_replay_timestamp
+//   - mysql: Seconds_Behind_Source of SHOW REPLICA STATUS, or Seconds_Behind_Master of SHOW SLAVE STATUS before
+//     MySQL 8.0.22
+//
+// If the replica does not replicate, e.g. because the replication threads of MySQL stopped, ErrReplicationLag
+// is returned. Other dialects return an *UnsupportedError: CockroachDB does not expose the lag, and SQLite and
+// SQL Server are not supported.
+func ReplicationLag(ctx context.Context, db Querier, dialect Dialect) (time.Duration, error) {
+	switch dialect {
+	case DialectPostgres:
+		return postgresReplicationLag(ctx, db)
+	case DialectMySQL:
+		return mysqlReplicationLag(ctx, db)
+	case DialectCockroach, DialectSQLite, DialectSQLServer:
+		return 0, errors.WithStack(&UnsupportedError{Dialect: dialect, Feature: "replication lag"})
+	}
+	return 0, errors.Errorf("unknown dialect %q", dialect)
+}
+
+func postgresReplicationLag(ctx context.Context, db Querier) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, postgresLagQuery)
+	if err != nil {
+		return 0, HandleError(err)
+	}
+	defer rows.Close()
+
+	var seconds sql.NullFloat64
+	if rows.Next() {
+		if err := rows.Scan(&seconds); err != nil {
+			return 0, HandleError(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, HandleError(err)
+	}
+	if !seconds.Valid {
+		return 0, errors.WithStack(ErrReplicationLag.WithReason("The standby has not replayed any transactions."))
+	}
+	return time.Duration(seconds.Float64 * float64(time.Second)), nil
+}
+
+func mysqlReplicationLag(ctx context.Context, db Querier) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	// ER_PARSE_ERROR, the statement was added by MySQL 8.0.22
+	if e := new(mysql.MySQLError); errors.As(err, &e) && e.Number == 1064 {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, HandleError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, HandleError(err)
+	}
+	lag := -1
+	values := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if strings.EqualFold(column, "Seconds_Behind_Source") || strings.EqualFold(column, "Seconds_Behind_Master") {
+			lag = i
+		}
+		values[i] = new(sql.RawBytes)
+	}
+	if lag < 0 {
+		return 0, errors.New("the replica status does not contain the seconds behind the source")
+	}
+
+	var seconds sql.NullInt64
+	values[lag] = &seconds
+	if !rows.Next() {
+		// the database is not a replica
+		return 0, HandleError(rows.Err())
+	}
+	if err := rows.Scan(values...); err != nil {
+		return 0, HandleError(err)
+	}
+	if !seconds.Valid {
+		return 0, errors.WithStack(ErrReplicationLag.WithReason("The replication threads of the replica are not running."))
+	}
+	return time.Duration(seconds.Int64) * time.Second, nil
+}
+
+// checkReplicationLag returns ErrReplicationLag if the replica lags behind by more than max.
+func checkReplicationLag(ctx context.Context, db Querier, dialect Dialect, max time.Duration) error {
+	lag, err := ReplicationLag(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+	if lag > max {
+		return errors.WithStack(ErrReplicationLag.WithReasonf("The replica lags behind by %s, but at most %s are tolerated.", lag, max))
+	}
+	return nil
+}
+
+// ReplicationLagChecker returns a healthx.ReadyChecker which fails with ErrReplicationLag if the replica lags
+// behind its primary by more than max, see ReplicationLag. The lag is queried within the DefaultProbeTimeout.
+func ReplicationLagChecker(db Querier, dialect Dialect, max time.Duration) healthx.ReadyChecker {
+	return func(r *http.Request) error {
+		return withProbeTimeout(r.Context(), 0, func(ctx context.Context) error {
+			return checkReplicationLag(ctx, db, dialect, max)
+		})
+	}
+}
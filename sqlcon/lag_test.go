@@ -0,0 +1,44 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplicationLag(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlcon-fake", "replica")
+	require.NoError(t, err)
+	defer db.Close()
+
+	for _, dialect := range []Dialect{DialectPostgres, DialectMySQL} {
+		t.Run("dialect="+dialect.String(), func(t *testing.T) {
+			testDriver.setLag("replica", int64(3))
+			lag, err := ReplicationLag(ctx, db, dialect)
+			require.NoError(t, err)
+			assert.Equal(t, 3*time.Second, lag)
+
+			r := httptest.NewRequest("GET", "/health/ready", nil)
+			assert.NoError(t, ReplicationLagChecker(db, dialect, 5*time.Second)(r))
+			assert.ErrorIs(t, ReplicationLagChecker(db, dialect, time.Second)(r), ErrReplicationLag)
+
+			testDriver.setLag("replica", nil)
+			_, err = ReplicationLag(ctx, db, dialect)
+			assert.ErrorIs(t, err, ErrReplicationLag)
+		})
+	}
+
+	for _, dialect := range []Dialect{DialectCockroach, DialectSQLite, DialectSQLServer} {
+		_, err := ReplicationLag(ctx, db, dialect)
+		var unsupported *UnsupportedError
+		assert.ErrorAs(t, err, &unsupported, dialect)
+	}
+	_, err = ReplicationLag(ctx, db, "oracle")
+	assert.EqualError(t, err, `unknown dialect "oracle"`)
+}
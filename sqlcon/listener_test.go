@@ -0,0 +1,117 @@
+package sqlcon
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNotificationConn struct {
+	execs         []string
+	notifications chan *Notification
+	err           error
+}
+
+func (c *fakeNotificationConn) Exec(_ context.Context, sql string) error {
+	c.execs = append(c.execs, sql)
+	return nil
+}
+
+func (c *fakeNotificationConn) WaitForNotification(ctx context.Context) (*Notification, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case n, ok := <-c.notifications:
+		if !ok {
+			return nil, c.err
+		}
+		return n, nil
+	}
+}
+
+func (c *fakeNotificationConn) Close(context.Context) error { return nil }
+
+func TestListener(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Millisecond}
+
+	t.Run("case=delivers notifications and reconnects", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		first := &fakeNotificationConn{notifications: make(chan *Notification, 1), err: errors.New("unexpected EOF")}
+		second := &fakeNotificationConn{notifications: make(chan *Notification, 1)}
+		conns := []*fakeNotificationConn{first, second}
+
+		var errs []error
+		reconnected := 0
+		l := &Listener{
+			Channels:    []string{"identities", `odd"name`},
+			RetryPolicy: policy,
+			OnError:     func(err error) { errs = append(errs, err) },
+			OnReconnect: func() { reconnected++ },
+			connect: func(context.Context, string) (notificationConn, error) {
+				conn := conns[0]
+				conns = conns[1:]
+				return conn, nil
+			},
+		}
+
+		first.notifications <- &Notification{Channel: "identities", Payload: "1"}
+		close(first.notifications)
+		second.notifications <- &Notification{Channel: "identities", Payload: "2"}
+
+		var received []string
+		require.NoError(t, l.Listen(ctx, func(n Notification) {
+			received = append(received, n.Payload)
+			if len(received) == 2 {
+				cancel()
+			}
+		}))
+
+		assert.Equal(t, []string{"1", "2"}, received)
+		assert.Equal(t, []string{`LISTEN "identities"`, `LISTEN "odd""name"`}, first.execs)
+		assert.Equal(t, first.execs, second.execs)
+		assert.Equal(t, 1, reconnected)
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "unexpected EOF")
+	})
+
+	t.Run("case=returns on authentication failures", func(t *testing.T) {
+		attempts := 0
+		l := &Listener{
+			Channels:    []string{"identities"},
+			RetryPolicy: policy,
+			connect: func(context.Context, string) (notificationConn, error) {
+				attempts++
+				return nil, &pgconnv5.PgError{Code: "28P01"}
+			},
+		}
+
+		assert.ErrorIs(t, l.Listen(context.Background(), func(Notification) {}), ErrAuthenticationFailed)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("case=gives up after max attempts", func(t *testing.T) {
+		attempts := 0
+		l := &Listener{
+			Channels:    []string{"identities"},
+			RetryPolicy: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+			connect: func(context.Context, string) (notificationConn, error) {
+				attempts++
+				return nil, &pgconnv5.PgError{Code: "08006"}
+			},
+		}
+
+		assert.ErrorIs(t, l.Listen(context.Background(), func(Notification) {}), ErrConnectionFailed)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("case=requires channels", func(t *testing.T) {
+		assert.Error(t, (&Listener{}).Listen(context.Background(), func(Notification) {}))
+	})
+}
@@ -0,0 +1,160 @@
+package sqlcon
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+	"github.com/pkg/errors"
+)
+
+// lockFilePollInterval is the interval in which a file lock held by another process is retried.
+const lockFilePollInterval = 50 * time.Millisecond
+
+// Lock is a named lock held by AcquireLock. It is bound to a single connection, or lock file for SQLite,
+// so it is released if the process terminates.
+type Lock struct {
+	name    string
+	release func(ctx context.Context) error
+}
+
+// Name returns the name of the lock.
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Release releases the lock. It is safe to call it more than once.
+func (l *Lock) Release(ctx context.Context) error {
+	if l.release == nil {
+		return nil
+	}
+	release := l.release
+	l.release = nil
+	return release(ctx)
+}
+
+// AcquireLock acquires the named lock across all sessions of the database, waiting until it is released by
+// the holder or the context is done. The locking mechanism depends on the dialect:
+//
+//   - postgres: a session-level advisory lock (pg_advisory_lock), keyed by the hash of the name
+//   - mysql: a user-level lock (GET_LOCK), using the hash of names longer than 64 characters
+//   - sqlite: a file lock next to the database file, or in the temporary directory for in-memory databases
+//
+// The lock must be released with Lock.Release, or use WithLock instead.
+func AcquireLock(ctx context.Context, db *sql.DB, dialect Dialect, name string) (*Lock, error) {
+	switch dialect {
+	case DialectPostgres:
+		key := advisoryLockKey(name)
+		return acquireConnLock(ctx, db, name, func(ctx context.Context, conn *sql.Conn) error {
+			_, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key)
+			return err
+		}, "SELECT pg_advisory_unlock($1)", key)
+	case DialectMySQL:
+		key := mysqlLockName(name)
+		return acquireConnLock(ctx, db, name, func(ctx context.Context, conn *sql.Conn) error {
+			// GET_LOCK returns 0 on timeouts, which the negative timeout disables, and NULL on errors
+			var acquired sql.NullInt64
+			if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, -1)", key).Scan(&acquired); err != nil {
+				return err
+			} else if acquired.Int64 != 1 {
+				return errors.Errorf("unable to acquire the lock %q", name)
+			}
+			return nil
+		}, "SELECT RELEASE_LOCK(?)", key)
+	case DialectSQLite:
+		return acquireFileLock(ctx, db, name)
+	}
+	return nil, errors.Errorf("named locks are not supported by the dialect %q", dialect)
+}
+
+// WithLock runs fn while holding the named lock, see AcquireLock. The lock is released when fn returns.
+func WithLock(ctx context.Context, db *sql.DB, dialect Dialect, name string, fn func(ctx context.Context) error) (err error) {
+	lock, err := AcquireLock(ctx, db, dialect, name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// release even if the context is done, so that the lock is not held until the connection breaks
+		if rerr := lock.Release(context.Background()); err == nil {
+			err = rerr
+		}
+	}()
+	return fn(ctx)
+}
+
+// advisoryLockKey hashes the name to the key of the PostgreSQL advisory lock.
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+// mysqlLockName hashes names exceeding the maximum length of 64 characters of MySQL lock names.
+func mysqlLockName(name string) string {
+	if len(name) <= 64 {
+		return name
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(name)))
+}
+
+// acquireConnLock holds a connection of the pool for the lock, because the lock belongs to the session.
+func acquireConnLock(ctx context.Context, db *sql.DB, name string, lock func(context.Context, *sql.Conn) error, unlock string, key interface{}) (*Lock, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, HandleError(err)
+	}
+
+	// discard discards the connection instead of putting it back into the pool, which releases
+	// the lock if the session still holds it
+	discard := func() {
+		_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		_ = conn.Close()
+	}
+
+	if err := lock(ctx, conn); err != nil {
+		discard()
+		return nil, HandleError(err)
+	}
+
+	return &Lock{name: name, release: func(ctx context.Context) error {
+		if _, err := conn.ExecContext(ctx, unlock, key); err != nil {
+			discard()
+			return HandleError(err)
+		}
+		return errors.WithStack(conn.Close())
+	}}, nil
+}
+
+// acquireFileLock locks a file, because SQLite has no named locks.
+func acquireFileLock(ctx context.Context, db *sql.DB, name string) (*Lock, error) {
+	var seq int
+	var schema, file string
+	if err := db.QueryRowContext(ctx, "PRAGMA database_list").Scan(&seq, &schema, &file); err != nil {
+		return nil, HandleError(err)
+	}
+
+	suffix := fmt.Sprintf(".%x.lock", uint64(advisoryLockKey(name)))
+	path := file + suffix
+	if file == "" {
+		path = filepath.Join(os.TempDir(), "sqlcon"+suffix)
+	}
+
+	f := flock.New(path)
+	locked, err := f.TryLockContext(ctx, lockFilePollInterval)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	} else if !locked {
+		return nil, errors.Errorf("unable to acquire the lock %q", name)
+	}
+
+	return &Lock{name: name, release: func(context.Context) error {
+		return errors.WithStack(f.Unlock())
+	}}, nil
+}
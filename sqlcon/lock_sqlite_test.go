@@ -0,0 +1,39 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLockSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "db.sqlite"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	lock, err := AcquireLock(ctx, db, "sqlite", "leader")
+	require.NoError(t, err)
+	assert.Equal(t, "leader", lock.Name())
+
+	timeout, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = AcquireLock(timeout, db, "sqlite", "leader")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	other, err := AcquireLock(ctx, db, "sqlite", "migrations")
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+	require.NoError(t, lock.Release(ctx))
+	require.NoError(t, WithLock(ctx, db, "sqlite", "leader", func(context.Context) error { return nil }))
+}
@@ -0,0 +1,48 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLock(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlcon-fake", "lock")
+	require.NoError(t, err)
+	defer db.Close()
+
+	t.Run("case=postgres advisory lock", func(t *testing.T) {
+		testDriver.mu.Lock()
+		testDriver.execs = nil
+		testDriver.mu.Unlock()
+
+		var inside bool
+		require.NoError(t, WithLock(ctx, db, "postgres", "migrations", func(context.Context) error {
+			inside = true
+			return nil
+		}))
+		assert.True(t, inside)
+
+		testDriver.mu.Lock()
+		defer testDriver.mu.Unlock()
+		assert.Equal(t, []string{"SELECT pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)"}, testDriver.execs)
+	})
+
+	t.Run("case=unsupported dialect", func(t *testing.T) {
+		_, err := AcquireLock(ctx, db, "sqlserver", "migrations")
+		assert.Error(t, err)
+	})
+
+	t.Run("case=lock names", func(t *testing.T) {
+		assert.Equal(t, advisoryLockKey("migrations"), advisoryLockKey("migrations"))
+		assert.NotEqual(t, advisoryLockKey("migrations"), advisoryLockKey("leader"))
+
+		assert.Equal(t, "migrations", mysqlLockName("migrations"))
+		assert.Len(t, mysqlLockName(strings.Repeat("a", 65)), 64)
+	})
+}
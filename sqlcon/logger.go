@@ -0,0 +1,43 @@
+package sqlcon
+
+import (
+	"time"
+
+	"github.com/ory/x/logx"
+)
+
+// DefaultSlowQueryThreshold is the threshold of SlowQueryLogger if none is set.
+const DefaultSlowQueryThreshold = time.Second
+
+// SlowQueryLogger logs queries which take longer than Threshold. The query and error are redacted,
+// see Redactor and RedactError.
+type SlowQueryLogger struct {
+	// Logger receives the slow queries at warn level.
+	Logger logx.Logger
+	// Threshold is the duration above which a query is considered slow.
+	// Default: DefaultSlowQueryThreshold
+	Threshold time.Duration
+	// Redactor redacts the logged queries.
+	// Default: RedactLiterals
+	Redactor Redactor
+}
+
+// Observe logs the query if it was slow. It is safe to call on a nil SlowQueryLogger.
+func (s *SlowQueryLogger) Observe(query string, took time.Duration, err error) {
+	if s == nil || s.Logger == nil {
+		return
+	}
+	threshold := s.Threshold
+	if threshold <= 0 {
+		threshold = DefaultSlowQueryThreshold
+	}
+	if took < threshold {
+		return
+	}
+
+	args := []interface{}{"query", s.Redactor.Redact(query), "took", took, "threshold", threshold}
+	if err != nil {
+		args = append(args, "error", RedactError(err))
+	}
+	s.Logger.Warn("slow query", args...)
+}
@@ -0,0 +1,44 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingLogger struct {
+	messages []string
+	args     [][]interface{}
+}
+
+func (l *recordingLogger) log(msg string, args ...interface{}) {
+	l.messages = append(l.messages, msg)
+	l.args = append(l.args, args)
+}
+
+func (l *recordingLogger) Debug(msg string, args ...interface{}) { l.log(msg, args...) }
+func (l *recordingLogger) Info(msg string, args ...interface{})  { l.log(msg, args...) }
+func (l *recordingLogger) Warn(msg string, args ...interface{})  { l.log(msg, args...) }
+func (l *recordingLogger) Error(msg string, args ...interface{}) { l.log(msg, args...) }
+
+func TestSlowQueryLogger(t *testing.T) {
+	l := new(recordingLogger)
+	s := &SlowQueryLogger{Logger: l, Threshold: 100 * time.Millisecond}
+
+	s.Observe("SELECT 1", 10*time.Millisecond, nil)
+	assert.Empty(t, l.messages)
+
+	s.Observe("SELECT * FROM users WHERE id = ? AND email = 'foo@bar.com'", 200*time.Millisecond, sql.ErrNoRows)
+	assert.Equal(t, []string{"slow query"}, l.messages)
+	assert.Equal(t, "SELECT * FROM users WHERE id = ? AND email = ?", l.args[0][1])
+	assert.ErrorIs(t, l.args[0][7].(error), ErrNoRows)
+
+	s.Redactor = RedactAll
+	s.Observe("SELECT * FROM users WHERE id = ?", 200*time.Millisecond, nil)
+	assert.Equal(t, "SELECT", l.args[1][1])
+
+	var nilLogger *SlowQueryLogger
+	nilLogger.Observe("SELECT 1", time.Hour, nil)
+}
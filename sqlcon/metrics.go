@@ -0,0 +1,117 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultPoolMetricsInterval is the interval of PoolMetrics.Run if none is set.
+const DefaultPoolMetricsInterval = 15 * time.Second
+
+// Statser is implemented by *sql.DB.
+type Statser interface {
+	Stats() sql.DBStats
+}
+
+// PoolMetrics exports the statistics of connection pools as Prometheus metrics, labeled by "pool".
+// It is a prometheus.Collector:
+//
+//	m := sqlcon.NewPoolMetrics("kratos")
+//	prometheus.MustRegister(m)
+//	go m.Run(ctx, "primary", db, 0)
+type PoolMetrics struct {
+	maxOpen      *prometheus.GaugeVec
+	open         *prometheus.GaugeVec
+	inUse        *prometheus.GaugeVec
+	idle         *prometheus.GaugeVec
+	waitCount    *prometheus.CounterVec
+	waitDuration *prometheus.CounterVec
+
+	mu   sync.Mutex
+	last map[string]sql.DBStats
+}
+
+// NewPoolMetrics creates the metrics. The prefix is prepended to the metric names, separated by "_".
+func NewPoolMetrics(prefix string) *PoolMetrics {
+	if prefix != "" {
+		prefix += "_"
+	}
+	gauge := func(name, help string) *prometheus.GaugeVec {
+		return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: prefix + name, Help: help}, []string{"pool"})
+	}
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: prefix + name, Help: help}, []string{"pool"})
+	}
+
+	return &PoolMetrics{
+		maxOpen:      gauge("sql_pool_max_open_connections", "maximum number of open connections to the database"),
+		open:         gauge("sql_pool_open_connections", "number of established connections, both in use and idle"),
+		inUse:        gauge("sql_pool_in_use_connections", "number of connections currently in use"),
+		idle:         gauge("sql_pool_idle_connections", "number of idle connections"),
+		waitCount:    counter("sql_pool_wait_count_total", "total number of connections waited for"),
+		waitDuration: counter("sql_pool_wait_duration_seconds_total", "total time blocked waiting for a new connection"),
+		last:         map[string]sql.DBStats{},
+	}
+}
+
+// Observe records the statistics of the pool.
+func (m *PoolMetrics) Observe(pool string, stats sql.DBStats) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.maxOpen.WithLabelValues(pool).Set(float64(stats.MaxOpenConnections))
+	m.open.WithLabelValues(pool).Set(float64(stats.OpenConnections))
+	m.inUse.WithLabelValues(pool).Set(float64(stats.InUse))
+	m.idle.WithLabelValues(pool).Set(float64(stats.Idle))
+
+	// the statistics are cumulative, while counters can only be increased
+	last := m.last[pool]
+	if stats.WaitCount >= last.WaitCount && stats.WaitDuration >= last.WaitDuration {
+		m.waitCount.WithLabelValues(pool).Add(float64(stats.WaitCount - last.WaitCount))
+		m.waitDuration.WithLabelValues(pool).Add((stats.WaitDuration - last.WaitDuration).Seconds())
+	}
+	m.last[pool] = stats
+}
+
+// Run observes the pool immediately and then every interval until the context is canceled.
+// The interval defaults to DefaultPoolMetricsInterval.
+func (m *PoolMetrics) Run(ctx context.Context, pool string, db Statser, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultPoolMetricsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Observe(pool, db.Stats())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *PoolMetrics) Describe(in chan<- *prometheus.Desc) {
+	m.maxOpen.Describe(in)
+	m.open.Describe(in)
+	m.inUse.Describe(in)
+	m.idle.Describe(in)
+	m.waitCount.Describe(in)
+	m.waitDuration.Describe(in)
+}
+
+// Collect implements prometheus.Collector.
+func (m *PoolMetrics) Collect(in chan<- prometheus.Metric) {
+	m.maxOpen.Collect(in)
+	m.open.Collect(in)
+	m.inUse.Collect(in)
+	m.idle.Collect(in)
+	m.waitCount.Collect(in)
+	m.waitDuration.Collect(in)
+}
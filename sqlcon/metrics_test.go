@@ -0,0 +1,60 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type statserFunc func() sql.DBStats
+
+func (f statserFunc) Stats() sql.DBStats { return f() }
+
+func TestPoolMetrics(t *testing.T) {
+	m := NewPoolMetrics("test")
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(m))
+
+	m.Observe("primary", sql.DBStats{MaxOpenConnections: 10, OpenConnections: 4, InUse: 3, Idle: 1, WaitCount: 2, WaitDuration: time.Second})
+	m.Observe("primary", sql.DBStats{MaxOpenConnections: 10, OpenConnections: 5, InUse: 5, WaitCount: 5, WaitDuration: 3 * time.Second})
+	m.Observe("replica", sql.DBStats{OpenConnections: 1, Idle: 1})
+
+	assert.Equal(t, float64(10), testutil.ToFloat64(m.maxOpen.WithLabelValues("primary")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(m.open.WithLabelValues("primary")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(m.inUse.WithLabelValues("primary")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.idle.WithLabelValues("primary")))
+	assert.Equal(t, float64(5), testutil.ToFloat64(m.waitCount.WithLabelValues("primary")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.waitDuration.WithLabelValues("primary")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.idle.WithLabelValues("replica")))
+
+	count, err := testutil.GatherAndCount(registry, "test_sql_pool_open_connections")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestPoolMetricsRun(t *testing.T) {
+	m := NewPoolMetrics("")
+	ctx, cancel := context.WithCancel(context.Background())
+
+	observed := make(chan struct{}, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx, "primary", statserFunc(func() sql.DBStats {
+			observed <- struct{}{}
+			return sql.DBStats{OpenConnections: 2}
+		}), time.Millisecond)
+	}()
+
+	<-observed
+	<-observed
+	cancel()
+	<-done
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.open.WithLabelValues("primary")))
+}
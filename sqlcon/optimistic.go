@@ -0,0 +1,105 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultVersionColumn is the version column of VersionedUpdate if none is set.
+const DefaultVersionColumn = "version"
+
+// VersionedUpdate builds UPDATE statements for optimistic concurrency control: the row is only updated if its
+// version column still has the version which was read, and the version is incremented. Pass the result to
+// CheckVersionedUpdate, which detects that a concurrent update changed the version:
+//
+//	u := &sqlcon.VersionedUpdate{Dialect: sqlcon.DialectPostgres, Table: "identities", Columns: []string{"state"}, KeyColumns: []string{"id"}}
+//	query, args, err := u.Build([]interface{}{state}, []interface{}{id}, identity.Version)
+//	if err != nil {
+//		return err
+//	}
+//	err = sqlcon.CheckVersionedUpdate(db.ExecContext(ctx, query, args...))
+type VersionedUpdate struct {
+	// Dialect determines the quotes of identifiers and the placeholders.
+	Dialect Dialect
+	// Table is the updated table, optionally qualified by the schema.
+	Table string
+	// Columns are the updated columns, in the order of the values.
+	Columns []string
+	// KeyColumns identify the updated row, in the order of the key values.
+	KeyColumns []string
+	// VersionColumn is the integer column holding the version.
+	// Default: DefaultVersionColumn
+	VersionColumn string
+}
+
+// Build returns the statement updating the columns to the values in the row identified by the key, if its
+// version is the given one, and the arguments of the statement.
+func (u *VersionedUpdate) Build(values, key []interface{}, version int64) (string, []interface{}, error) {
+	if len(u.Columns) == 0 {
+		return "", nil, errors.New("no columns to update")
+	} else if len(u.KeyColumns) == 0 {
+		return "", nil, errors.New("no key columns to identify the row")
+	} else if len(values) != len(u.Columns) {
+		return "", nil, errors.Errorf("%d values are set for %d columns", len(values), len(u.Columns))
+	} else if len(key) != len(u.KeyColumns) {
+		return "", nil, errors.Errorf("%d key values are set for %d key columns", len(key), len(u.KeyColumns))
+	}
+
+	versionColumn := u.VersionColumn
+	if versionColumn == "" {
+		versionColumn = DefaultVersionColumn
+	}
+	table, err := quoteIdentifier(u.Dialect, u.Table)
+	if err != nil {
+		return "", nil, err
+	}
+	columns, err := quoteIdentifiers(u.Dialect, u.Columns)
+	if err != nil {
+		return "", nil, err
+	}
+	keyColumns, err := quoteIdentifiers(u.Dialect, u.KeyColumns)
+	if err != nil {
+		return "", nil, err
+	}
+	versioned, err := quoteIdentifier(u.Dialect, versionColumn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := make([]interface{}, 0, len(values)+len(key)+1)
+	set := make([]string, 0, len(columns)+1)
+	for i, c := range columns {
+		args = append(args, values[i])
+		set = append(set, fmt.Sprintf("%s = %s", c, u.Dialect.Placeholder(len(args))))
+	}
+	set = append(set, fmt.Sprintf("%s = %s + 1", versioned, versioned))
+
+	where := make([]string, 0, len(keyColumns)+1)
+	for i, c := range keyColumns {
+		args = append(args, key[i])
+		where = append(where, fmt.Sprintf("%s = %s", c, u.Dialect.Placeholder(len(args))))
+	}
+	args = append(args, version)
+	where = append(where, fmt.Sprintf("%s = %s", versioned, u.Dialect.Placeholder(len(args))))
+
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(set, ", "), strings.Join(where, " AND ")), args, nil
+}
+
+// CheckVersionedUpdate returns ErrConcurrentUpdate if the statement built by VersionedUpdate did not update a
+// row, because the version was changed by a concurrent update. If the row may not exist at all, check that
+// separately, as it can not be distinguished by the result. Other errors are handled by HandleError.
+func CheckVersionedUpdate(result sql.Result, err error) error {
+	if err != nil {
+		return HandleError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return HandleError(err)
+	} else if affected == 0 {
+		return errors.WithStack(ErrConcurrentUpdate)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedUpdateSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY, state TEXT NOT NULL, version INTEGER NOT NULL); INSERT INTO identities VALUES (1, 'active', 1)")
+	require.NoError(t, err)
+
+	u := &VersionedUpdate{Dialect: DialectSQLite, Table: "identities", Columns: []string{"state"}, KeyColumns: []string{"id"}}
+	update := func(state string, version int64) error {
+		query, args, err := u.Build([]interface{}{state}, []interface{}{1}, version)
+		require.NoError(t, err)
+		return CheckVersionedUpdate(db.ExecContext(ctx, query, args...))
+	}
+
+	require.NoError(t, update("inactive", 1))
+	assert.ErrorIs(t, update("active", 1), ErrConcurrentUpdate, "the version was incremented by the first update")
+
+	var state string
+	var version int64
+	require.NoError(t, db.QueryRow("SELECT state, version FROM identities WHERE id = 1").Scan(&state, &version))
+	assert.Equal(t, "inactive", state)
+	assert.Equal(t, int64(2), version)
+}
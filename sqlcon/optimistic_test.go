@@ -0,0 +1,39 @@
+package sqlcon
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionedUpdate(t *testing.T) {
+	for dialect, expected := range map[Dialect]string{
+		DialectPostgres:  `UPDATE "identities" SET "state" = $1, "version" = "version" + 1 WHERE "nid" = $2 AND "id" = $3 AND "version" = $4`,
+		DialectMySQL:     "UPDATE `identities` SET `state` = ?, `version` = `version` + 1 WHERE `nid` = ? AND `id` = ? AND `version` = ?",
+		DialectSQLServer: `UPDATE "identities" SET "state" = @p1, "version" = "version" + 1 WHERE "nid" = @p2 AND "id" = @p3 AND "version" = @p4`,
+	} {
+		u := &VersionedUpdate{Dialect: dialect, Table: "identities", Columns: []string{"state"}, KeyColumns: []string{"nid", "id"}}
+		query, args, err := u.Build([]interface{}{"active"}, []interface{}{"n", 1}, 3)
+		require.NoError(t, err, dialect)
+		assert.Equal(t, expected, query, dialect)
+		assert.Equal(t, []interface{}{"active", "n", 1, int64(3)}, args, dialect)
+	}
+
+	u := &VersionedUpdate{Dialect: DialectSQLite, Table: "identities", Columns: []string{"state"}, KeyColumns: []string{"id"}, VersionColumn: "rev"}
+	query, _, err := u.Build([]interface{}{"active"}, []interface{}{1}, 3)
+	require.NoError(t, err)
+	assert.Equal(t, `UPDATE "identities" SET "state" = ?, "rev" = "rev" + 1 WHERE "id" = ? AND "rev" = ?`, query)
+
+	_, _, err = u.Build([]interface{}{"active", "extra"}, []interface{}{1}, 3)
+	assert.Error(t, err)
+	_, _, err = u.Build([]interface{}{"active"}, nil, 3)
+	assert.Error(t, err)
+}
+
+func TestCheckVersionedUpdate(t *testing.T) {
+	assert.NoError(t, CheckVersionedUpdate(driver.RowsAffected(1), nil))
+	assert.ErrorIs(t, CheckVersionedUpdate(driver.RowsAffected(0), nil), ErrConcurrentUpdate)
+	assert.ErrorIs(t, CheckVersionedUpdate(nil, driver.ErrBadConn), ErrConnectionFailed)
+}
@@ -1,6 +1,7 @@
 package sqlcon
 
 import (
+	"database/sql"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -86,6 +87,40 @@ func ParseConnectionOptions(l *logrusx.Logger, dsn string) (maxConns int, maxIdl
 	return
 }
 
+// PoolOptions are the connection pool options of a DSN, see ParsePoolOptions.
+type PoolOptions struct {
+	// MaxConns is set from the "max_conns" query parameter.
+	MaxConns int
+	// MaxIdleConns is set from the "max_idle_conns" query parameter.
+	MaxIdleConns int
+	// MaxConnLifetime is set from the "max_conn_lifetime" query parameter.
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime is set from the "max_conn_idle_time" query parameter.
+	MaxConnIdleTime time.Duration
+}
+
+// ParsePoolOptions parses the pool options like ParseConnectionOptions. Open the database with the
+// returned DSN, which does not contain the pool options, and pass it to PoolOptions.Apply:
+//
+//	opts, dsn := sqlcon.ParsePoolOptions(l, dsn)
+//	db, err := sql.Open("pgx", dsn)
+//	if err != nil {
+//		return err
+//	}
+//	opts.Apply(db)
+func ParsePoolOptions(l *logrusx.Logger, dsn string) (opts PoolOptions, cleanedDSN string) {
+	opts.MaxConns, opts.MaxIdleConns, opts.MaxConnLifetime, opts.MaxConnIdleTime, cleanedDSN = ParseConnectionOptions(l, dsn)
+	return opts, cleanedDSN
+}
+
+// Apply configures the connection pool of the database.
+func (o PoolOptions) Apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.MaxConns)
+	db.SetMaxIdleConns(o.MaxIdleConns)
+	db.SetConnMaxLifetime(o.MaxConnLifetime)
+	db.SetConnMaxIdleTime(o.MaxConnIdleTime)
+}
+
 // FinalizeDSN will return a finalized DSN URI.
 func FinalizeDSN(l *logrusx.Logger, dsn string) string {
 	if strings.HasPrefix(dsn, "mysql://") {
@@ -0,0 +1,88 @@
+package sqlcon
+
+import (
+	"strings"
+)
+
+// QuoteIdentifier quotes the parts of a qualified identifier and joins them by ".", for dynamically constructed
+// queries whose identifiers can not be validated like the ones of the builders:
+//
+//	sqlcon.DialectPostgres.QuoteIdentifier("public", "user \"data\"") // "public"."user ""data"""
+//	sqlcon.DialectMySQL.QuoteIdentifier("identities")                // `identities`
+//
+// Quotes in the parts are escaped by doubling them, so that any part is a single identifier. The parts must not
+// contain NUL characters, which no dialect allows in identifiers.
+func (d Dialect) QuoteIdentifier(parts ...string) string {
+	quote := `"`
+	if d == DialectMySQL {
+		quote = "`"
+	}
+
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(quoted, ".")
+}
+
+// Rebind replaces the "?" bind parameters of the statement by the placeholders of the dialect, e.g. by "$1",
+// "$2", ... for PostgreSQL, so that statements can be written once for all dialects:
+//
+//	query := sqlcon.DialectPostgres.Rebind("SELECT * FROM identities WHERE id = ? AND nid = ?")
+//	// SELECT * FROM identities WHERE id = $1 AND nid = $2
+//
+// Question marks in string literals, quoted identifiers and comments are kept. Statements for MySQL and SQLite,
+// which use "?" themselves, are returned as they are.
+func (d Dialect) Rebind(query string) string {
+	if d.Placeholder(1) == "?" || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n := 0
+	for i := 0; i < len(query); i++ {
+		end := i
+		switch c := query[i]; {
+		case c == '?':
+			n++
+			b.WriteString(d.Placeholder(n))
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			end = closingQuote(query, i, c)
+		case c == '[' && d == DialectSQLServer:
+			end = closingQuote(query, i, ']')
+		case strings.HasPrefix(query[i:], "--"):
+			if end = strings.IndexByte(query[i:], '\n'); end < 0 {
+				end = len(query) - 1
+			} else {
+				end += i
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if end = strings.Index(query[i+2:], "*/"); end < 0 {
+				end = len(query) - 1
+			} else {
+				end += i + 3
+			}
+		}
+		b.WriteString(query[i : end+1])
+		i = end
+	}
+	return b.String()
+}
+
+// closingQuote returns the index of the quote which ends the literal or identifier starting at i, skipping
+// doubled quotes, or the last index if it is not terminated.
+func closingQuote(query string, i int, quote byte) int {
+	for j := i + 1; j < len(query); j++ {
+		if query[j] != quote {
+			continue
+		}
+		if j+1 < len(query) && query[j+1] == quote {
+			j++
+			continue
+		}
+		return j
+	}
+	return len(query) - 1
+}
@@ -0,0 +1,38 @@
+package sqlcon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	assert.Equal(t, `"public"."identities"`, DialectPostgres.QuoteIdentifier("public", "identities"))
+	assert.Equal(t, `"user ""data"""`, DialectSQLite.QuoteIdentifier(`user "data"`))
+	assert.Equal(t, `"a.b"`, DialectCockroach.QuoteIdentifier("a.b"), "dots in parts do not qualify")
+	assert.Equal(t, "`user ``data```", DialectMySQL.QuoteIdentifier("user `data`"))
+	assert.Equal(t, `"dbo"."identities"`, DialectSQLServer.QuoteIdentifier("dbo", "identities"))
+}
+
+func TestRebind(t *testing.T) {
+	for _, tc := range []struct {
+		dialect        Dialect
+		query, rebound string
+	}{
+		{dialect: DialectPostgres, query: "SELECT * FROM identities WHERE id = ? AND nid = ?", rebound: "SELECT * FROM identities WHERE id = $1 AND nid = $2"},
+		{dialect: DialectCockroach, query: "INSERT INTO t (a, b) VALUES (?,?)", rebound: "INSERT INTO t (a, b) VALUES ($1,$2)"},
+		{dialect: DialectSQLServer, query: "SELECT * FROM t WHERE a = ? AND b = ?", rebound: "SELECT * FROM t WHERE a = @p1 AND b = @p2"},
+		{dialect: DialectMySQL, query: "SELECT * FROM t WHERE a = ?", rebound: "SELECT * FROM t WHERE a = ?"},
+		{dialect: DialectSQLite, query: "SELECT * FROM t WHERE a = ?", rebound: "SELECT * FROM t WHERE a = ?"},
+		{dialect: DialectPostgres, query: "SELECT 1", rebound: "SELECT 1"},
+		{dialect: DialectPostgres, query: "SELECT 'why?', 'it''s ?', a FROM t WHERE b = ?", rebound: "SELECT 'why?', 'it''s ?', a FROM t WHERE b = $1"},
+		{dialect: DialectPostgres, query: `SELECT "a?""?" FROM t WHERE b = ?`, rebound: `SELECT "a?""?" FROM t WHERE b = $1`},
+		{dialect: DialectPostgres, query: "SELECT a -- a?\nFROM t /* b? */ WHERE b = ? /* c?", rebound: "SELECT a -- a?\nFROM t /* b? */ WHERE b = $1 /* c?"},
+		{dialect: DialectPostgres, query: "SELECT a FROM t WHERE b = 'unterminated ?", rebound: "SELECT a FROM t WHERE b = 'unterminated ?"},
+		{dialect: DialectPostgres, query: "SELECT a - ? FROM t -- ?", rebound: "SELECT a - $1 FROM t -- ?"},
+		{dialect: DialectSQLServer, query: "SELECT [a?] FROM t WHERE b = ?", rebound: "SELECT [a?] FROM t WHERE b = @p1"},
+		{dialect: DialectPostgres, query: "SELECT a[?] FROM t", rebound: "SELECT a[$1] FROM t"},
+	} {
+		assert.Equal(t, tc.rebound, tc.dialect.Rebind(tc.query), "%s: %s", tc.dialect, tc.query)
+	}
+}
@@ -0,0 +1,85 @@
+package sqlcon
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// stringLiteral matches SQL string literals, including quotes escaped by doubling or, as in MySQL, by a backslash
+	stringLiteral = regexp.MustCompile(`'(?:[^'\\]|''|\\.)*'`)
+	// numericLiteral matches numbers which are not part of identifiers or placeholders like $1
+	numericLiteral = regexp.MustCompile(`(^|[^\w$.])-?\d+(?:\.\d+)?\b`)
+	// quotedText matches text in single, double or back quotes, which errors use to quote values
+	quotedText = regexp.MustCompile("'[^']*'|\"[^\"]*\"|`[^`]*`")
+)
+
+// Redactor redacts SQL statements before they leave the process in telemetry, such as spans, logs and
+// error messages. The statements passed are not expected to be valid, and may be truncated.
+type Redactor func(statement string) string
+
+var (
+	// RedactLiterals replaces string and numeric literals by "?", which keeps the shape of the statement:
+	//
+	//	SELECT * FROM users WHERE email = 'foo@bar.com' LIMIT 1 -> SELECT * FROM users WHERE email = ? LIMIT ?
+	//
+	// Placeholders like $1 are kept. It is used if no Redactor is set.
+	RedactLiterals Redactor = func(statement string) string {
+		statement = stringLiteral.ReplaceAllString(statement, "?")
+		return numericLiteral.ReplaceAllString(statement, "${1}?")
+	}
+	// RedactAll only keeps the first keyword of the statement, e.g. "SELECT".
+	RedactAll Redactor = func(statement string) string {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			return ""
+		}
+		return strings.ToUpper(fields[0])
+	}
+	// RedactNone keeps statements as they are. Only use it if statements never contain sensitive literals,
+	// e.g. because all values are passed as arguments.
+	RedactNone Redactor = func(statement string) string {
+		return statement
+	}
+)
+
+// Redact returns the redacted statement. A nil Redactor redacts like RedactLiterals.
+func (r Redactor) Redact(statement string) string {
+	if r == nil {
+		return RedactLiterals(statement)
+	}
+	return r(statement)
+}
+
+// redactedError replaces the message of an error but still unwraps to it.
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// RedactError returns the error handled by HandleError, so that it can be recorded in telemetry. The messages of
+// the errors classified by HandleError do not contain the values of the statement, unlike the original ones,
+// e.g. "Key (email)=(foo@bar.com) already exists.". Other errors can still quote values, e.g. `invalid input
+// syntax for type uuid: "foo"`, so their quoted text is replaced by "?" in the message. The original error can
+// still be unwrapped with errors.Is and errors.As.
+func RedactError(err error) error {
+	err = HandleError(err)
+	if err == nil {
+		return nil
+	}
+
+	var classified interface{ StatusCode() int }
+	if errors.As(err, &classified) {
+		return err
+	}
+	msg := quotedText.ReplaceAllString(err.Error(), "?")
+	if msg == err.Error() {
+		return err
+	}
+	return errors.WithStack(&redactedError{msg: msg, err: err})
+}
@@ -0,0 +1,40 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactor(t *testing.T) {
+	for statement, expected := range map[string]string{
+		"SELECT * FROM users WHERE email = 'foo@bar.com' AND name = 'O''Brien'": "SELECT * FROM users WHERE email = ? AND name = ?",
+		`SELECT * FROM users WHERE name = 'O\'Brien' AND age > 18`:              "SELECT * FROM users WHERE name = ? AND age > ?",
+		"SELECT * FROM users WHERE id = $1 LIMIT 10 OFFSET -5":                  "SELECT * FROM users WHERE id = $1 LIMIT ? OFFSET ?",
+		"INSERT INTO t2 (a, b) VALUES (?, 3)":                                   "INSERT INTO t2 (a, b) VALUES (?, ?)",
+	} {
+		assert.Equal(t, expected, RedactLiterals.Redact(statement))
+		assert.Equal(t, expected, Redactor(nil).Redact(statement))
+	}
+
+	assert.Equal(t, "SELECT", RedactAll.Redact("  select * FROM users WHERE email = 'foo@bar.com'"))
+	assert.Equal(t, "", RedactAll.Redact(""))
+	assert.Equal(t, "SELECT 1", RedactNone.Redact("SELECT 1"))
+}
+
+func TestRedactError(t *testing.T) {
+	assert.NoError(t, RedactError(nil))
+
+	err := &pq.Error{Code: "23505", Detail: "Key (email)=(foo@bar.com) already exists."}
+	assert.Equal(t, ErrUniqueViolation.Error(), RedactError(err).Error())
+	assert.ErrorIs(t, RedactError(err), ErrUniqueViolation)
+	assert.ErrorIs(t, RedactError(sql.ErrNoRows), ErrNoRows)
+
+	err = &pq.Error{Code: "22P02", Message: `invalid input syntax for type uuid: "foo@bar.com"`}
+	assert.EqualError(t, RedactError(err), "pq: invalid input syntax for type uuid: ?")
+	assert.ErrorIs(t, RedactError(err), err)
+
+	assert.Equal(t, sql.ErrTxDone.Error(), RedactError(sql.ErrTxDone).Error())
+}
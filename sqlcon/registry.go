@@ -0,0 +1,205 @@
+package sqlcon
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/healthx"
+	"github.com/ory/x/logx"
+)
+
+type (
+	// Registry manages the connections of services using several databases, e.g. a primary database, an
+	// analytics database and tenant shards, under unique names. Each connection is configured by its own
+	// options, and optionally
+	//
+	//   - pinged by a ready check named "database:<name>" of a healthx.Registry, see WithHealthRegistry
+	//   - observed by PoolMetrics with the name as the "pool" label, and "<name>/reader-<n>" for its readers,
+	//     see WithRegistryMetrics
+	//
+	// A Registry is safe for concurrent use.
+	Registry struct {
+		health          *healthx.Registry
+		metrics         *PoolMetrics
+		metricsInterval time.Duration
+		l               logx.Logger
+
+		mu          sync.RWMutex
+		connections map[string]*registeredConnection
+	}
+	registeredConnection struct {
+		conn        *Connection
+		stopMetrics context.CancelFunc
+	}
+	// RegistryOption configures NewRegistry.
+	RegistryOption func(*Registry)
+)
+
+// WithHealthRegistry adds a ready check for every connection to the health registry, which pings the
+// connection with the DefaultProbeTimeout, see Connection.PingContext.
+func WithHealthRegistry(h *healthx.Registry) RegistryOption {
+	return func(r *Registry) {
+		r.health = h
+	}
+}
+
+// WithRegistryMetrics observes the pools of every connection every interval, see PoolMetrics.Run.
+func WithRegistryMetrics(m *PoolMetrics, interval time.Duration) RegistryOption {
+	return func(r *Registry) {
+		r.metrics, r.metricsInterval = m, interval
+	}
+}
+
+// WithRegistryLogger sets the logger of the connections, unless they are opened with WithConnectionLogger.
+func WithRegistryLogger(l logx.Logger) RegistryOption {
+	return func(r *Registry) {
+		r.l = l
+	}
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{connections: map[string]*registeredConnection{}}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+func readyCheckName(name string) string {
+	return "database:" + name
+}
+
+// Open opens the connection using NewConnection and registers it under the name, which must not be
+// registered already.
+func (r *Registry) Open(name, driverName, writerDSN string, readerDSNs []string, opts ...ConnectionOption) (*Connection, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.connections[name]; ok {
+		return nil, errors.Errorf("the database %q is registered already", name)
+	}
+	if r.l != nil {
+		opts = append([]ConnectionOption{WithConnectionLogger(r.l)}, opts...)
+	}
+	conn, err := NewConnection(driverName, writerDSN, readerDSNs, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rc := &registeredConnection{conn: conn, stopMetrics: func() {}}
+	if r.metrics != nil {
+		var ctx context.Context
+		ctx, rc.stopMetrics = context.WithCancel(context.Background())
+		go r.metrics.Run(ctx, name, conn.writer, r.metricsInterval)
+		for i, reader := range conn.readers {
+			go r.metrics.Run(ctx, fmt.Sprintf("%s/reader-%d", name, i+1), reader.db, r.metricsInterval)
+		}
+	}
+	if r.health != nil {
+		r.health.AddReadyCheck(readyCheckName(name), func(req *http.Request) error {
+			return PingWithTimeout(req.Context(), conn, 0)
+		})
+	}
+	r.connections[name] = rc
+	return conn, nil
+}
+
+// Get returns the connection registered under the name.
+func (r *Registry) Get(name string) (*Connection, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rc, ok := r.connections[name]
+	if !ok {
+		return nil, false
+	}
+	return rc.conn, true
+}
+
+// Names returns the sorted names of the registered connections.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return sortedNames(r.connections)
+}
+
+// Remove unregisters and closes the connection registered under the name.
+func (r *Registry) Remove(name string) error {
+	r.mu.Lock()
+	rc, ok := r.connections[name]
+	delete(r.connections, name)
+	r.mu.Unlock()
+
+	if !ok {
+		return errors.Errorf("the database %q is not registered", name)
+	}
+	return r.close(name, rc)
+}
+
+func (r *Registry) close(name string, rc *registeredConnection) error {
+	rc.stopMetrics()
+	if r.health != nil {
+		r.health.RemoveCheck(readyCheckName(name))
+	}
+	return rc.conn.Close()
+}
+
+// PingContext pings all connections concurrently and returns their errors by name, see Connection.PingContext.
+// Connections which responded are omitted.
+func (r *Registry) PingContext(ctx context.Context) map[string]error {
+	r.mu.RLock()
+	connections := make(map[string]*Connection, len(r.connections))
+	for name, rc := range r.connections {
+		connections[name] = rc.conn
+	}
+	r.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := map[string]error{}
+	for name, conn := range connections {
+		wg.Add(1)
+		go func(name string, conn *Connection) {
+			defer wg.Done()
+			if err := conn.PingContext(ctx); err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}
+		}(name, conn)
+	}
+	wg.Wait()
+	return errs
+}
+
+// Close unregisters and closes all connections. It returns the first error.
+func (r *Registry) Close() error {
+	r.mu.Lock()
+	connections := r.connections
+	r.connections = map[string]*registeredConnection{}
+	r.mu.Unlock()
+
+	var err error
+	for _, name := range sortedNames(connections) {
+		if cerr := r.close(name, connections[name]); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func sortedNames(connections map[string]*registeredConnection) []string {
+	names := make([]string, 0, len(connections))
+	for name := range connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,76 @@
+package sqlcon
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/healthx"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	health := healthx.NewRegistry()
+	metrics := NewPoolMetrics("")
+	r := NewRegistry(WithHealthRegistry(health), WithRegistryMetrics(metrics, time.Millisecond))
+
+	primary, err := r.Open("primary", "sqlcon-fake", "registry-primary?max_conns=3", []string{"registry-replica"})
+	require.NoError(t, err)
+	_, err = r.Open("analytics", "sqlcon-fake", "registry-analytics", nil)
+	require.NoError(t, err)
+
+	_, err = r.Open("primary", "sqlcon-fake", "registry-other", nil)
+	assert.EqualError(t, err, `the database "primary" is registered already`)
+
+	actual, ok := r.Get("primary")
+	require.True(t, ok)
+	assert.Same(t, primary, actual)
+	rows, err := actual.Writer().QueryContext(ctx, "SELECT 1")
+	assert.Equal(t, "registry-primary?", servedBy(t, rows, err))
+	_, ok = r.Get("unknown")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"analytics", "primary"}, r.Names())
+
+	t.Run("case=observes the pools by name", func(t *testing.T) {
+		// primary, primary/reader-1 and analytics
+		assert.Eventually(t, func() bool {
+			return testutil.CollectAndCount(metrics.maxOpen) == 3
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, float64(3), testutil.ToFloat64(metrics.maxOpen.WithLabelValues("primary")))
+	})
+
+	t.Run("case=checks the connections", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/health/ready", nil)
+		assert.Empty(t, health.CheckReady(req))
+		assert.Empty(t, r.PingContext(ctx))
+
+		testDriver.setDown("registry-analytics", true)
+		defer testDriver.setDown("registry-analytics", false)
+
+		errs := health.CheckReady(req)
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs["database:analytics"], ErrConnectionFailed)
+		errs = r.PingContext(ctx)
+		require.Len(t, errs, 1)
+		assert.ErrorIs(t, errs["analytics"], ErrConnectionFailed)
+	})
+
+	t.Run("case=removes connections", func(t *testing.T) {
+		require.NoError(t, r.Remove("analytics"))
+		assert.Equal(t, []string{"primary"}, r.Names())
+		assert.Error(t, r.Remove("analytics"))
+
+		testDriver.setDown("registry-analytics", true)
+		defer testDriver.setDown("registry-analytics", false)
+		assert.Empty(t, health.CheckReady(httptest.NewRequest("GET", "/health/ready", nil)))
+	})
+
+	require.NoError(t, r.Close())
+	assert.Empty(t, r.Names())
+	assert.Error(t, primary.Writer().PingContext(ctx), "the connections are closed")
+}
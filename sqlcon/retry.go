@@ -0,0 +1,187 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logx"
+)
+
+type (
+	// TxBeginner is implemented by *sql.DB and *sql.Conn.
+	TxBeginner interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}
+	// Execer is implemented by *sql.DB, *sql.Conn and Connection.
+	Execer interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	}
+	// RetryPolicy decides which errors are retried, how often, and how long to wait in between.
+	// The zero value is valid and uses the defaults.
+	RetryPolicy struct {
+		// MaxAttempts is the maximum number of attempts, including the first one.
+		// Default: 10
+		MaxAttempts int
+		// InitialBackoff is the backoff after the first attempt. It doubles after every attempt, and the
+		// actual wait time is chosen randomly up to it.
+		// Default: 50ms
+		InitialBackoff time.Duration
+		// MaxBackoff caps the backoff.
+		// Default: 5s
+		MaxBackoff time.Duration
+		// Retryable are the error classes which are retried, matched using errors.Is after HandleError.
+		// Default: ErrConcurrentUpdate and ErrLockTimeout
+		Retryable []error
+		// Logger logs retried attempts at info level. It is optional.
+		Logger logx.Logger
+	}
+	retryOptions struct {
+		policy    RetryPolicy
+		txOptions *sql.TxOptions
+		observer  TxObserver
+	}
+	// RetryOption configures Retry.
+	RetryOption func(*retryOptions)
+)
+
+// DefaultRetryable are the error classes retried by default: serialization failures, deadlocks (including
+// CockroachDB's retryable errors) and lock timeouts (including SQLITE_BUSY).
+var DefaultRetryable = []error{ErrConcurrentUpdate, ErrLockTimeout}
+
+// WithRetryPolicy sets the retry policy.
+func WithRetryPolicy(p RetryPolicy) RetryOption {
+	return func(o *retryOptions) {
+		o.policy = p
+	}
+}
+
+// WithMaxAttempts sets RetryPolicy.MaxAttempts.
+func WithMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) {
+		o.policy.MaxAttempts = n
+	}
+}
+
+// WithBackoff sets RetryPolicy.InitialBackoff and RetryPolicy.MaxBackoff.
+func WithBackoff(initial, max time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.policy.InitialBackoff, o.policy.MaxBackoff = initial, max
+	}
+}
+
+// WithTxOptions sets the options of the transactions, e.g. the isolation level.
+func WithTxOptions(opts *sql.TxOptions) RetryOption {
+	return func(o *retryOptions) {
+		o.txOptions = opts
+	}
+}
+
+// WithTxObserver sets TransactionOptions.Observer.
+func WithTxObserver(observer TxObserver) RetryOption {
+	return func(o *retryOptions) {
+		o.observer = observer
+	}
+}
+
+// WithRetryLogger sets RetryPolicy.Logger.
+func WithRetryLogger(l logx.Logger) RetryOption {
+	return func(o *retryOptions) {
+		o.policy.Logger = l
+	}
+}
+
+// IsRetryable returns true if the error is caused by a conflict with a concurrent transaction, after which the
+// transaction can be retried, according to the default RetryPolicy. This includes CockroachDB's retryable errors
+// (SQLSTATE 40001, "restart transaction"), serialization failures, deadlocks and lock timeouts on all supported drivers.
+func IsRetryable(err error) bool {
+	return new(RetryPolicy).IsRetryable(err)
+}
+
+// IsRetryable returns true if the error belongs to one of the retryable error classes.
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	handled := HandleError(err)
+	for _, target := range retryable {
+		if errors.Is(handled, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the randomized time to wait after the given attempt, starting at 1.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff, max := p.InitialBackoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = 50 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	// full jitter, see https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+	return time.Duration(rand.Int63n(int64(backoff) + 1)) // #nosec G404
+}
+
+// Do runs fn until it succeeds, fails with an error which is not retryable, the maximum number of attempts is
+// reached, or the context is canceled. Errors are handled by HandleError. If the context ends while waiting to
+// retry, the error is ErrCanceled or ErrTimeout, whose message includes the error of the last attempt.
+func (p *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 10
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !p.IsRetryable(err) || attempt >= maxAttempts {
+			return HandleError(err)
+		}
+
+		wait := p.Backoff(attempt)
+		if p.Logger != nil {
+			p.Logger.Info("retrying after retryable error", "attempt", attempt, "wait", wait, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithMessagef(HandleError(ctx.Err()), "aborted retrying after attempt %d failed with: %s", attempt, err)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Exec executes the statement until it succeeds like Do, e.g. while SQLite reports "database is locked"
+// (SQLITE_BUSY) because another connection is writing, which is classified as ErrLockTimeout. Use it for single
+// writes outside of transactions; statements in transactions can not be retried on their own, use Retry instead.
+func (p *RetryPolicy) Exec(ctx context.Context, db Execer, query string, args ...interface{}) (res sql.Result, err error) {
+	err = p.Do(ctx, func(ctx context.Context) (err error) {
+		res, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// Retry runs fn in a transaction using WithTransaction, configured by options instead.
+func Retry(ctx context.Context, db TxBeginner, fn func(ctx context.Context, tx *sql.Tx) error, opts ...RetryOption) error {
+	o := new(retryOptions)
+	for _, f := range opts {
+		f(o)
+	}
+
+	return WithTransaction(ctx, db, &TransactionOptions{TxOptions: o.txOptions, RetryPolicy: &o.policy, Observer: o.observer}, fn)
+}
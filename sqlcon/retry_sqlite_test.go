@@ -0,0 +1,114 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE counters (id INTEGER PRIMARY KEY, value INTEGER NOT NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO counters (id, value) VALUES (1, 0)")
+	require.NoError(t, err)
+
+	value := func() (v int) {
+		require.NoError(t, db.QueryRow("SELECT value FROM counters WHERE id = 1").Scan(&v))
+		return v
+	}
+	increment := func(fail int) func(ctx context.Context, tx *sql.Tx) error {
+		var attempts int
+		return func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			if _, err := tx.ExecContext(ctx, "UPDATE counters SET value = value + 1 WHERE id = 1"); err != nil {
+				return err
+			}
+			if attempts <= fail {
+				return &pgconnv5.PgError{Code: "40001", Message: "restart transaction"}
+			}
+			return nil
+		}
+	}
+
+	t.Run("case=retries until the transaction succeeds", func(t *testing.T) {
+		l := new(recordingLogger)
+		require.NoError(t, Retry(ctx, db, increment(2), WithBackoff(time.Millisecond, time.Millisecond), WithRetryLogger(l)))
+		assert.Equal(t, 1, value(), "failed attempts must be rolled back")
+		assert.Len(t, l.messages, 2)
+	})
+
+	t.Run("case=gives up after the maximum attempts", func(t *testing.T) {
+		err := Retry(ctx, db, increment(5), WithMaxAttempts(3), WithBackoff(time.Millisecond, time.Millisecond))
+		assert.ErrorIs(t, err, ErrConcurrentUpdate)
+		assert.Equal(t, 1, value())
+	})
+
+	t.Run("case=does not retry other errors", func(t *testing.T) {
+		var attempts int
+		err := Retry(ctx, db, func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			return sql.ErrNoRows
+		})
+		assert.ErrorIs(t, err, ErrNoRows)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("case=stops when the context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		err := Retry(ctx, db, increment(5), WithBackoff(time.Hour, time.Hour))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestRetryExec(t *testing.T) {
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "db.sqlite") + "?_busy_timeout=0"
+	db, err := sql.Open("sqlite3", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	// another connection holds the write lock
+	lock, err := db.Conn(ctx)
+	require.NoError(t, err)
+	defer lock.Close()
+	_, err = lock.ExecContext(ctx, "BEGIN IMMEDIATE")
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+	require.ErrorIs(t, HandleError(err), ErrLockTimeout, "the write fails with SQLITE_BUSY without retries")
+
+	released := make(chan error)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_, err := lock.ExecContext(ctx, "COMMIT")
+		released <- err
+	}()
+
+	res, err := (&RetryPolicy{InitialBackoff: 5 * time.Millisecond, MaxBackoff: 10 * time.Millisecond, MaxAttempts: 100}).
+		Exec(ctx, db, "INSERT INTO items DEFAULT VALUES")
+	require.NoError(t, err)
+	require.NoError(t, <-released)
+	n, err := res.RowsAffected()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, n)
+}
@@ -0,0 +1,99 @@
+package sqlcon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRetryable(t *testing.T) {
+	assert.False(t, IsRetryable(nil))
+	assert.False(t, IsRetryable(errors.New("foo")))
+	assert.False(t, IsRetryable(&pgconnv5.PgError{Code: "23505"}))
+
+	assert.True(t, IsRetryable(fmt.Errorf("wrapped: %w", &pgconnv5.PgError{Code: "40001", Message: "restart transaction: TransactionRetryWithProtoRefreshError"})))
+	assert.True(t, IsRetryable(&pgconnv5.PgError{Code: "40P01"}))
+	assert.True(t, IsRetryable(&mysql.MySQLError{Number: 1213}))
+	assert.True(t, IsRetryable(HandleError(&pgconnv5.PgError{Code: "40001"})))
+}
+
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=backoff grows up to the maximum", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 40 * time.Millisecond}
+		for attempt, max := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 10: 40 * time.Millisecond} {
+			for i := 0; i < 100; i++ {
+				assert.LessOrEqual(t, p.Backoff(attempt), max)
+			}
+		}
+	})
+
+	t.Run("case=custom error classes", func(t *testing.T) {
+		p := RetryPolicy{Retryable: []error{ErrConcurrentUpdate}}
+		assert.True(t, p.IsRetryable(&mysql.MySQLError{Number: 1213}))
+		assert.False(t, p.IsRetryable(&mysql.MySQLError{Number: 1205}))
+		assert.True(t, IsRetryable(&mysql.MySQLError{Number: 1205}))
+	})
+
+	t.Run("case=retries until success or max attempts", func(t *testing.T) {
+		l := new(recordingLogger)
+		p := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Logger: l}
+
+		var attempts int
+		require.NoError(t, p.Do(ctx, func(context.Context) error {
+			if attempts++; attempts < 3 {
+				return &pgconnv5.PgError{Code: "40001"}
+			}
+			return nil
+		}))
+		assert.Equal(t, 3, attempts)
+		assert.Len(t, l.messages, 2)
+
+		attempts = 0
+		err := p.Do(ctx, func(context.Context) error {
+			attempts++
+			return &pgconnv5.PgError{Code: "55P03"}
+		})
+		assert.ErrorIs(t, err, ErrLockTimeout)
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("case=classifies the end of the context while waiting to retry", func(t *testing.T) {
+		p := RetryPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+		for name, tc := range map[string]struct {
+			ctx   func() (context.Context, context.CancelFunc)
+			class error
+		}{
+			"canceled": {ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(ctx)
+				time.AfterFunc(10*time.Millisecond, cancel)
+				return ctx, cancel
+			}, class: ErrCanceled},
+			"deadline": {ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(ctx, 10*time.Millisecond)
+			}, class: ErrTimeout},
+		} {
+			t.Run("case="+name, func(t *testing.T) {
+				ctx, cancel := tc.ctx()
+				defer cancel()
+
+				var attempts int
+				err := p.Do(ctx, func(context.Context) error {
+					attempts++
+					return &pgconnv5.PgError{Code: "40001", Message: "restart transaction"}
+				})
+				assert.ErrorIs(t, err, tc.class)
+				assert.NotErrorIs(t, err, ErrSerializationFailure, "the error is not retried again")
+				assert.Contains(t, err.Error(), "restart transaction", "the error of the last attempt is included")
+				assert.Equal(t, 1, attempts)
+			})
+		}
+	})
+}
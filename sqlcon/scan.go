@@ -0,0 +1,187 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+
+	// structFields caches the fields of the struct types by their column names.
+	structFields sync.Map
+)
+
+// ScanOne scans the first row into dest and closes the rows. If there is no row, ErrNoRows is returned.
+// Errors are handled by HandleError.
+//
+// dest is a pointer to a struct, whose fields are assigned to the columns of the same name, or a pointer to a
+// single value if the rows have a single column. The column of a field is set by its "db" tag, like
+// `db:"created_at"`, or otherwise its lower-cased name; fields tagged with `db:"-"` are skipped, and embedded
+// structs are flattened. Every column must have a field. NULLs are scanned into pointer fields as nil and into
+// sql.Scanner fields such as sql.NullString and sqlxx.NullString; scanning NULLs into other fields fails.
+//
+//	var identity struct {
+//		ID        string     `db:"id"`
+//		DeletedAt *time.Time `db:"deleted_at"`
+//	}
+//	rows, err := db.QueryContext(ctx, "SELECT id, deleted_at FROM identities WHERE id = ?", id)
+//	if err != nil {
+//		return sqlcon.HandleError(err)
+//	}
+//	err = sqlcon.ScanOne(rows, &identity)
+func ScanOne(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.Errorf("the destination must be a non-nil pointer, but is %T", dest)
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return HandleError(err)
+		}
+		return HandleError(sql.ErrNoRows)
+	}
+	if err := scanRow(rows, v.Elem()); err != nil {
+		return err
+	}
+	return HandleError(rows.Close())
+}
+
+// ScanAll scans all rows into dest, a pointer to a slice of structs, pointers to structs or values, and closes
+// the rows. The rows are scanned as by ScanOne; no rows result in an empty slice. Errors are handled by
+// HandleError.
+//
+//	var identities []Identity
+//	err = sqlcon.ScanAll(rows, &identities)
+func ScanAll(rows *sql.Rows, dest interface{}) error {
+	defer rows.Close()
+
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("the destination must be a non-nil pointer to a slice, but is %T", dest)
+	}
+	slice := v.Elem()
+	elem := slice.Type().Elem()
+
+	result := reflect.MakeSlice(slice.Type(), 0, 0)
+	for rows.Next() {
+		row := reflect.New(elem).Elem()
+		target := row
+		if elem.Kind() == reflect.Ptr && !isScalar(elem.Elem()) {
+			row.Set(reflect.New(elem.Elem()))
+			target = row.Elem()
+		}
+		if err := scanRow(rows, target); err != nil {
+			return err
+		}
+		result = reflect.Append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return HandleError(err)
+	}
+	slice.Set(result)
+	return HandleError(rows.Close())
+}
+
+// isScalar returns true if values of the type are scanned from a single column.
+func isScalar(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct || t == timeType || reflect.PtrTo(t).Implements(scannerType)
+}
+
+// scanRow scans the current row into the value, which must be addressable.
+func scanRow(rows *sql.Rows, v reflect.Value) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return HandleError(err)
+	}
+
+	if isScalar(v.Type()) {
+		if len(columns) != 1 {
+			return errors.Errorf("unable to scan %d columns into %s", len(columns), v.Type())
+		}
+		return HandleError(rows.Scan(v.Addr().Interface()))
+	}
+
+	fields := fieldsOf(v.Type())
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			return errors.Errorf("the column %q has no field in %s", column, v.Type())
+		}
+		dest[i] = fieldByIndex(v, index).Addr().Interface()
+	}
+	return HandleError(rows.Scan(dest...))
+}
+
+// fieldByIndex returns the field, allocating nil embedded pointers on the way.
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldsOf returns the indices of the fields of the struct type by their lower-cased column names.
+func fieldsOf(t reflect.Type) map[string][]int {
+	if cached, ok := structFields.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := strings.Split(f.Tag.Get("db"), ",")[0]
+			if tag == "-" || f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			fieldIndex := append(append([]int(nil), index...), i)
+
+			embedded := f.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if f.Anonymous && tag == "" && !isScalar(embedded) {
+				// nil pointers to unexported structs can not be allocated
+				if f.PkgPath != "" && f.Type.Kind() == reflect.Ptr {
+					continue
+				}
+				walk(embedded, fieldIndex)
+				continue
+			}
+			if f.PkgPath != "" {
+				continue
+			}
+
+			name := tag
+			if name == "" {
+				name = f.Name
+			}
+			// fields of outer structs take precedence over embedded ones
+			key := strings.ToLower(name)
+			if existing, ok := fields[key]; !ok || len(fieldIndex) < len(existing) {
+				fields[key] = fieldIndex
+			}
+		}
+	}
+	walk(t, nil)
+
+	structFields.Store(t, fields)
+	return fields
+}
@@ -0,0 +1,117 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ory/x/sqlxx"
+)
+
+type (
+	scanTimestamps struct {
+		CreatedAt string `db:"created_at"`
+	}
+	// ScanAudit is exported, so that nil pointers to it can be allocated when it is embedded.
+	ScanAudit struct {
+		CreatedAt string `db:"created_at"`
+	}
+	scanIdentity struct {
+		ID       string `db:"id"`
+		Nickname *string
+		Email    sqlxx.NullString `db:"email"`
+		Ignored  string           `db:"-"`
+		*ScanAudit
+		Timestamps scanTimestamps `db:"-"`
+	}
+	scanIdentityWithTimestamps struct {
+		ID       string `db:"id"`
+		Nickname *string
+		Email    sqlxx.NullString `db:"email"`
+		scanTimestamps
+	}
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE identities (id TEXT PRIMARY KEY, nickname TEXT, email TEXT, created_at TEXT NOT NULL);
+INSERT INTO identities VALUES ('a', 'alice', 'alice@example.org', '2022-01-01'), ('b', NULL, NULL, '2022-01-02')`)
+	require.NoError(t, err)
+	query := func(q string, args ...interface{}) *sql.Rows {
+		rows, err := db.QueryContext(ctx, q, args...)
+		require.NoError(t, err)
+		return rows
+	}
+
+	t.Run("case=scans one row into a struct", func(t *testing.T) {
+		var a scanIdentityWithTimestamps
+		require.NoError(t, ScanOne(query("SELECT id, nickname, email, created_at FROM identities WHERE id = 'a'"), &a))
+		require.NotNil(t, a.Nickname)
+		assert.Equal(t, "alice", *a.Nickname)
+		assert.Equal(t, sqlxx.NullString("alice@example.org"), a.Email)
+		assert.Equal(t, "2022-01-01", a.CreatedAt)
+
+		var b scanIdentityWithTimestamps
+		require.NoError(t, ScanOne(query("SELECT id, nickname, email FROM identities WHERE id = 'b'"), &b))
+		assert.Equal(t, scanIdentityWithTimestamps{ID: "b"}, b, "NULLs are scanned into pointers and scanners")
+	})
+
+	t.Run("case=allocates embedded pointers", func(t *testing.T) {
+		var a scanIdentity
+		require.NoError(t, ScanOne(query("SELECT id, created_at FROM identities WHERE id = 'a'"), &a))
+		require.NotNil(t, a.ScanAudit)
+		assert.Equal(t, "2022-01-01", a.CreatedAt)
+		assert.Empty(t, a.Timestamps.CreatedAt)
+	})
+
+	t.Run("case=scans single values", func(t *testing.T) {
+		var n int
+		require.NoError(t, ScanOne(query("SELECT COUNT(*) FROM identities"), &n))
+		assert.Equal(t, 2, n)
+
+		var nicknames []*string
+		require.NoError(t, ScanAll(query("SELECT nickname FROM identities ORDER BY id"), &nicknames))
+		require.Len(t, nicknames, 2)
+		assert.Equal(t, "alice", *nicknames[0])
+		assert.Nil(t, nicknames[1])
+	})
+
+	t.Run("case=scans all rows", func(t *testing.T) {
+		var identities []scanIdentityWithTimestamps
+		require.NoError(t, ScanAll(query("SELECT id, email FROM identities ORDER BY id"), &identities))
+		assert.Equal(t, []scanIdentityWithTimestamps{{ID: "a", Email: "alice@example.org"}, {ID: "b"}}, identities)
+
+		var pointers []*scanIdentityWithTimestamps
+		require.NoError(t, ScanAll(query("SELECT id FROM identities ORDER BY id"), &pointers))
+		require.Len(t, pointers, 2)
+		assert.Equal(t, "b", pointers[1].ID)
+
+		require.NoError(t, ScanAll(query("SELECT id FROM identities WHERE id = 'c'"), &pointers))
+		assert.NotNil(t, pointers)
+		assert.Empty(t, pointers)
+	})
+
+	t.Run("case=fails", func(t *testing.T) {
+		var identity scanIdentityWithTimestamps
+		assert.ErrorIs(t, ScanOne(query("SELECT id FROM identities WHERE id = 'c'"), &identity), ErrNoRows)
+		assert.EqualError(t, ScanOne(query("SELECT id, ignored FROM (SELECT id, 1 AS ignored FROM identities)"), &identity),
+			`the column "ignored" has no field in sqlcon.scanIdentityWithTimestamps`)
+		assert.Error(t, ScanOne(query("SELECT id FROM identities"), identity))
+
+		var nickname string
+		assert.Error(t, ScanOne(query("SELECT nickname FROM identities WHERE id = 'b'"), &nickname), "NULLs can not be scanned into strings")
+		assert.Error(t, ScanOne(query("SELECT id, nickname FROM identities"), &nickname))
+	})
+}
@@ -0,0 +1,98 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	schemaOptions struct {
+		table, column string
+	}
+	// SchemaOption configures CheckSchemaVersion.
+	SchemaOption func(*schemaOptions)
+)
+
+// WithSchemaTable sets the table and column of the applied migration versions.
+// Default: "schema_migration" and "version", as used by popx
+func WithSchemaTable(table, column string) SchemaOption {
+	return func(o *schemaOptions) {
+		o.table, o.column = table, column
+	}
+}
+
+// CheckSchemaVersion compares the latest applied migration version with the expected one, e.g. the latest
+// migration embedded in the binary, so that services fail at startup with an actionable error instead of
+// failing requests with missing tables or columns:
+//
+//   - ErrSchemaOutdated if the applied version is older, or the version table or column does not exist
+//   - ErrSchemaAhead if the applied version is newer
+//
+// Versions are compared as numbers if they consist of digits, such as timestamps, and as strings otherwise.
+func CheckSchemaVersion(ctx context.Context, db Querier, expected string, opts ...SchemaOption) error {
+	o := &schemaOptions{table: "schema_migration", column: "version"}
+	for _, f := range opts {
+		f(o)
+	}
+	for _, name := range append(strings.Split(o.table, "."), o.column) {
+		if !identifier.MatchString(name) {
+			return errors.Errorf("invalid identifier %q", name)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT MAX("+o.column+") FROM "+o.table) // #nosec G202 -- the identifiers are validated
+	if err != nil {
+		err = HandleError(err)
+		if errors.Is(err, ErrNoSuchTable) || errors.Is(err, ErrNoSuchColumn) {
+			return errors.WithStack(ErrSchemaOutdated.WithReason("The migration versions are missing, the database schema was never migrated.").WithWrap(err))
+		}
+		return err
+	}
+	defer rows.Close()
+
+	var applied sql.NullString
+	if rows.Next() {
+		if err := rows.Scan(&applied); err != nil {
+			return HandleError(err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return HandleError(err)
+	}
+
+	switch c := compareVersions(applied.String, expected); {
+	case !applied.Valid:
+		return errors.WithStack(ErrSchemaOutdated.WithReasonf("No migrations are applied, but version %s is expected.", expected))
+	case c < 0:
+		return errors.WithStack(ErrSchemaOutdated.WithReasonf("The database schema is at version %s, but version %s is expected.", applied.String, expected))
+	case c > 0:
+		return errors.WithStack(ErrSchemaAhead.WithReasonf("The database schema is at version %s, but this service only knows version %s.", applied.String, expected))
+	}
+	return nil
+}
+
+// compareVersions returns -1, 0 or 1 if a is older than, the same as, or newer than b.
+func compareVersions(a, b string) int {
+	if isDigits(a) && isDigits(b) {
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
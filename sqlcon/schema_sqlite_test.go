@@ -0,0 +1,36 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchemaVersionSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20220101000000000000"), ErrSchemaOutdated, "the table does not exist")
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20220101000000000000"), ErrNoSuchTable)
+
+	_, err = db.Exec("CREATE TABLE schema_migration (version VARCHAR(48) NOT NULL)")
+	require.NoError(t, err)
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20220101000000000000"), ErrSchemaOutdated, "no migrations are applied")
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "1", WithSchemaTable("schema_migration", "id")), ErrNoSuchColumn)
+
+	_, err = db.Exec("INSERT INTO schema_migration (version) VALUES ('20210101000000000000'), ('20220101000000000000')")
+	require.NoError(t, err)
+	assert.NoError(t, CheckSchemaVersion(ctx, db, "20220101000000000000"))
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20230101000000000000"), ErrSchemaOutdated)
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20210101000000000000"), ErrSchemaAhead)
+}
@@ -0,0 +1,41 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	// the fake driver serves the DSN as the applied version
+	db, err := sql.Open("sqlcon-fake", "20220101000000000000")
+	require.NoError(t, err)
+	defer db.Close()
+
+	assert.NoError(t, CheckSchemaVersion(ctx, db, "20220101000000000000"))
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20230101000000000000"), ErrSchemaOutdated)
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "20210101000000000000"), ErrSchemaAhead)
+	assert.ErrorIs(t, CheckSchemaVersion(ctx, db, "100000000000000000000"), ErrSchemaOutdated, "versions are compared as numbers")
+
+	assert.Error(t, CheckSchemaVersion(ctx, db, "20220101000000000000", WithSchemaTable("schema_migration; DROP TABLE users", "version")))
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "1", b: "1", expected: 0},
+		{a: "9", b: "10", expected: -1},
+		{a: "010", b: "9", expected: 1},
+		{a: "20220101", b: "20220102", expected: -1},
+		{a: "v1.10", b: "v1.9", expected: -1},
+		{a: "", b: "1", expected: -1},
+	} {
+		assert.Equal(t, tc.expected, compareVersions(tc.a, tc.b), "%s <=> %s", tc.a, tc.b)
+	}
+}
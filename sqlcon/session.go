@@ -0,0 +1,192 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// pragmaValue matches the keywords, names and numbers PRAGMAs are set to, e.g. ON, WAL or -2000.
+var pragmaValue = regexp.MustCompile(`^(-?\d+|[a-zA-Z_][a-zA-Z0-9_]*)$`)
+
+type (
+	// SessionSettings are applied to every new connection of a pool, because settings like the search_path
+	// only apply to the session which sets them, see NewSessionConnector. The settings which are not
+	// supported by the dialect result in an *UnsupportedError.
+	SessionSettings struct {
+		// Dialect determines the syntax of the settings.
+		Dialect Dialect
+		// SearchPath are the schemas searched for unqualified names, for DialectPostgres and DialectCockroach.
+		SearchPath []string
+		// TimeZone is the time zone of the session, e.g. "UTC" or "+00:00", for DialectPostgres,
+		// DialectCockroach and DialectMySQL.
+		TimeZone string
+		// SQLMode is the sql_mode of the session, e.g. "TRADITIONAL,ANSI_QUOTES", for DialectMySQL.
+		SQLMode string
+		// Pragmas are set in order, e.g. foreign_keys = ON and journal_mode = WAL, for DialectSQLite.
+		Pragmas []Pragma
+		// Statements are executed after the settings, e.g. to set what the fields above do not cover.
+		Statements []string
+	}
+	// Pragma is a SQLite PRAGMA set to a keyword, name or number, e.g. {Name: "busy_timeout", Value: "5000"}.
+	Pragma struct {
+		Name, Value string
+	}
+	sessionConnector struct {
+		driver.Connector
+		statements []string
+	}
+	dsnConnector struct {
+		driver driver.Driver
+		dsn    string
+	}
+)
+
+// Build returns the statements applying the settings.
+func (s *SessionSettings) Build() ([]string, error) {
+	if !s.Dialect.Valid() {
+		return nil, errors.Errorf("unknown dialect %q", s.Dialect)
+	}
+	postgres := s.Dialect == DialectPostgres || s.Dialect == DialectCockroach
+	unsupported := func(feature string) error {
+		return errors.WithStack(&UnsupportedError{Dialect: s.Dialect, Feature: feature})
+	}
+
+	var statements []string
+	if len(s.SearchPath) > 0 {
+		if !postgres {
+			return nil, unsupported("search_path")
+		}
+		schemas, err := quoteIdentifiers(s.Dialect, s.SearchPath)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, "SET search_path TO "+strings.Join(schemas, ", "))
+	}
+	if s.TimeZone != "" {
+		switch {
+		case postgres:
+			statements = append(statements, "SET TIME ZONE "+quoteLiteral(s.TimeZone))
+		case s.Dialect == DialectMySQL:
+			statements = append(statements, "SET time_zone = "+quoteLiteral(s.TimeZone))
+		default:
+			return nil, unsupported("the session time zone")
+		}
+	}
+	if s.SQLMode != "" {
+		if s.Dialect != DialectMySQL {
+			return nil, unsupported("sql_mode")
+		}
+		statements = append(statements, "SET SESSION sql_mode = "+quoteLiteral(s.SQLMode))
+	}
+	if len(s.Pragmas) > 0 && s.Dialect != DialectSQLite {
+		return nil, unsupported("PRAGMA")
+	}
+	for _, p := range s.Pragmas {
+		if !identifier.MatchString(p.Name) {
+			return nil, errors.Errorf("invalid pragma %q", p.Name)
+		}
+		if !pragmaValue.MatchString(p.Value) {
+			return nil, errors.Errorf("invalid value %q of the pragma %s", p.Value, p.Name)
+		}
+		statements = append(statements, "PRAGMA "+p.Name+" = "+p.Value)
+	}
+	return append(statements, s.Statements...), nil
+}
+
+// quoteLiteral quotes the value as a string literal, escaping single quotes by doubling them.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// NewSessionConnector returns a connector which applies the settings to every connection of the connector
+// before it is added to the pool. Connections whose settings fail are closed. Open the pool with sql.OpenDB:
+//
+//	connector, err := sqlcon.NewDriverConnector(&sqlite3.SQLiteDriver{}, "file:kratos.db")
+//	if err != nil {
+//		return err
+//	}
+//	connector, err = sqlcon.NewSessionConnector(connector, &sqlcon.SessionSettings{
+//		Dialect: sqlcon.DialectSQLite,
+//		Pragmas: []sqlcon.Pragma{{Name: "foreign_keys", Value: "ON"}, {Name: "journal_mode", Value: "WAL"}},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	db := sql.OpenDB(connector)
+//
+// Statements which change the settings later, e.g. SET search_path, only apply to the connection they run on.
+func NewSessionConnector(c driver.Connector, settings *SessionSettings) (driver.Connector, error) {
+	statements, err := settings.Build()
+	if err != nil {
+		return nil, err
+	}
+	return &sessionConnector{Connector: c, statements: statements}, nil
+}
+
+// Connect implements driver.Connector.
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range c.statements {
+		if err := execConn(ctx, conn, statement); err != nil {
+			_ = conn.Close()
+			return nil, errors.WithMessagef(err, "unable to apply the session setting %q", statement)
+		}
+	}
+	return conn, nil
+}
+
+// execConn executes the statement on the connection, preparing it if the driver does not execute statements
+// directly.
+func execConn(ctx context.Context, conn driver.Conn, statement string) error {
+	if e, ok := conn.(driver.ExecerContext); ok {
+		if _, err := e.ExecContext(ctx, statement, nil); !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	var stmt driver.Stmt
+	var err error
+	if p, ok := conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, statement)
+	} else {
+		stmt, err = conn.Prepare(statement)
+	}
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if e, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = e.ExecContext(ctx, nil)
+		return err
+	}
+	//nolint:staticcheck // the fallback for drivers without ExecContext
+	_, err = stmt.Exec(nil)
+	return err
+}
+
+// NewDriverConnector returns a connector which opens the DSN with the driver, e.g. to wrap it with
+// NewSessionConnector.
+func NewDriverConnector(d driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return &dsnConnector{driver: d, dsn: dsn}, nil
+}
+
+// Connect implements driver.Connector.
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements driver.Connector.
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
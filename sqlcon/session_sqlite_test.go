@@ -0,0 +1,69 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionConnector(t *testing.T) {
+	ctx := context.Background()
+	registered, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	d := registered.Driver()
+	require.NoError(t, registered.Close())
+
+	open := func(t *testing.T, settings *SessionSettings) *sql.DB {
+		connector, err := NewDriverConnector(d, filepath.Join(t.TempDir(), "session.db"))
+		require.NoError(t, err)
+		connector, err = NewSessionConnector(connector, settings)
+		require.NoError(t, err)
+		db := sql.OpenDB(connector)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	t.Run("case=applies the settings to every connection", func(t *testing.T) {
+		db := open(t, &SessionSettings{
+			Dialect: DialectSQLite,
+			Pragmas: []Pragma{{Name: "foreign_keys", Value: "ON"}, {Name: "journal_mode", Value: "WAL"}},
+		})
+
+		// hold two connections at once, so that both are new
+		conns := make([]*sql.Conn, 2)
+		for i := range conns {
+			conns[i], err = db.Conn(ctx)
+			require.NoError(t, err)
+			defer conns[i].Close()
+		}
+		for _, conn := range conns {
+			var foreignKeys int
+			require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys))
+			assert.Equal(t, 1, foreignKeys)
+			var journalMode string
+			require.NoError(t, conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode))
+			assert.Equal(t, "wal", journalMode)
+		}
+	})
+
+	t.Run("case=fails the connection if a setting fails", func(t *testing.T) {
+		db := open(t, &SessionSettings{Dialect: DialectSQLite, Statements: []string{"SELECT * FROM unknown"}})
+
+		err := db.PingContext(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unable to apply the session setting "SELECT * FROM unknown"`)
+	})
+
+	t.Run("case=rejects invalid settings", func(t *testing.T) {
+		_, err := NewSessionConnector(nil, &SessionSettings{Dialect: DialectSQLite, TimeZone: "UTC"})
+		assert.Error(t, err)
+	})
+}
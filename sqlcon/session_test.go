@@ -0,0 +1,81 @@
+package sqlcon
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionSettings(t *testing.T) {
+	t.Run("case=builds the settings of the dialect", func(t *testing.T) {
+		for _, tc := range []struct {
+			settings   SessionSettings
+			statements []string
+		}{
+			{
+				settings: SessionSettings{Dialect: DialectPostgres, SearchPath: []string{"kratos", "public"}, TimeZone: "Europe/Berlin"},
+				statements: []string{
+					`SET search_path TO "kratos", "public"`,
+					"SET TIME ZONE 'Europe/Berlin'",
+				},
+			},
+			{
+				settings:   SessionSettings{Dialect: DialectCockroach, TimeZone: "UTC", Statements: []string{"SET application_name = 'kratos'"}},
+				statements: []string{"SET TIME ZONE 'UTC'", "SET application_name = 'kratos'"},
+			},
+			{
+				settings:   SessionSettings{Dialect: DialectMySQL, TimeZone: "+00:00", SQLMode: "TRADITIONAL,ANSI_QUOTES"},
+				statements: []string{"SET time_zone = '+00:00'", "SET SESSION sql_mode = 'TRADITIONAL,ANSI_QUOTES'"},
+			},
+			{
+				settings: SessionSettings{Dialect: DialectSQLite, Pragmas: []Pragma{
+					{Name: "foreign_keys", Value: "ON"},
+					{Name: "journal_mode", Value: "WAL"},
+					{Name: "cache_size", Value: "-2000"},
+				}},
+				statements: []string{"PRAGMA foreign_keys = ON", "PRAGMA journal_mode = WAL", "PRAGMA cache_size = -2000"},
+			},
+			{
+				settings: SessionSettings{Dialect: DialectSQLServer},
+			},
+		} {
+			statements, err := tc.settings.Build()
+			require.NoError(t, err, "%+v", tc.settings)
+			assert.Equal(t, tc.statements, statements, "%+v", tc.settings)
+		}
+	})
+
+	t.Run("case=escapes the values", func(t *testing.T) {
+		statements, err := (&SessionSettings{Dialect: DialectMySQL, TimeZone: "'; DROP TABLE identities; --"}).Build()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"SET time_zone = '''; DROP TABLE identities; --'"}, statements)
+	})
+
+	t.Run("case=rejects unsupported settings", func(t *testing.T) {
+		for _, settings := range []SessionSettings{
+			{Dialect: DialectMySQL, SearchPath: []string{"kratos"}},
+			{Dialect: DialectSQLite, TimeZone: "UTC"},
+			{Dialect: DialectPostgres, SQLMode: "TRADITIONAL"},
+			{Dialect: DialectPostgres, Pragmas: []Pragma{{Name: "foreign_keys", Value: "ON"}}},
+		} {
+			_, err := settings.Build()
+			var unsupported *UnsupportedError
+			require.True(t, errors.As(err, &unsupported), "%+v", settings)
+			assert.Equal(t, settings.Dialect, unsupported.Dialect)
+		}
+	})
+
+	t.Run("case=rejects invalid settings", func(t *testing.T) {
+		for _, settings := range []SessionSettings{
+			{Dialect: "oracle"},
+			{Dialect: DialectPostgres, SearchPath: []string{"kratos; DROP TABLE identities"}},
+			{Dialect: DialectSQLite, Pragmas: []Pragma{{Name: "foreign_keys = ON; DROP TABLE identities", Value: "ON"}}},
+			{Dialect: DialectSQLite, Pragmas: []Pragma{{Name: "foreign_keys", Value: "ON; DROP TABLE identities"}}},
+		} {
+			_, err := settings.Build()
+			assert.Error(t, err, "%+v", settings)
+		}
+	})
+}
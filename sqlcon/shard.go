@@ -0,0 +1,246 @@
+package sqlcon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultHashRingReplicas is the number of points of every shard on a HashRing if none is set.
+const DefaultHashRingReplicas = 128
+
+type (
+	// ShardStrategy maps shard keys, e.g. tenant IDs, to the names of the shards.
+	ShardStrategy interface {
+		Shard(key string) (string, error)
+	}
+	// StaticShards maps the keys to the shards using a fixed map. Keys which are not mapped use the default
+	// shard, or fail if it is empty.
+	StaticShards struct {
+		Shards  map[string]string
+		Default string
+	}
+	// HashRing maps the keys to the shards using consistent hashing, so that adding or removing a shard only
+	// moves the keys of about one shard. A HashRing must not be changed once it is used by a ShardRouter,
+	// rebalance to a new ring instead.
+	HashRing struct {
+		replicas int
+		shards   map[string]bool
+		points   []uint64
+		owners   map[uint64]string
+	}
+	// ShardMove is a key which is mapped to another shard after rebalancing.
+	ShardMove struct {
+		Key, From, To string
+	}
+	// RebalanceHook is called when a ShardRouter rebalances from the old to the next strategy.
+	RebalanceHook func(ctx context.Context, old, next ShardStrategy) error
+	// ShardRouter routes shard keys to the connections of a Registry, e.g. to the database of a tenant, like
+	// the proxy's HostMapper routes hosts to their upstreams. The shards are the names of the connections.
+	//
+	// A ShardRouter is safe for concurrent use.
+	ShardRouter struct {
+		registry *Registry
+		before   []RebalanceHook
+		after    []RebalanceHook
+
+		mu          sync.RWMutex
+		rebalanceMu sync.Mutex
+		strategy    ShardStrategy
+	}
+	// ShardRouterOption configures NewShardRouter.
+	ShardRouterOption func(*ShardRouter)
+)
+
+var (
+	_ ShardStrategy = (*StaticShards)(nil)
+	_ ShardStrategy = (*HashRing)(nil)
+)
+
+// Shard implements ShardStrategy.
+func (s *StaticShards) Shard(key string) (string, error) {
+	if shard, ok := s.Shards[key]; ok {
+		return shard, nil
+	}
+	if s.Default == "" {
+		return "", errors.Errorf("the shard key %q is not mapped to a shard", key)
+	}
+	return s.Default, nil
+}
+
+// NewHashRing returns a ring of the shards with the number of points per shard, which defaults to
+// DefaultHashRingReplicas. More points distribute the keys more evenly.
+func NewHashRing(replicas int, shards ...string) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+	r := &HashRing{replicas: replicas, shards: map[string]bool{}, owners: map[uint64]string{}}
+	for _, shard := range shards {
+		r.Add(shard)
+	}
+	return r
+}
+
+// hashRingKey hashes the key to its point on the ring. Unlike FNV, SHA-256 spreads similar keys such as
+// "shard-1#0" and "shard-1#1" evenly.
+func hashRingKey(key string) uint64 {
+	sum := sha256.Sum256([]byte(key))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// Add adds the shard to the ring.
+func (r *HashRing) Add(shard string) {
+	if r.shards[shard] {
+		return
+	}
+	r.shards[shard] = true
+	for i := 0; i < r.replicas; i++ {
+		point := hashRingKey(fmt.Sprintf("%s#%d", shard, i))
+		if _, ok := r.owners[point]; ok {
+			continue
+		}
+		r.owners[point] = shard
+		r.points = append(r.points, point)
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+}
+
+// Remove removes the shard from the ring.
+func (r *HashRing) Remove(shard string) {
+	if !r.shards[shard] {
+		return
+	}
+	delete(r.shards, shard)
+	points := r.points[:0]
+	for _, point := range r.points {
+		if r.owners[point] == shard {
+			delete(r.owners, point)
+			continue
+		}
+		points = append(points, point)
+	}
+	r.points = points
+}
+
+// Shards returns the sorted shards of the ring.
+func (r *HashRing) Shards() []string {
+	shards := make([]string, 0, len(r.shards))
+	for shard := range r.shards {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	return shards
+}
+
+// Shard implements ShardStrategy. It fails if the ring is empty.
+func (r *HashRing) Shard(key string) (string, error) {
+	if len(r.points) == 0 {
+		return "", errors.New("the hash ring has no shards")
+	}
+	h := hashRingKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], nil
+}
+
+// ShardMoves returns the keys which the next strategy maps to other shards than the old one, e.g. to migrate
+// the data of known tenants in a RebalanceHook.
+func ShardMoves(old, next ShardStrategy, keys []string) ([]ShardMove, error) {
+	var moves []ShardMove
+	for _, key := range keys {
+		from, err := old.Shard(key)
+		if err != nil {
+			return nil, err
+		}
+		to, err := next.Shard(key)
+		if err != nil {
+			return nil, err
+		}
+		if from != to {
+			moves = append(moves, ShardMove{Key: key, From: from, To: to})
+		}
+	}
+	return moves, nil
+}
+
+// WithBeforeRebalance adds a hook which is called before the router switches to the new strategy, e.g. to
+// copy the data of moving keys. If it fails, the router keeps the old strategy.
+func WithBeforeRebalance(hook RebalanceHook) ShardRouterOption {
+	return func(r *ShardRouter) {
+		r.before = append(r.before, hook)
+	}
+}
+
+// WithAfterRebalance adds a hook which is called after the router switched to the new strategy, e.g. to
+// delete the data of moved keys from their old shards. Its errors are returned by Rebalance.
+func WithAfterRebalance(hook RebalanceHook) ShardRouterOption {
+	return func(r *ShardRouter) {
+		r.after = append(r.after, hook)
+	}
+}
+
+// NewShardRouter returns a router which maps the keys to the connections of the registry using the strategy.
+func NewShardRouter(registry *Registry, strategy ShardStrategy, opts ...ShardRouterOption) *ShardRouter {
+	r := &ShardRouter{registry: registry, strategy: strategy}
+	for _, f := range opts {
+		f(r)
+	}
+	return r
+}
+
+// Strategy returns the current strategy.
+func (r *ShardRouter) Strategy() ShardStrategy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.strategy
+}
+
+// Shard returns the name of the shard of the key.
+func (r *ShardRouter) Shard(key string) (string, error) {
+	return r.Strategy().Shard(key)
+}
+
+// Route returns the connection of the shard of the key.
+func (r *ShardRouter) Route(key string) (*Connection, error) {
+	shard, err := r.Shard(key)
+	if err != nil {
+		return nil, err
+	}
+	conn, ok := r.registry.Get(shard)
+	if !ok {
+		return nil, errors.Errorf("the shard %q of the shard key %q is not registered", shard, key)
+	}
+	return conn, nil
+}
+
+// Rebalance switches to the new strategy, running the hooks before and after. Rebalances do not run
+// concurrently; keys are routed using the old strategy until the hooks before succeeded.
+func (r *ShardRouter) Rebalance(ctx context.Context, strategy ShardStrategy) error {
+	r.rebalanceMu.Lock()
+	defer r.rebalanceMu.Unlock()
+
+	old := r.Strategy()
+	for _, hook := range r.before {
+		if err := hook(ctx, old, strategy); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.strategy = strategy
+	r.mu.Unlock()
+
+	for _, hook := range r.after {
+		if err := hook(ctx, old, strategy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
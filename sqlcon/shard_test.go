@@ -0,0 +1,117 @@
+package sqlcon
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticShards(t *testing.T) {
+	s := &StaticShards{Shards: map[string]string{"tenant-a": "shard-1"}}
+	shard, err := s.Shard("tenant-a")
+	require.NoError(t, err)
+	assert.Equal(t, "shard-1", shard)
+	_, err = s.Shard("tenant-b")
+	assert.EqualError(t, err, `the shard key "tenant-b" is not mapped to a shard`)
+
+	s.Default = "shard-0"
+	shard, err = s.Shard("tenant-b")
+	require.NoError(t, err)
+	assert.Equal(t, "shard-0", shard)
+}
+
+func TestHashRing(t *testing.T) {
+	_, err := NewHashRing(0).Shard("tenant")
+	assert.Error(t, err)
+
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("tenant-%d", i)
+	}
+	ring := NewHashRing(0, "shard-1", "shard-2", "shard-3")
+	assert.Equal(t, []string{"shard-1", "shard-2", "shard-3"}, ring.Shards())
+
+	counts := map[string]int{}
+	for _, key := range keys {
+		shard, err := ring.Shard(key)
+		require.NoError(t, err)
+		counts[shard]++
+
+		again, err := ring.Shard(key)
+		require.NoError(t, err)
+		assert.Equal(t, shard, again, "the mapping is stable")
+	}
+	for shard, count := range counts {
+		assert.InDelta(t, len(keys)/3, count, float64(len(keys))/10, "shard %s", shard)
+	}
+
+	grown := NewHashRing(0, "shard-1", "shard-2", "shard-3", "shard-4")
+	moves, err := ShardMoves(ring, grown, keys)
+	require.NoError(t, err)
+	assert.InDelta(t, len(keys)/4, len(moves), float64(len(keys))/10)
+	for _, m := range moves {
+		assert.Equal(t, "shard-4", m.To, "keys only move to the new shard")
+	}
+
+	grown.Remove("shard-4")
+	moves, err = ShardMoves(ring, grown, keys)
+	require.NoError(t, err)
+	assert.Empty(t, moves)
+}
+
+func TestShardRouter(t *testing.T) {
+	ctx := context.Background()
+	r := NewRegistry()
+	defer r.Close()
+	for _, name := range []string{"shard-1", "shard-2"} {
+		_, err := r.Open(name, "sqlcon-fake", "router-"+name, nil)
+		require.NoError(t, err)
+	}
+
+	var calls []string
+	var moves []ShardMove
+	fail := false
+	router := NewShardRouter(r, &StaticShards{Default: "shard-1"},
+		WithBeforeRebalance(func(_ context.Context, old, next ShardStrategy) (err error) {
+			calls = append(calls, "before")
+			if fail {
+				return errors.New("expected")
+			}
+			moves, err = ShardMoves(old, next, []string{"tenant-a", "tenant-b"})
+			return err
+		}),
+		WithAfterRebalance(func(context.Context, ShardStrategy, ShardStrategy) error {
+			calls = append(calls, "after")
+			return nil
+		}),
+	)
+
+	conn, err := router.Route("tenant-a")
+	require.NoError(t, err)
+	rows, err := conn.QueryContext(ctx, "SELECT 1")
+	assert.Equal(t, "router-shard-1", servedBy(t, rows, err))
+
+	next := &StaticShards{Shards: map[string]string{"tenant-a": "shard-2", "tenant-c": "shard-3"}, Default: "shard-1"}
+	fail = true
+	assert.EqualError(t, router.Rebalance(ctx, next), "expected")
+	assert.IsType(t, &StaticShards{}, router.Strategy())
+	assert.NotSame(t, next, router.Strategy(), "the old strategy is kept if a hook fails")
+
+	fail = false
+	require.NoError(t, router.Rebalance(ctx, next))
+	assert.Same(t, next, router.Strategy())
+	assert.Equal(t, []string{"before", "before", "after"}, calls)
+	assert.Equal(t, []ShardMove{{Key: "tenant-a", From: "shard-1", To: "shard-2"}}, moves)
+
+	conn, err = router.Route("tenant-a")
+	require.NoError(t, err)
+	rows, err = conn.QueryContext(ctx, "SELECT 1")
+	assert.Equal(t, "router-shard-2", servedBy(t, rows, err))
+
+	_, err = router.Route("tenant-c")
+	assert.EqualError(t, err, `the shard "shard-3" of the shard key "tenant-c" is not registered`)
+}
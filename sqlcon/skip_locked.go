@@ -0,0 +1,123 @@
+package sqlcon
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// UnsupportedError is returned if a dialect does not support a feature.
+type UnsupportedError struct {
+	Dialect Dialect
+	// Feature describes the feature, e.g. "SKIP LOCKED".
+	Feature string
+}
+
+func (e *UnsupportedError) Error() string {
+	return fmt.Sprintf("%s is not supported by the dialect %q", e.Feature, e.Dialect)
+}
+
+// SkipLocked builds SELECT statements which lock the selected rows, skipping rows locked by other transactions,
+// to implement work queues where several workers claim jobs concurrently:
+//
+//	query, err := (&sqlcon.SkipLocked{
+//		Dialect: sqlcon.DialectPostgres,
+//		Table:   "jobs",
+//		Columns: []string{"id", "payload"},
+//		Where:   "state = 'pending' AND run_at <= $1",
+//		OrderBy: []string{"run_at"},
+//		Limit:   10,
+//	}).Build()
+//
+// Run the statement in a transaction, e.g. using WithTransaction, and update the claimed rows in it. The locks
+// are held until the transaction ends.
+//
+// PostgreSQL, CockroachDB and MySQL use FOR UPDATE SKIP LOCKED. SQL Server does not support it, so the table
+// hints UPDLOCK, READPAST and ROWLOCK are used instead, which behave the same. SQLite has no row locks, so an
+// *UnsupportedError is returned.
+type SkipLocked struct {
+	// Dialect determines the syntax.
+	Dialect Dialect
+	// Table is the table to select from, optionally qualified by the schema.
+	Table string
+	// Columns are the selected columns.
+	Columns []string
+	// Where is the condition of the rows, with parameters in the syntax of the dialect. It is optional.
+	Where string
+	// OrderBy are the columns to order by, each optionally followed by " ASC" or " DESC". It is optional.
+	OrderBy []string
+	// Limit is the maximum number of selected rows. It is optional.
+	Limit int
+}
+
+// orderBy returns the quoted ORDER BY columns.
+func (s *SkipLocked) orderBy() ([]string, error) {
+	order := make([]string, len(s.OrderBy))
+	for i, o := range s.OrderBy {
+		column, direction := o, ""
+		if fields := strings.Fields(o); len(fields) == 2 && (strings.EqualFold(fields[1], "ASC") || strings.EqualFold(fields[1], "DESC")) {
+			column, direction = fields[0], " "+strings.ToUpper(fields[1])
+		}
+		quoted, err := quoteIdentifier(s.Dialect, column)
+		if err != nil {
+			return nil, err
+		}
+		order[i] = quoted + direction
+	}
+	return order, nil
+}
+
+// Build returns the statement, or an *UnsupportedError if the dialect does not support skipping locked rows.
+func (s *SkipLocked) Build() (string, error) {
+	switch s.Dialect {
+	case DialectPostgres, DialectCockroach, DialectMySQL, DialectSQLServer:
+	case DialectSQLite:
+		return "", errors.WithStack(&UnsupportedError{Dialect: s.Dialect, Feature: "SKIP LOCKED"})
+	default:
+		return "", errors.Errorf("unknown dialect %q", s.Dialect)
+	}
+	if len(s.Columns) == 0 {
+		return "", errors.New("no columns to select")
+	} else if s.Limit < 0 {
+		return "", errors.Errorf("invalid limit %d", s.Limit)
+	}
+
+	table, err := quoteIdentifier(s.Dialect, s.Table)
+	if err != nil {
+		return "", err
+	}
+	columns, err := quoteIdentifiers(s.Dialect, s.Columns)
+	if err != nil {
+		return "", err
+	}
+	order, err := s.orderBy()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	if s.Dialect == DialectSQLServer && s.Limit > 0 {
+		b.WriteString("TOP (" + strconv.Itoa(s.Limit) + ") ")
+	}
+	fmt.Fprintf(&b, "%s FROM %s", strings.Join(columns, ", "), table)
+	if s.Dialect == DialectSQLServer {
+		b.WriteString(" WITH (UPDLOCK, READPAST, ROWLOCK)")
+	}
+	if s.Where != "" {
+		b.WriteString(" WHERE " + s.Where)
+	}
+	if len(order) > 0 {
+		b.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	}
+	if s.Dialect == DialectSQLServer {
+		return b.String(), nil
+	}
+	if s.Limit > 0 {
+		b.WriteString(" LIMIT " + strconv.Itoa(s.Limit))
+	}
+	b.WriteString(" FOR UPDATE SKIP LOCKED")
+	return b.String(), nil
+}
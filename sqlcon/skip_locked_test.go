@@ -0,0 +1,72 @@
+package sqlcon
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipLocked(t *testing.T) {
+	for _, tc := range []struct {
+		dialect  Dialect
+		where    string
+		expected string
+	}{
+		{
+			dialect:  DialectPostgres,
+			where:    "state = $1",
+			expected: `SELECT "id", "payload" FROM "public"."jobs" WHERE state = $1 ORDER BY "run_at", "id" DESC LIMIT 10 FOR UPDATE SKIP LOCKED`,
+		},
+		{
+			dialect:  DialectCockroach,
+			where:    "state = $1",
+			expected: `SELECT "id", "payload" FROM "public"."jobs" WHERE state = $1 ORDER BY "run_at", "id" DESC LIMIT 10 FOR UPDATE SKIP LOCKED`,
+		},
+		{
+			dialect:  DialectMySQL,
+			where:    "state = ?",
+			expected: "SELECT `id`, `payload` FROM `public`.`jobs` WHERE state = ? ORDER BY `run_at`, `id` DESC LIMIT 10 FOR UPDATE SKIP LOCKED",
+		},
+		{
+			dialect:  DialectSQLServer,
+			where:    "state = @p1",
+			expected: `SELECT TOP (10) "id", "payload" FROM "public"."jobs" WITH (UPDLOCK, READPAST, ROWLOCK) WHERE state = @p1 ORDER BY "run_at", "id" DESC`,
+		},
+	} {
+		t.Run("dialect="+tc.dialect.String(), func(t *testing.T) {
+			query, err := (&SkipLocked{
+				Dialect: tc.dialect,
+				Table:   "public.jobs",
+				Columns: []string{"id", "payload"},
+				Where:   tc.where,
+				OrderBy: []string{"run_at", "id desc"},
+				Limit:   10,
+			}).Build()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, query)
+		})
+	}
+
+	query, err := (&SkipLocked{Dialect: DialectPostgres, Table: "jobs", Columns: []string{"id"}}).Build()
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT "id" FROM "jobs" FOR UPDATE SKIP LOCKED`, query)
+
+	_, err = (&SkipLocked{Dialect: DialectSQLite, Table: "jobs", Columns: []string{"id"}}).Build()
+	var unsupported *UnsupportedError
+	require.ErrorAs(t, err, &unsupported)
+	assert.Equal(t, DialectSQLite, unsupported.Dialect)
+	assert.EqualError(t, err, `SKIP LOCKED is not supported by the dialect "sqlite"`)
+
+	for _, s := range []SkipLocked{
+		{Dialect: "oracle", Table: "jobs", Columns: []string{"id"}},
+		{Dialect: DialectPostgres, Table: "jobs"},
+		{Dialect: DialectPostgres, Table: "jobs", Columns: []string{"id"}, Limit: -1},
+		{Dialect: DialectPostgres, Table: "jobs", Columns: []string{"id"}, OrderBy: []string{"id; DROP TABLE jobs"}},
+	} {
+		_, err := s.Build()
+		assert.Error(t, err)
+		assert.False(t, errors.As(err, &unsupported))
+	}
+}
@@ -0,0 +1,57 @@
+package sqlcon
+
+import (
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// sqlStates are the SQLSTATEs of the error classes, ordered from specific to generic.
+var sqlStates = []struct {
+	class error
+	state string
+}{
+	{class: ErrDeadlock, state: "40P01"},
+	{class: ErrSerializationFailure, state: "40001"},
+	{class: ErrConcurrentUpdate, state: "40001"},
+	{class: ErrAmbiguousResult, state: "40003"},
+	{class: ErrUniqueViolation, state: "23505"},
+	{class: ErrForeignKeyViolation, state: "23503"},
+	{class: ErrNotNullViolation, state: "23502"},
+	{class: ErrCheckViolation, state: "23514"},
+	{class: ErrValueTooLong, state: "22001"},
+	{class: ErrNumericOverflow, state: "22003"},
+	{class: ErrReadOnly, state: "25006"},
+	{class: ErrLockTimeout, state: "55P03"},
+	{class: ErrNoSuchTable, state: "42P01"},
+	{class: ErrNoSuchColumn, state: "42703"},
+	{class: ErrStatementTimeout, state: "57014"},
+	{class: ErrAuthenticationFailed, state: "28000"},
+	{class: ErrPermissionDenied, state: "42501"},
+	{class: ErrTooManyConnections, state: "53300"},
+	{class: ErrConnectionFailed, state: "08006"},
+	{class: ErrNoRows, state: "02000"},
+}
+
+// SQLState returns the SQLSTATE of the driver error wrapped by err, e.g. "23505" for a unique violation.
+// For drivers which do not report SQLSTATEs, such as SQLite, MySQL and SQL Server, the SQLSTATE of the
+// error class detected by HandleError is returned instead. It returns false if the SQLSTATE is unknown.
+func SQLState(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+
+	var st stater
+	if errors.As(err, &st) {
+		return st.SQLState(), true
+	} else if e := new(pq.Error); errors.As(err, &e) {
+		return string(e.Code), true
+	}
+
+	handled := HandleError(err)
+	for _, s := range sqlStates {
+		if errors.Is(handled, s.class) {
+			return s.state, true
+		}
+	}
+	return "", false
+}
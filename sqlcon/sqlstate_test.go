@@ -0,0 +1,46 @@
+package sqlcon
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSQLState(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected string
+	}{
+		{err: &pgconnv5.PgError{Code: "22001"}, expected: "22001"},
+		{err: fmt.Errorf("wrapped: %w", &pq.Error{Code: "42703"}), expected: "42703"},
+		{err: HandleError(&pgconnv5.PgError{Code: "23505"}), expected: "23505"},
+		{err: &mysql.MySQLError{Number: 1062}, expected: "23505"},
+		{err: &mysql.MySQLError{Number: 1213}, expected: "40P01"},
+		{err: HandleError(&mysql.MySQLError{Number: 1452}), expected: "23503"},
+		{err: &mysql.MySQLError{Number: 1048}, expected: "23502"},
+		{err: &mysql.MySQLError{Number: 1205}, expected: "55P03"},
+		{err: &mysql.MySQLError{Number: 1054}, expected: "42703"},
+		{err: &mysql.MySQLError{Number: 1406}, expected: "22001"},
+		{err: &mysql.MySQLError{Number: 1040}, expected: "53300"},
+		{err: &mysql.MySQLError{Number: 1045}, expected: "28000"},
+		{err: &mysql.MySQLError{Number: 1142}, expected: "42501"},
+		{err: sql.ErrNoRows, expected: "02000"},
+	} {
+		t.Run(fmt.Sprintf("err=%s", tc.err), func(t *testing.T) {
+			state, ok := SQLState(tc.err)
+			assert.True(t, ok)
+			assert.Equal(t, tc.expected, state)
+		})
+	}
+
+	for _, err := range []error{nil, errors.New("foo"), &mysql.MySQLError{Number: 1050}} {
+		_, ok := SQLState(err)
+		assert.False(t, ok, "%s", err)
+	}
+}
@@ -0,0 +1,88 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// StatementError is the error of a statement run by Statements.
+	StatementError struct {
+		// Statement is the redacted statement.
+		Statement string
+		// Took is the duration until the statement failed.
+		Took time.Duration
+		// Err is the error handled by RedactError.
+		Err error
+	}
+	// Statements runs statements on the DB, so that repositories cannot forget to handle their errors:
+	//
+	//	db := &sqlcon.Statements{DB: pool, SlowQueryLogger: &sqlcon.SlowQueryLogger{Logger: l}}
+	//	_, err := db.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < $1", now)
+	//	if errors.Is(err, sqlcon.ErrTimeout) {
+	//		// ...
+	//	}
+	//
+	// Errors are returned as *StatementError, which carries the redacted statement and its duration, and
+	// unwraps to the error handled by HandleError, so that the error classes can be matched with errors.Is.
+	// Statements implements Execer and Querier.
+	Statements struct {
+		// DB runs the statements, e.g. *sql.DB, *sql.Tx or Connection.
+		DB Executor
+		// Redactor redacts the statements of the errors.
+		// Default: RedactLiterals
+		Redactor Redactor
+		// SlowQueryLogger observes the durations of the statements. It is optional.
+		SlowQueryLogger *SlowQueryLogger
+	}
+)
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("statement %q failed after %s: %s", e.Statement, e.Took, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
+// run runs the statement unless the context is done already, in which case ErrTimeout or ErrCanceled is
+// returned without waiting for a connection.
+func (s *Statements) run(ctx context.Context, query string, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return s.error(query, 0, err)
+	}
+	start := time.Now()
+	err := fn()
+	took := time.Since(start)
+	s.SlowQueryLogger.Observe(query, took, err)
+	if err != nil {
+		return s.error(query, took, err)
+	}
+	return nil
+}
+
+func (s *Statements) error(query string, took time.Duration, err error) error {
+	return errors.WithStack(&StatementError{Statement: s.Redactor.Redact(query), Took: took, Err: RedactError(err)})
+}
+
+// ExecContext executes the statement.
+func (s *Statements) ExecContext(ctx context.Context, query string, args ...interface{}) (res sql.Result, err error) {
+	err = s.run(ctx, query, func() (err error) {
+		res, err = s.DB.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+// QueryContext runs the query. The errors of the returned rows are not handled.
+func (s *Statements) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	err = s.run(ctx, query, func() (err error) {
+		rows, err = s.DB.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
@@ -0,0 +1,62 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatementsSQLite(t *testing.T) {
+	ctx := context.Background()
+	pool, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer pool.Close()
+	pool.SetMaxOpenConns(1)
+
+	l := new(recordingLogger)
+	db := &Statements{DB: pool, SlowQueryLogger: &SlowQueryLogger{Logger: l, Threshold: time.Nanosecond}}
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE users (email TEXT PRIMARY KEY)")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "INSERT INTO users (email) VALUES ('foo@bar.com')")
+	require.NoError(t, err)
+
+	_, err = db.ExecContext(ctx, "INSERT INTO users (email) VALUES ('foo@bar.com')")
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	var violation *ConstraintViolation
+	assert.ErrorAs(t, err, &violation)
+	var stmtErr *StatementError
+	require.ErrorAs(t, err, &stmtErr)
+	assert.Equal(t, "INSERT INTO users (email) VALUES (?)", stmtErr.Statement)
+	assert.NotZero(t, stmtErr.Took)
+	assert.NotContains(t, err.Error(), "foo@bar.com")
+	assert.Contains(t, err.Error(), `statement "INSERT INTO users (email) VALUES (?)" failed after`)
+	assert.Len(t, l.messages, 3)
+
+	rows, err := db.QueryContext(ctx, "SELECT email FROM users")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+
+	_, err = db.QueryContext(ctx, "SELECT * FROM does_not_exist WHERE email = 'foo@bar.com'")
+	assert.ErrorIs(t, err, ErrNoSuchTable)
+	require.ErrorAs(t, err, &stmtErr)
+	assert.Equal(t, "SELECT * FROM does_not_exist WHERE email = ?", stmtErr.Statement)
+
+	t.Run("case=does not run statements if the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, -time.Second)
+		defer cancel()
+		_, err := db.ExecContext(ctx, "DELETE FROM users")
+		assert.ErrorIs(t, err, ErrTimeout)
+
+		var count int
+		require.NoError(t, pool.QueryRow("SELECT COUNT(*) FROM users").Scan(&count))
+		assert.Equal(t, 1, count)
+	})
+}
@@ -0,0 +1,262 @@
+package sqlcon
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultStmtCacheSize is the number of statements of a StmtCache if no size is set.
+const DefaultStmtCacheSize = 256
+
+type (
+	// Preparer is implemented by *sql.DB and *sql.Conn.
+	Preparer interface {
+		PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	}
+	// StmtCache caches prepared statements by their query, so that hot statements are prepared once instead of
+	// on every call. When the cache is full, the least recently used statement is closed once it is not in use
+	// anymore. It implements Execer and Querier. Errors are handled by HandleError.
+	//
+	// Only cache statements with placeholders for their values, as every distinct query occupies an entry.
+	StmtCache struct {
+		db      Preparer
+		size    int
+		metrics *StmtCacheMetrics
+		name    string
+
+		mu      sync.Mutex
+		lru     *list.List
+		entries map[string]*list.Element
+	}
+	stmtCacheEntry struct {
+		query   string
+		stmt    *sql.Stmt
+		ready   chan struct{}
+		err     error
+		refs    int
+		evicted bool
+	}
+	// StmtCacheOption configures NewStmtCache.
+	StmtCacheOption func(*StmtCache)
+	// StmtCacheMetrics exports the hits, misses, evictions and size of statement caches as Prometheus metrics,
+	// labeled by "cache". It is a prometheus.Collector.
+	StmtCacheMetrics struct {
+		hits      *prometheus.CounterVec
+		misses    *prometheus.CounterVec
+		evictions *prometheus.CounterVec
+		size      *prometheus.GaugeVec
+	}
+)
+
+// NewStmtCacheMetrics creates the metrics. The prefix is prepended to the metric names, separated by "_".
+func NewStmtCacheMetrics(prefix string) *StmtCacheMetrics {
+	if prefix != "" {
+		prefix += "_"
+	}
+	counter := func(name, help string) *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: prefix + name, Help: help}, []string{"cache"})
+	}
+
+	return &StmtCacheMetrics{
+		hits:      counter("sql_stmt_cache_hits_total", "total number of statements served from the cache"),
+		misses:    counter("sql_stmt_cache_misses_total", "total number of statements prepared because they were not cached"),
+		evictions: counter("sql_stmt_cache_evictions_total", "total number of statements evicted from the cache"),
+		size: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prefix + "sql_stmt_cache_statements", Help: "number of cached statements",
+		}, []string{"cache"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *StmtCacheMetrics) Describe(in chan<- *prometheus.Desc) {
+	m.hits.Describe(in)
+	m.misses.Describe(in)
+	m.evictions.Describe(in)
+	m.size.Describe(in)
+}
+
+// Collect implements prometheus.Collector.
+func (m *StmtCacheMetrics) Collect(in chan<- prometheus.Metric) {
+	m.hits.Collect(in)
+	m.misses.Collect(in)
+	m.evictions.Collect(in)
+	m.size.Collect(in)
+}
+
+// WithStmtCacheSize sets the maximum number of cached statements.
+// Default: DefaultStmtCacheSize
+func WithStmtCacheSize(n int) StmtCacheOption {
+	return func(c *StmtCache) {
+		c.size = n
+	}
+}
+
+// WithStmtCacheMetrics records the hits, misses, evictions and size of the cache with the "cache" label.
+func WithStmtCacheMetrics(m *StmtCacheMetrics, cache string) StmtCacheOption {
+	return func(c *StmtCache) {
+		c.metrics, c.name = m, cache
+	}
+}
+
+// NewStmtCache returns an empty cache of the statements prepared on the db.
+func NewStmtCache(db Preparer, opts ...StmtCacheOption) *StmtCache {
+	c := &StmtCache{db: db, size: DefaultStmtCacheSize, lru: list.New(), entries: map[string]*list.Element{}}
+	for _, f := range opts {
+		f(c)
+	}
+	if c.size <= 0 {
+		c.size = DefaultStmtCacheSize
+	}
+	return c
+}
+
+// acquire returns the cached entry of the query, preparing the statement if it is not cached. The entry must
+// be released after the statement was used.
+func (c *StmtCache) acquire(ctx context.Context, query string) (*stmtCacheEntry, error) {
+	c.mu.Lock()
+	if el, ok := c.entries[query]; ok {
+		e := el.Value.(*stmtCacheEntry)
+		e.refs++
+		c.lru.MoveToFront(el)
+		c.mu.Unlock()
+		if c.metrics != nil {
+			c.metrics.hits.WithLabelValues(c.name).Inc()
+		}
+
+		// the statement may still be prepared by another caller
+		<-e.ready
+		if e.err != nil {
+			c.release(e)
+			return nil, e.err
+		}
+		return e, nil
+	}
+
+	e := &stmtCacheEntry{query: query, ready: make(chan struct{}), refs: 1}
+	c.entries[query] = c.lru.PushFront(e)
+	c.mu.Unlock()
+	if c.metrics != nil {
+		c.metrics.misses.WithLabelValues(c.name).Inc()
+	}
+
+	e.stmt, e.err = c.db.PrepareContext(ctx, query)
+	e.err = HandleError(e.err)
+	close(e.ready)
+	if e.err != nil {
+		c.remove(e)
+		c.release(e)
+		return nil, e.err
+	}
+	c.evict()
+	return e, nil
+}
+
+// evict removes the least recently used statements above the size, and closes those not in use.
+func (c *StmtCache) evict() {
+	c.mu.Lock()
+	var evicted, closing []*stmtCacheEntry
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Remove(c.lru.Back()).(*stmtCacheEntry)
+		delete(c.entries, oldest.query)
+		oldest.evicted = true
+		evicted = append(evicted, oldest)
+		if oldest.refs == 0 {
+			closing = append(closing, oldest)
+		}
+	}
+	size := c.lru.Len()
+	c.mu.Unlock()
+
+	if c.metrics != nil {
+		c.metrics.evictions.WithLabelValues(c.name).Add(float64(len(evicted)))
+		c.metrics.size.WithLabelValues(c.name).Set(float64(size))
+	}
+	for _, e := range closing {
+		_ = e.stmt.Close()
+	}
+}
+
+// remove removes the entry of a statement which failed to prepare, so that it is prepared again.
+func (c *StmtCache) remove(e *stmtCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.query]; ok && el.Value == e {
+		c.lru.Remove(el)
+		delete(c.entries, e.query)
+	}
+}
+
+// release closes the statement if it was evicted and is not used anymore.
+func (c *StmtCache) release(e *stmtCacheEntry) {
+	c.mu.Lock()
+	e.refs--
+	closing := e.evicted && e.refs == 0 && e.stmt != nil
+	c.mu.Unlock()
+	if closing {
+		_ = e.stmt.Close()
+	}
+}
+
+// ExecContext executes the cached statement of the query.
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(e)
+
+	res, err := e.stmt.ExecContext(ctx, args...)
+	return res, HandleError(err)
+}
+
+// QueryContext runs the cached statement of the query. The statement stays open until the rows are closed,
+// even if it is evicted meanwhile.
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	e, err := c.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(e)
+
+	rows, err := e.stmt.QueryContext(ctx, args...)
+	return rows, HandleError(err)
+}
+
+// Len returns the number of cached statements.
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Close closes and removes all cached statements. Statements in use are closed once they are released.
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	var closing []*stmtCacheEntry
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*stmtCacheEntry)
+		e.evicted = true
+		if e.refs == 0 {
+			closing = append(closing, e)
+		}
+	}
+	c.lru.Init()
+	c.entries = map[string]*list.Element{}
+	c.mu.Unlock()
+
+	if c.metrics != nil {
+		c.metrics.size.WithLabelValues(c.name).Set(0)
+	}
+	var err error
+	for _, e := range closing {
+		if cerr := e.stmt.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return errors.WithStack(err)
+}
@@ -0,0 +1,95 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStmtCacheSQLite(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY, state TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	m := NewStmtCacheMetrics("test")
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(m))
+	c := NewStmtCache(db, WithStmtCacheSize(2), WithStmtCacheMetrics(m, "primary"))
+	defer c.Close()
+
+	const insert = "INSERT INTO identities (id, state) VALUES (?, ?)"
+	for i := 0; i < 3; i++ {
+		_, err := c.ExecContext(ctx, insert, i, "active")
+		require.NoError(t, err)
+	}
+	_, err = c.ExecContext(ctx, insert, 0, "active")
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.misses.WithLabelValues("primary")))
+	assert.Equal(t, float64(3), testutil.ToFloat64(m.hits.WithLabelValues("primary")))
+
+	t.Run("case=evicts the least recently used statement", func(t *testing.T) {
+		rows, err := c.QueryContext(ctx, "SELECT id FROM identities ORDER BY id")
+		require.NoError(t, err)
+
+		// evicts the insert and then the query, which is still in use
+		_, err = c.ExecContext(ctx, "UPDATE identities SET state = ? WHERE id = ?", "inactive", 1)
+		require.NoError(t, err)
+		other, err := c.QueryContext(ctx, "SELECT 1 FROM identities WHERE id = ?", 2)
+		require.NoError(t, err)
+		require.NoError(t, other.Close())
+		assert.Equal(t, 2, c.Len())
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.evictions.WithLabelValues("primary")))
+		assert.Equal(t, float64(2), testutil.ToFloat64(m.size.WithLabelValues("primary")))
+
+		var ids []int
+		for rows.Next() {
+			var id int
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		require.NoError(t, rows.Close())
+		assert.Equal(t, []int{0, 1, 2}, ids)
+	})
+
+	t.Run("case=does not cache statements which fail to prepare", func(t *testing.T) {
+		_, err := c.QueryContext(ctx, "SELECT * FROM does_not_exist")
+		assert.ErrorIs(t, err, ErrNoSuchTable)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("case=concurrent use", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var count int
+				rows, err := c.QueryContext(ctx, []string{"SELECT COUNT(*) FROM identities", "SELECT COUNT(id) FROM identities", "SELECT COUNT(state) FROM identities"}[i%3])
+				require.NoError(t, err)
+				defer rows.Close()
+				require.True(t, rows.Next())
+				require.NoError(t, rows.Scan(&count))
+				assert.Equal(t, 3, count)
+			}(i)
+		}
+		wg.Wait()
+		assert.Equal(t, 2, c.Len())
+	})
+
+	require.NoError(t, c.Close())
+	assert.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,61 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+type statementTimeoutContextKey struct{}
+
+// mysqlSelect matches the SELECT keyword which the optimizer hint must follow
+var mysqlSelect = regexp.MustCompile(`(?i)^(\s*SELECT)\b`)
+
+// WithStatementTimeout returns a context which bounds the statements prepared by ApplyStatementTimeout to the timeout.
+func WithStatementTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, statementTimeoutContextKey{}, d)
+}
+
+// StatementTimeout returns the timeout set by WithStatementTimeout.
+func StatementTimeout(ctx context.Context) (time.Duration, bool) {
+	d, ok := ctx.Value(statementTimeoutContextKey{}).(time.Duration)
+	return d, ok && d > 0
+}
+
+// ApplyStatementTimeout prepares running the query with the timeout of WithStatementTimeout, using the mechanism
+// of the dialect:
+//
+//   - postgres and cockroach: "SET LOCAL statement_timeout" is executed in the transaction, which bounds all
+//     following statements of it
+//   - mysql: a MAX_EXECUTION_TIME optimizer hint is added to SELECT queries
+//   - all others, and PostgreSQL without a transaction: the context's deadline is set, so that database/sql
+//     cancels the statement
+//
+// Run the statement with the returned context and query, and call cancel afterwards. Timeouts enforced by the
+// database are handled as ErrStatementTimeout by HandleError.
+func ApplyStatementTimeout(ctx context.Context, tx *sql.Tx, dialect Dialect, query string) (context.Context, string, context.CancelFunc, error) {
+	d, ok := StatementTimeout(ctx)
+	if !ok {
+		return ctx, query, func() {}, nil
+	}
+	// zero disables the timeouts
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+
+	switch {
+	case (dialect == DialectPostgres || dialect == DialectCockroach) && tx != nil:
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", ms)); err != nil {
+			return nil, "", nil, HandleError(err)
+		}
+		return ctx, query, func() {}, nil
+	case dialect == DialectMySQL && mysqlSelect.MatchString(query):
+		return ctx, mysqlSelect.ReplaceAllString(query, fmt.Sprintf("${1} /*+ MAX_EXECUTION_TIME(%d) */", ms)), func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, query, cancel, nil
+}
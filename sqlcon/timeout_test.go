@@ -0,0 +1,83 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyStatementTimeout(t *testing.T) {
+	const query = "SELECT * FROM users"
+
+	t.Run("case=no timeout", func(t *testing.T) {
+		ctx, q, cancel, err := ApplyStatementTimeout(context.Background(), nil, "postgres", query)
+		require.NoError(t, err)
+		defer cancel()
+		assert.Equal(t, query, q)
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+	})
+
+	ctx := WithStatementTimeout(context.Background(), 1500*time.Millisecond)
+	d, ok := StatementTimeout(ctx)
+	require.True(t, ok)
+	assert.Equal(t, 1500*time.Millisecond, d)
+
+	t.Run("case=mysql", func(t *testing.T) {
+		actx, q, cancel, err := ApplyStatementTimeout(ctx, nil, "mysql", "  select * FROM users")
+		require.NoError(t, err)
+		defer cancel()
+		assert.Equal(t, "  select /*+ MAX_EXECUTION_TIME(1500) */ * FROM users", q)
+		_, ok := actx.Deadline()
+		assert.False(t, ok)
+
+		actx, q, cancel, err = ApplyStatementTimeout(ctx, nil, "mysql", "DELETE FROM users")
+		require.NoError(t, err)
+		defer cancel()
+		assert.Equal(t, "DELETE FROM users", q)
+		_, ok = actx.Deadline()
+		assert.True(t, ok, "statements without hint support are bounded by the context")
+	})
+
+	t.Run("case=postgres", func(t *testing.T) {
+		db, err := sql.Open("sqlcon-fake", "postgres")
+		require.NoError(t, err)
+		defer db.Close()
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback()
+
+		actx, q, cancel, err := ApplyStatementTimeout(ctx, tx, "postgres", query)
+		require.NoError(t, err)
+		defer cancel()
+		assert.Equal(t, query, q)
+		_, ok := actx.Deadline()
+		assert.False(t, ok)
+
+		testDriver.mu.Lock()
+		assert.Contains(t, testDriver.execs, "SET LOCAL statement_timeout = 1500")
+		testDriver.mu.Unlock()
+
+		actx, _, cancel, err = ApplyStatementTimeout(ctx, nil, "postgres", query)
+		require.NoError(t, err)
+		defer cancel()
+		_, ok = actx.Deadline()
+		assert.True(t, ok, "statements outside of transactions are bounded by the context")
+	})
+
+	t.Run("case=sqlite", func(t *testing.T) {
+		actx, q, cancel, err := ApplyStatementTimeout(ctx, nil, "sqlite", query)
+		require.NoError(t, err)
+		assert.Equal(t, query, q)
+		deadline, ok := actx.Deadline()
+		require.True(t, ok)
+		assert.WithinDuration(t, time.Now().Add(1500*time.Millisecond), deadline, time.Second)
+
+		cancel()
+		assert.ErrorIs(t, actx.Err(), context.Canceled)
+	})
+}
@@ -0,0 +1,150 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	txContextKey struct{}
+	txContext    struct {
+		tx    *sql.Tx
+		depth int
+	}
+)
+
+// Executor is implemented by *sql.DB, *sql.Conn, *sql.Tx, Connection and Statements.
+type Executor interface {
+	Execer
+	Querier
+}
+
+// TxFromContext returns the transaction of the WithTransaction scope the context belongs to.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	c, ok := ctx.Value(txContextKey{}).(*txContext)
+	if !ok {
+		return nil, false
+	}
+	return c.tx, true
+}
+
+// ContextWithTx returns a context carrying the transaction, e.g. one started without WithTransaction, so that
+// WithTransaction and ExecutorFromContext join it. The caller remains responsible for committing or rolling back
+// the transaction.
+func ContextWithTx(ctx context.Context, tx *sql.Tx) context.Context {
+	return context.WithValue(ctx, txContextKey{}, &txContext{tx: tx})
+}
+
+// ExecutorFromContext returns the transaction the context carries, or db if there is none. Repository code uses
+// it to join the ambient transaction of its caller, and to run on its own otherwise:
+//
+//	func (r *Repository) Delete(ctx context.Context, id string) error {
+//		_, err := sqlcon.ExecutorFromContext(ctx, r.db).ExecContext(ctx, "DELETE FROM identities WHERE id = ?", id)
+//		return sqlcon.HandleError(err)
+//	}
+func ExecutorFromContext(ctx context.Context, db Executor) Executor {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// TransactionOptions configures WithTransaction.
+type TransactionOptions struct {
+	// TxOptions are passed to BeginTx, e.g. to set the isolation level, see Dialect.TxOptions.
+	TxOptions *sql.TxOptions
+	// RetryPolicy decides which errors cause the transaction to be retried.
+	// Default: the zero RetryPolicy, which retries serialization failures, deadlocks and lock timeouts
+	RetryPolicy *RetryPolicy
+	// Observer is notified when the transaction begins, commits and rolls back. It is optional.
+	Observer TxObserver
+}
+
+// WithTransaction runs fn in a transaction. The transaction is committed if fn returns no error and rolled back
+// otherwise, including if fn panics. If fn or the commit fail with a retryable error, the transaction is run
+// again as configured by the RetryPolicy. opts may be nil.
+//
+// The context passed to fn carries the transaction. If WithTransaction is called with such a context, no new
+// transaction is started; instead, fn runs in a savepoint of the existing transaction, which is released if fn
+// succeeds and rolled back to otherwise. Nested scopes are not retried, their errors are retried by the
+// outermost scope instead. This makes repository code composable without knowing whether or how deeply it
+// is nested. Savepoints are supported by SQLite, PostgreSQL, CockroachDB and MySQL.
+//
+// As fn may run more than once, it must not have side effects outside of the transaction. Errors are handled
+// by HandleError.
+func WithTransaction(ctx context.Context, db TxBeginner, opts *TransactionOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if outer, ok := ctx.Value(txContextKey{}).(*txContext); ok {
+		return HandleError(runSavepoint(ctx, outer, fn))
+	}
+
+	if opts == nil {
+		opts = new(TransactionOptions)
+	}
+	policy := opts.RetryPolicy
+	if policy == nil {
+		policy = new(RetryPolicy)
+	}
+
+	observer := opts.Observer
+	if observer == nil {
+		observer = noopTxObserver{}
+	}
+
+	return policy.Do(ctx, func(ctx context.Context) error {
+		return runTx(ctx, db, opts.TxOptions, observer, fn)
+	})
+}
+
+func runTx(ctx context.Context, db TxBeginner, opts *sql.TxOptions, observer TxObserver, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	began := time.Now()
+	tx, err := db.BeginTx(ctx, opts)
+	observer.ObserveBegin(ctx, opts, time.Since(began), HandleError(err))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	rollback := func(cause error) {
+		err := tx.Rollback()
+		observer.ObserveRollback(ctx, time.Since(began), HandleError(cause), HandleError(err))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			rollback(errors.Errorf("panic: %v", r))
+			panic(r)
+		}
+	}()
+
+	if err := fn(ContextWithTx(ctx, tx), tx); err != nil {
+		rollback(err)
+		return err
+	}
+	err = tx.Commit()
+	observer.ObserveCommit(ctx, time.Since(began), HandleError(err))
+	return errors.WithStack(err)
+}
+
+func runSavepoint(ctx context.Context, outer *txContext, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	tx, depth := outer.tx, outer.depth+1
+	savepoint := fmt.Sprintf("sqlcon_savepoint_%d", depth)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(r)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txContextKey{}, &txContext{tx: tx, depth: depth}), tx); err != nil {
+		if _, rollbackErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rollbackErr != nil {
+			return errors.Wrapf(err, "unable to roll back to savepoint: %s", rollbackErr)
+		}
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint)
+	return errors.WithStack(err)
+}
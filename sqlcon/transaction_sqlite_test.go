@@ -0,0 +1,293 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	count := func() (n int) {
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n))
+		return n
+	}
+	insert := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO items DEFAULT VALUES")
+		return err
+	}
+
+	t.Run("case=commits", func(t *testing.T) {
+		require.NoError(t, WithTransaction(ctx, db, nil, insert))
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("case=rolls back on error", func(t *testing.T) {
+		expected := errors.New("expected")
+		err := WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			require.NoError(t, insert(ctx, tx))
+			return expected
+		})
+		assert.ErrorIs(t, err, expected)
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("case=rolls back on panic", func(t *testing.T) {
+		assert.PanicsWithValue(t, "expected", func() {
+			_ = WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+				require.NoError(t, insert(ctx, tx))
+				panic("expected")
+			})
+		})
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("case=maps driver errors", func(t *testing.T) {
+		err := WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO items (id) VALUES (1)")
+			return err
+		})
+		assert.ErrorIs(t, err, ErrUniqueViolation)
+	})
+
+	t.Run("case=retries according to the policy", func(t *testing.T) {
+		var attempts int
+		err := WithTransaction(ctx, db, &TransactionOptions{
+			RetryPolicy: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+		}, func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			require.NoError(t, insert(ctx, tx))
+			return &pgconnv5.PgError{Code: "40P01"}
+		})
+		assert.ErrorIs(t, err, ErrConcurrentUpdate)
+		assert.Equal(t, 2, attempts)
+		assert.Equal(t, 1, count())
+	})
+
+	t.Run("case=passes transaction options", func(t *testing.T) {
+		expected := &sql.TxOptions{Isolation: sql.LevelSerializable}
+		var actual *sql.TxOptions
+		require.NoError(t, WithTransaction(ctx, txBeginnerFunc(func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+			actual = opts
+			return db.BeginTx(ctx, opts)
+		}), &TransactionOptions{TxOptions: expected}, insert))
+		assert.Same(t, expected, actual)
+	})
+
+	t.Run("case=runs with the options of the dialect", func(t *testing.T) {
+		txOpts, err := DialectSQLite.TxOptions(sql.LevelRepeatableRead, false)
+		require.NoError(t, err)
+		before := count()
+		require.NoError(t, WithTransaction(ctx, db, &TransactionOptions{TxOptions: txOpts}, insert))
+		assert.Equal(t, before+1, count())
+	})
+}
+
+func TestWithTransactionObserver(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)")
+	require.NoError(t, err)
+
+	var events []string
+	var causes []error
+	observer := TxHooks{
+		OnBegin: func(_ context.Context, _ *sql.TxOptions, took time.Duration, err error) {
+			assert.NoError(t, err)
+			assert.Positive(t, took)
+			events = append(events, "begin")
+		},
+		OnCommit: func(_ context.Context, took time.Duration, err error) {
+			assert.Positive(t, took)
+			events = append(events, "commit")
+			causes = append(causes, err)
+		},
+		OnRollback: func(_ context.Context, took time.Duration, cause, err error) {
+			assert.NoError(t, err)
+			assert.Positive(t, took)
+			events = append(events, "rollback")
+			causes = append(causes, cause)
+		},
+	}
+	insert := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO items (id) VALUES (1)")
+		return err
+	}
+
+	require.NoError(t, WithTransaction(ctx, db, &TransactionOptions{Observer: observer}, insert))
+	assert.ErrorIs(t, WithTransaction(ctx, db, &TransactionOptions{Observer: observer}, insert), ErrUniqueViolation)
+	assert.Panics(t, func() {
+		_ = WithTransaction(ctx, db, &TransactionOptions{Observer: observer}, func(context.Context, *sql.Tx) error {
+			panic("expected")
+		})
+	})
+	assert.ErrorIs(t, Retry(ctx, db, func(context.Context, *sql.Tx) error {
+		return &pgconnv5.PgError{Code: "40P01"}
+	}, WithMaxAttempts(2), WithBackoff(time.Millisecond, time.Millisecond), WithTxObserver(observer)), ErrConcurrentUpdate)
+
+	assert.Equal(t, []string{
+		"begin", "commit",
+		"begin", "rollback",
+		"begin", "rollback",
+		"begin", "rollback", "begin", "rollback",
+	}, events)
+	require.Len(t, causes, 5)
+	assert.NoError(t, causes[0])
+	assert.ErrorIs(t, causes[1], ErrUniqueViolation)
+	assert.EqualError(t, causes[2], "panic: expected")
+	assert.ErrorIs(t, causes[3], ErrConcurrentUpdate)
+	assert.ErrorIs(t, causes[4], ErrConcurrentUpdate)
+
+	t.Run("case=observes failures to begin", func(t *testing.T) {
+		expected := errors.New("expected")
+		var observed error
+		err := WithTransaction(ctx, txBeginnerFunc(func(context.Context, *sql.TxOptions) (*sql.Tx, error) {
+			return nil, expected
+		}), &TransactionOptions{Observer: TxHooks{OnBegin: func(_ context.Context, _ *sql.TxOptions, _ time.Duration, err error) {
+			observed = err
+		}}}, insert)
+		assert.ErrorIs(t, err, expected)
+		assert.ErrorIs(t, observed, expected)
+	})
+}
+
+type txBeginnerFunc func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+
+func (f txBeginnerFunc) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return f(ctx, opts)
+}
+
+func TestWithTransactionNested(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE items (name TEXT NOT NULL PRIMARY KEY)")
+	require.NoError(t, err)
+
+	names := func() (names []string) {
+		rows, err := db.Query("SELECT name FROM items ORDER BY name")
+		require.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		return names
+	}
+	// insert is repository code which does not know whether it runs in a transaction
+	insert := func(ctx context.Context, name string) error {
+		return WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", name)
+			return err
+		})
+	}
+
+	expected := errors.New("expected")
+	require.NoError(t, WithTransaction(ctx, db, nil, func(ctx context.Context, outer *sql.Tx) error {
+		tx, ok := TxFromContext(ctx)
+		require.True(t, ok)
+		assert.Same(t, outer, tx)
+
+		require.NoError(t, insert(ctx, "a"))
+
+		// the failing nested scope is rolled back, the outer one continues
+		assert.ErrorIs(t, insert(ctx, "a"), ErrUniqueViolation)
+		assert.ErrorIs(t, WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			require.NoError(t, insert(ctx, "b"))
+			return expected
+		}), expected)
+
+		assert.Panics(t, func() {
+			_ = WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+				require.NoError(t, insert(ctx, "c"))
+				panic("expected")
+			})
+		})
+
+		return WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+			return insert(ctx, "d")
+		})
+	}))
+	assert.Equal(t, []string{"a", "d"}, names())
+
+	// the outermost scope rolls back everything
+	assert.ErrorIs(t, WithTransaction(ctx, db, nil, func(ctx context.Context, tx *sql.Tx) error {
+		require.NoError(t, insert(ctx, "e"))
+		return expected
+	}), expected)
+	assert.Equal(t, []string{"a", "d"}, names())
+
+	_, ok := TxFromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestContextWithTx(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE items (name TEXT NOT NULL PRIMARY KEY)")
+	require.NoError(t, err)
+	count := func() (n int) {
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM items").Scan(&n))
+		return n
+	}
+	// insert is repository code which joins the transaction of its caller, if any
+	insert := func(ctx context.Context, name string) error {
+		_, err := ExecutorFromContext(ctx, db).ExecContext(ctx, "INSERT INTO items (name) VALUES (?)", name)
+		return HandleError(err)
+	}
+
+	require.NoError(t, insert(ctx, "a"))
+	assert.Equal(t, 1, count())
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	txCtx := ContextWithTx(ctx, tx)
+	actual, ok := TxFromContext(txCtx)
+	require.True(t, ok)
+	assert.Same(t, tx, actual)
+	assert.Same(t, tx, ExecutorFromContext(txCtx, db))
+
+	require.NoError(t, insert(txCtx, "b"))
+	// nested scopes run in savepoints of the stashed transaction
+	assert.ErrorIs(t, WithTransaction(txCtx, db, nil, func(ctx context.Context, nested *sql.Tx) error {
+		assert.Same(t, tx, nested)
+		require.NoError(t, insert(ctx, "c"))
+		return insert(ctx, "a")
+	}), ErrUniqueViolation)
+	var n int
+	require.NoError(t, tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM items").Scan(&n))
+	assert.Equal(t, 2, n, "only the savepoint was rolled back")
+	require.NoError(t, tx.Rollback())
+
+	assert.Equal(t, 1, count())
+}
@@ -0,0 +1,62 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type (
+	// TxObserver is notified about the transactions run by WithTransaction, e.g. to audit long-running
+	// transactions or to count rollbacks by their cause. Every attempt of a retried transaction is observed.
+	// Savepoints of nested scopes are not observed. All errors are handled by HandleError, so they can be
+	// matched against the error classes using errors.Is.
+	//
+	// The methods are called synchronously and must not block.
+	TxObserver interface {
+		// ObserveBegin is called after BeginTx returned, with how long it took and the error it failed with.
+		ObserveBegin(ctx context.Context, opts *sql.TxOptions, took time.Duration, err error)
+		// ObserveCommit is called after the commit, with how long the transaction took since it began and the
+		// error the commit failed with.
+		ObserveCommit(ctx context.Context, took time.Duration, err error)
+		// ObserveRollback is called after the rollback, with how long the transaction took since it began, the
+		// cause of the rollback which is the error or the panic of fn, and the error the rollback failed with.
+		ObserveRollback(ctx context.Context, took time.Duration, cause, err error)
+	}
+	// TxHooks implements TxObserver using functions, all of which are optional.
+	TxHooks struct {
+		OnBegin    func(ctx context.Context, opts *sql.TxOptions, took time.Duration, err error)
+		OnCommit   func(ctx context.Context, took time.Duration, err error)
+		OnRollback func(ctx context.Context, took time.Duration, cause, err error)
+	}
+)
+
+var _ TxObserver = TxHooks{}
+
+// ObserveBegin implements TxObserver.
+func (h TxHooks) ObserveBegin(ctx context.Context, opts *sql.TxOptions, took time.Duration, err error) {
+	if h.OnBegin != nil {
+		h.OnBegin(ctx, opts, took, err)
+	}
+}
+
+// ObserveCommit implements TxObserver.
+func (h TxHooks) ObserveCommit(ctx context.Context, took time.Duration, err error) {
+	if h.OnCommit != nil {
+		h.OnCommit(ctx, took, err)
+	}
+}
+
+// ObserveRollback implements TxObserver.
+func (h TxHooks) ObserveRollback(ctx context.Context, took time.Duration, cause, err error) {
+	if h.OnRollback != nil {
+		h.OnRollback(ctx, took, cause, err)
+	}
+}
+
+// noopTxObserver is used if TransactionOptions.Observer is not set.
+type noopTxObserver struct{}
+
+func (noopTxObserver) ObserveBegin(context.Context, *sql.TxOptions, time.Duration, error) {}
+func (noopTxObserver) ObserveCommit(context.Context, time.Duration, error)                {}
+func (noopTxObserver) ObserveRollback(context.Context, time.Duration, error, error)       {}
@@ -0,0 +1,168 @@
+package sqlcon
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var identifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Upsert builds INSERT statements which update the rows conflicting with existing ones:
+//
+//	query, args, err := (&sqlcon.Upsert{
+//		Dialect:         sqlcon.DialectPostgres,
+//		Table:           "identities",
+//		Columns:         []string{"id", "state", "updated_at"},
+//		ConflictColumns: []string{"id"},
+//	}).Build([]interface{}{id, state, now})
+type Upsert struct {
+	// Dialect determines the syntax, which is supported for DialectPostgres, DialectCockroach, DialectMySQL
+	// and DialectSQLite.
+	Dialect Dialect
+	// Table is the table to insert into, optionally qualified by the schema.
+	Table string
+	// Columns are the inserted columns, in the order of the values of each row.
+	Columns []string
+	// ConflictColumns are the columns of the unique constraint which detects the conflicts. MySQL detects
+	// conflicts with all unique constraints, so they are optional there.
+	ConflictColumns []string
+	// UpdateColumns are the columns updated on conflicts. If none are set, all columns except the
+	// ConflictColumns are updated. If the ConflictColumns are all columns, conflicting rows are left unchanged.
+	UpdateColumns []string
+	// Returning are the columns returned for the inserted or updated rows. It is not supported by MySQL.
+	Returning []string
+}
+
+func quoteIdentifier(dialect Dialect, name string) (string, error) {
+	parts := strings.Split(name, ".")
+	for _, part := range parts {
+		if !identifier.MatchString(part) {
+			return "", errors.Errorf("invalid identifier %q", name)
+		}
+	}
+	return dialect.QuoteIdentifier(parts...), nil
+}
+
+func quoteIdentifiers(dialect Dialect, names []string) ([]string, error) {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		var err error
+		if quoted[i], err = quoteIdentifier(dialect, name); err != nil {
+			return nil, err
+		}
+	}
+	return quoted, nil
+}
+
+// updateColumns returns the UpdateColumns, or the Columns which are not ConflictColumns.
+func (u *Upsert) updateColumns() []string {
+	if len(u.UpdateColumns) > 0 {
+		return u.UpdateColumns
+	}
+
+	conflict := make(map[string]bool, len(u.ConflictColumns))
+	for _, c := range u.ConflictColumns {
+		conflict[c] = true
+	}
+	var update []string
+	for _, c := range u.Columns {
+		if !conflict[c] {
+			update = append(update, c)
+		}
+	}
+	return update
+}
+
+// Build returns the statement inserting the rows and their values as the arguments. Each row must have a value
+// for each of the Columns.
+func (u *Upsert) Build(rows ...[]interface{}) (string, []interface{}, error) {
+	switch u.Dialect {
+	case DialectPostgres, DialectCockroach, DialectSQLite:
+		if len(u.ConflictColumns) == 0 {
+			return "", nil, errors.Errorf("the dialect %q requires conflict columns", u.Dialect)
+		}
+	case DialectMySQL:
+		if len(u.Returning) > 0 {
+			return "", nil, errors.New(`the dialect "mysql" does not support returning columns`)
+		}
+	default:
+		return "", nil, errors.Errorf("upserts are not supported by the dialect %q", u.Dialect)
+	}
+	if len(u.Columns) == 0 {
+		return "", nil, errors.New("no columns to insert")
+	} else if len(rows) == 0 {
+		return "", nil, errors.New("no rows to insert")
+	}
+
+	table, err := quoteIdentifier(u.Dialect, u.Table)
+	if err != nil {
+		return "", nil, err
+	}
+	columns, err := quoteIdentifiers(u.Dialect, u.Columns)
+	if err != nil {
+		return "", nil, err
+	}
+	conflict, err := quoteIdentifiers(u.Dialect, u.ConflictColumns)
+	if err != nil {
+		return "", nil, err
+	}
+	update, err := quoteIdentifiers(u.Dialect, u.updateColumns())
+	if err != nil {
+		return "", nil, err
+	}
+	returning, err := quoteIdentifiers(u.Dialect, u.Returning)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	args := make([]interface{}, 0, len(rows)*len(columns))
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", table, strings.Join(columns, ", "))
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return "", nil, errors.Errorf("row %d has %d values, but %d columns are inserted", i, len(row), len(columns))
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j, v := range row {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			args = append(args, v)
+			b.WriteString(u.Dialect.Placeholder(len(args)))
+		}
+		b.WriteString(")")
+	}
+
+	set := make([]string, len(update))
+	if u.Dialect == DialectMySQL {
+		for i, c := range update {
+			set[i] = fmt.Sprintf("%s = VALUES(%s)", c, c)
+		}
+		if len(set) == 0 {
+			// assigning a column to itself leaves the conflicting row unchanged
+			set = []string{fmt.Sprintf("%s = %s", columns[0], columns[0])}
+		}
+		fmt.Fprintf(&b, " ON DUPLICATE KEY UPDATE %s", strings.Join(set, ", "))
+		return b.String(), args, nil
+	}
+
+	fmt.Fprintf(&b, " ON CONFLICT (%s)", strings.Join(conflict, ", "))
+	if len(set) == 0 {
+		b.WriteString(" DO NOTHING")
+	} else {
+		for i, c := range update {
+			set[i] = fmt.Sprintf("%s = excluded.%s", c, c)
+		}
+		fmt.Fprintf(&b, " DO UPDATE SET %s", strings.Join(set, ", "))
+	}
+	if len(returning) > 0 {
+		fmt.Fprintf(&b, " RETURNING %s", strings.Join(returning, ", "))
+	}
+	return b.String(), args, nil
+}
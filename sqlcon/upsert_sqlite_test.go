@@ -0,0 +1,38 @@
+//go:build sqlite
+// +build sqlite
+
+package sqlcon
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsertSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE identities (id INTEGER PRIMARY KEY, state TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	u := &Upsert{Dialect: "sqlite", Table: "identities", Columns: []string{"id", "state"}, ConflictColumns: []string{"id"}, Returning: []string{"state"}}
+	query, args, err := u.Build([]interface{}{1, "active"}, []interface{}{2, "active"})
+	require.NoError(t, err)
+	_, err = db.Exec(query, args...)
+	require.NoError(t, err)
+
+	query, args, err = u.Build([]interface{}{2, "inactive"})
+	require.NoError(t, err)
+	var state string
+	require.NoError(t, db.QueryRow(query, args...).Scan(&state))
+	assert.Equal(t, "inactive", state)
+
+	var count int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM identities WHERE state = 'active'").Scan(&count))
+	assert.Equal(t, 1, count)
+}
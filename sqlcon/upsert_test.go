@@ -0,0 +1,56 @@
+package sqlcon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsert(t *testing.T) {
+	rows := [][]interface{}{{1, "active"}, {2, "inactive"}}
+
+	for _, tc := range []struct {
+		upsert   Upsert
+		expected string
+	}{
+		{
+			upsert:   Upsert{Dialect: "postgres", Table: "public.identities", Columns: []string{"id", "state"}, ConflictColumns: []string{"id"}, Returning: []string{"id"}},
+			expected: `INSERT INTO "public"."identities" ("id", "state") VALUES ($1, $2), ($3, $4) ON CONFLICT ("id") DO UPDATE SET "state" = excluded."state" RETURNING "id"`,
+		},
+		{
+			upsert:   Upsert{Dialect: "sqlite", Table: "identities", Columns: []string{"id", "state"}, ConflictColumns: []string{"id", "state"}},
+			expected: `INSERT INTO "identities" ("id", "state") VALUES (?, ?), (?, ?) ON CONFLICT ("id", "state") DO NOTHING`,
+		},
+		{
+			upsert:   Upsert{Dialect: "mysql", Table: "identities", Columns: []string{"id", "state"}},
+			expected: "INSERT INTO `identities` (`id`, `state`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `id` = VALUES(`id`), `state` = VALUES(`state`)",
+		},
+		{
+			upsert:   Upsert{Dialect: "mysql", Table: "identities", Columns: []string{"id", "state"}, UpdateColumns: []string{"state"}},
+			expected: "INSERT INTO `identities` (`id`, `state`) VALUES (?, ?), (?, ?) ON DUPLICATE KEY UPDATE `state` = VALUES(`state`)",
+		},
+	} {
+		t.Run("dialect="+tc.upsert.Dialect.String(), func(t *testing.T) {
+			query, args, err := tc.upsert.Build(rows...)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, query)
+			assert.Equal(t, []interface{}{1, "active", 2, "inactive"}, args)
+		})
+	}
+
+	t.Run("case=invalid", func(t *testing.T) {
+		for _, u := range []Upsert{
+			{Dialect: "sqlserver", Table: "identities", Columns: []string{"id"}},
+			{Dialect: "postgres", Table: "identities", Columns: []string{"id"}},
+			{Dialect: "mysql", Table: "identities", Columns: []string{"id"}, Returning: []string{"id"}},
+			{Dialect: "postgres", Table: "identities; DROP TABLE identities", Columns: []string{"id"}, ConflictColumns: []string{"id"}},
+		} {
+			_, _, err := u.Build([]interface{}{1})
+			assert.Error(t, err, "%+v", u)
+		}
+
+		_, _, err := (&Upsert{Dialect: "mysql", Table: "identities", Columns: []string{"id", "state"}}).Build([]interface{}{1})
+		assert.Error(t, err)
+	})
+}
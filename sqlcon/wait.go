@@ -0,0 +1,119 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ory/x/logrusx"
+	"github.com/ory/x/logx"
+)
+
+// DefaultWaitTimeout is the total time WaitForDB waits for the database if no timeout is set.
+const DefaultWaitTimeout = time.Minute
+
+type (
+	waitOptions struct {
+		timeout     time.Duration
+		pingTimeout time.Duration
+		policy      RetryPolicy
+		onRetry     func(attempt int, wait time.Duration, err error)
+	}
+	// WaitOption configures WaitForDB and Connect.
+	WaitOption func(*waitOptions)
+)
+
+// WithWaitTimeout sets the total time to wait for the database.
+// Default: DefaultWaitTimeout
+func WithWaitTimeout(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.timeout = d
+	}
+}
+
+// WithPingTimeout sets the timeout of each ping, see PingWithTimeout.
+// Default: DefaultProbeTimeout
+func WithPingTimeout(d time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.pingTimeout = d
+	}
+}
+
+// WithWaitBackoff sets the backoff between the pings, see RetryPolicy.Backoff.
+// Default: 50ms, doubling up to 5s
+func WithWaitBackoff(initial, max time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.policy.InitialBackoff, o.policy.MaxBackoff = initial, max
+	}
+}
+
+// WithWaitLogger logs failed pings at info level.
+func WithWaitLogger(l logx.Logger) WaitOption {
+	return func(o *waitOptions) {
+		o.policy.Logger = l
+	}
+}
+
+// WithOnRetry sets a callback which is called with every failed ping, before waiting for the next one.
+func WithOnRetry(f func(attempt int, wait time.Duration, err error)) WaitOption {
+	return func(o *waitOptions) {
+		o.onRetry = f
+	}
+}
+
+// WaitForDB pings the database until it responds, e.g. while its container is still starting. Failed pings
+// are retried with exponential backoff until the timeout is exceeded. Rejected credentials and denied privileges
+// are permanent, so ErrAuthenticationFailed and ErrPermissionDenied are returned immediately. Errors are handled
+// by HandleError.
+func WaitForDB(ctx context.Context, db Pinger, opts ...WaitOption) error {
+	o := &waitOptions{timeout: DefaultWaitTimeout}
+	for _, f := range opts {
+		f(o)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.timeout)
+	defer cancel()
+
+	for attempt := 1; ; attempt++ {
+		err := PingWithTimeout(ctx, db, o.pingTimeout)
+		if err == nil || errors.Is(err, ErrPermissionDenied) {
+			return err
+		}
+		// the database did not respond before the wait timeout, which interrupted the ping
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && errors.Is(err, ErrTimeout) {
+			err = errors.WithStack(ErrConnectionFailed.WithWrap(err))
+		}
+
+		wait := o.policy.Backoff(attempt)
+		if o.policy.Logger != nil {
+			o.policy.Logger.Info("waiting for the database", "attempt", attempt, "wait", wait, "error", err)
+		}
+		if o.onRetry != nil {
+			o.onRetry(attempt, wait, err)
+		}
+		select {
+		case <-ctx.Done():
+			return errors.WithMessagef(err, "unable to connect to the database after %d attempts", attempt)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Connect opens the DSN with the driver and waits for the database using WaitForDB. The pool is configured
+// by the query parameters of the DSN, see ParsePoolOptions.
+func Connect(ctx context.Context, driverName, dsn string, opts ...WaitOption) (*sql.DB, error) {
+	pool, cleaned := ParsePoolOptions(logrusx.New("", ""), dsn)
+	db, err := sql.Open(driverName, cleaned)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open %s", RedactDSN(dsn))
+	}
+	pool.Apply(db)
+
+	if err := WaitForDB(ctx, db, opts...); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,81 @@
+package sqlcon
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	pgconnv5 "github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForDB(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("case=retries until the database responds", func(t *testing.T) {
+		pings, retries := 0, 0
+		db := pingerFunc(func(context.Context) error {
+			if pings++; pings < 3 {
+				return driver.ErrBadConn
+			}
+			return nil
+		})
+
+		require.NoError(t, WaitForDB(ctx, db, WithWaitBackoff(time.Millisecond, time.Millisecond), WithOnRetry(func(attempt int, _ time.Duration, err error) {
+			retries++
+			assert.Equal(t, retries, attempt)
+			assert.ErrorIs(t, err, ErrConnectionFailed)
+		})))
+		assert.Equal(t, 3, pings)
+		assert.Equal(t, 2, retries)
+	})
+
+	t.Run("case=fails fast on rejected credentials", func(t *testing.T) {
+		pings := 0
+		err := WaitForDB(ctx, pingerFunc(func(context.Context) error {
+			pings++
+			return &pgconnv5.PgError{Code: "28P01"}
+		}))
+		assert.ErrorIs(t, err, ErrAuthenticationFailed)
+		assert.Equal(t, 1, pings)
+	})
+
+	t.Run("case=fails fast on denied privileges", func(t *testing.T) {
+		pings := 0
+		err := WaitForDB(ctx, pingerFunc(func(context.Context) error {
+			pings++
+			return &mysql.MySQLError{Number: 1044}
+		}))
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		assert.Equal(t, 1, pings)
+	})
+
+	t.Run("case=gives up after the timeout", func(t *testing.T) {
+		err := WaitForDB(ctx, pingerFunc(func(context.Context) error {
+			return driver.ErrBadConn
+		}), WithWaitTimeout(20*time.Millisecond), WithWaitBackoff(time.Millisecond, 5*time.Millisecond))
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+	})
+
+	t.Run("case=gives up if the timeout interrupts a ping", func(t *testing.T) {
+		err := WaitForDB(ctx, pingerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}), WithWaitTimeout(20*time.Millisecond))
+		assert.ErrorIs(t, err, ErrConnectionFailed)
+	})
+}
+
+func TestConnect(t *testing.T) {
+	db, err := Connect(context.Background(), "sqlcon-fake", "wait?max_conns=2")
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 2, db.Stats().MaxOpenConnections)
+
+	testDriver.setDown("wait-down", true)
+	_, err = Connect(context.Background(), "sqlcon-fake", "wait-down", WithWaitTimeout(20*time.Millisecond))
+	assert.ErrorIs(t, err, ErrConnectionFailed)
+}